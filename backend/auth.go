@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"spieven/common"
+	"spieven/common/packet"
+	"strings"
+	"time"
+)
+
+// loadServerAuth reads the certificates and the token created by "spieven auth init". Clients have to present
+// a certificate signed by the same CA or the token.
+func loadServerAuth(authDir string) (*tls.Config, string, error) {
+	missingFilesErr := func(err error) error {
+		return fmt.Errorf("cannot load credentials from %v, run spieven auth init first: %w", authDir, err)
+	}
+
+	serverCert, err := tls.LoadX509KeyPair(filepath.Join(authDir, common.AuthServerCertFile), filepath.Join(authDir, common.AuthServerKeyFile))
+	if err != nil {
+		return nil, "", missingFilesErr(err)
+	}
+
+	caCert, err := os.ReadFile(filepath.Join(authDir, common.AuthCaCertFile))
+	if err != nil {
+		return nil, "", missingFilesErr(err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caCert) {
+		return nil, "", fmt.Errorf("invalid CA certificate in %v", authDir)
+	}
+
+	token, err := loadAuthToken(authDir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    caPool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		MinVersion:   tls.VersionTLS13,
+	}
+	return tlsConfig, token, nil
+}
+
+// loadAuthToken reads only the token created by "spieven auth init", for listeners which do not use certificates.
+func loadAuthToken(authDir string) (string, error) {
+	token, err := os.ReadFile(filepath.Join(authDir, common.AuthTokenFile))
+	if err != nil {
+		return "", fmt.Errorf("cannot load credentials from %v, run spieven auth init first: %w", authDir, err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// isValidAuthToken compares tokens in constant time, so the token cannot be guessed by measuring response times.
+func isValidAuthToken(token string, expectedToken string) bool {
+	return token != "" && expectedToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expectedToken)) == 1
+}
+
+// authenticateConnection performs the TLS handshake and expects an authenticate packet. The frontend is accepted if it
+// presented a valid client certificate or a correct token.
+func authenticateConnection(backendState *BackendState, connection *tls.Conn) error {
+	connection.SetDeadline(time.Now().Add(time.Second * 10))
+	defer connection.SetDeadline(time.Time{})
+
+	if err := connection.Handshake(); err != nil {
+		return err
+	}
+	hasClientCert := len(connection.ConnectionState().VerifiedChains) > 0
+
+	requestPacket, err := packet.ReceivePacket(connection)
+	if err != nil {
+		return err
+	}
+	request, err := packet.DecodeAuthenticatePacket(requestPacket)
+	if err != nil {
+		return err
+	}
+	hasValidToken := isValidAuthToken(request.Token, backendState.authToken)
+
+	response := packet.AuthenticateResponseBody{Success: hasClientCert || hasValidToken}
+	responsePacket, err := packet.EncodeAuthenticateResponsePacket(response)
+	if err != nil {
+		return err
+	}
+	err = packet.SendPacket(connection, responsePacket)
+	if err != nil {
+		return err
+	}
+
+	if !response.Success {
+		return errors.New("no valid client certificate or token")
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package backend
 
 import (
+	"errors"
+	"spieven/common/types"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -9,22 +12,46 @@ import (
 func CreateCliCommand() *cobra.Command {
 	var (
 		frequentTrim           bool
-		remote                 bool
+		listenOptions          ListenOptions
 		displayKillGracePeriod int
 		port                   int
+		logRetention           types.LogRetention
+		logSinks               types.LogSinks
 	)
 	command := &cobra.Command{
 		Use:   "serve [OPTIONS...]",
 		Short: "Launch Spieven backend engine.",
 		Args:  cobra.ExactArgs(0),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			isHttpOnUnixSocket := strings.HasPrefix(listenOptions.Http, "unix://")
+			if listenOptions.AllowedGroup != "" && listenOptions.Socket == "" && !isHttpOnUnixSocket {
+				return errors.New("--allow-group requires --socket or --http unix://PATH, because the default socket is in a private runtime directory")
+			}
+			if err := logSinks.Validate(); err != nil {
+				return err
+			}
+
 			displayKillGracePeriod := time.Millisecond * time.Duration(displayKillGracePeriod)
-			return RunServer(frequentTrim, remote, displayKillGracePeriod, port)
+			return RunServer(frequentTrim, listenOptions, displayKillGracePeriod, port, logRetention, logSinks)
 		},
 	}
 	command.Flags().BoolVarP(&frequentTrim, "frequent-trim", "t", false, "Enable very frequent resource trimming. This flag should only be used for testing purposes")
-	command.Flags().BoolVarP(&remote, "remote", "r", false, "Allow connections from remote addresses")
+	command.Flags().BoolVarP(&listenOptions.Remote, "remote", "r", false, "Listen on a TCP port on all interfaces and allow connections from remote addresses. Connections are encrypted with TLS and require credentials created with spieven auth init")
+	command.Flags().BoolVar(&listenOptions.Tcp, "tcp", false, "Listen on a TCP port on localhost in addition to the unix socket. Cannot be used with --remote, which listens on localhost as well")
+	command.Flags().StringVar(&listenOptions.AuthDir, "auth-dir", "", "Directory with credentials created with spieven auth init (default: ~/.config/Spieven/auth)")
+	command.Flags().StringVar(&listenOptions.Http, "http", "", "Serve a REST API with json bodies on given address. "+HttpAddressHelpString)
+	command.Flags().StringVar(&listenOptions.Socket, "socket", "", "Listen on an additional unix socket at a given path. Frontends connect to it with --server-address unix://PATH")
+	command.Flags().StringVar(&listenOptions.AllowedGroup, "allow-group", "", "Allow members of a group to connect to the socket given with --socket and to the HTTP API on a unix socket. "+
+		"The default socket is in a private runtime directory, so only the owner can connect to it")
 	command.Flags().IntVarP(&displayKillGracePeriod, "display-kill-grace-period", "g", 1000, "Delay in milliseconds before killing all tasks related to a display that has been closed")
-	command.Flags().IntVarP(&port, "port", "p", 0, "Port to listen on")
+	command.Flags().IntVarP(&port, "port", "p", 0, "Port to listen on. It also selects the name of the unix socket")
+	command.Flags().IntVar(&logRetention.MaxExecutions, "keep-executions", 100, "Default number of most recent executions to keep stdout/stderr files for. Specify 0 for no limit.")
+	command.Flags().Int64Var(&logRetention.MaxTaskLogBytes, "max-log-size", 10*1024*1024, "Default size in bytes after which a task log is rotated. Specify 0 for no limit.")
+	command.Flags().DurationVar(&logRetention.MaxAge, "max-log-age", 0, "Default age after which stdout/stderr files and rotated task logs are removed, e.g. 24h. Specify 0 for no limit.")
+	command.Flags().BoolVar(&logRetention.Compress, "compress-logs", false, "Compress stdout/stderr files of older executions and rotated task logs with gzip by default")
+	command.Flags().StringVar(&logSinks.Syslog, "syslog", "", "Forward logs of all tasks to a syslog daemon as RFC 5424 messages. "+types.SyslogAddressHelpString)
+	command.Flags().IntVar(&logSinks.SyslogMaxLength, "syslog-max-length", 0, types.SyslogMaxLengthHelpString)
+	command.Flags().StringVar(&logSinks.LogPipe, "log-pipe", "", "Forward logs of all tasks to stdin of a long-running shell command, one json object per line")
+	command.MarkFlagsMutuallyExclusive("tcp", "remote")
 	return command
 }
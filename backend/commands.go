@@ -1,12 +1,19 @@
 package backend
 
 import (
+	"fmt"
+	"io"
 	"net"
+	"os"
+	"regexp"
+	"slices"
 	i "spieven/backend/interfaces"
 	"spieven/backend/scheduler"
 	"spieven/common"
 	"spieven/common/packet"
 	"spieven/common/types"
+	"strings"
+	"time"
 )
 
 func getSelectorFunc(filter *types.TaskFilter) func(*scheduler.Task) bool {
@@ -35,6 +42,18 @@ func getSelectorFunc(filter *types.TaskFilter) func(*scheduler.Task) bool {
 			return prev(task) && common.ContainsAll(filter.AllTagsFilter, task.Tags)
 		}
 	}
+	if filter.HasWhereFilter {
+		prev := selector
+		expression, err := types.ParseTaskExpression(filter.WhereFilter)
+		if err != nil {
+			// Frontends validate expressions before sending them, so this can only come from a broken client. Selecting
+			// nothing is safer than ignoring the expression, since filters also select tasks to stop.
+			return func(task *scheduler.Task) bool { return false }
+		}
+		selector = func(task *scheduler.Task) bool {
+			return prev(task) && expression.Matches(getTaskExpressionFields(task))
+		}
+	}
 	if !filter.IncludeActive {
 		prev := selector
 		selector = func(task *scheduler.Task) bool {
@@ -50,25 +69,165 @@ func getSelectorFunc(filter *types.TaskFilter) func(*scheduler.Task) bool {
 	return selector
 }
 
-func CmdLog(backendState *BackendState, frontendConnection net.Conn) error {
+func getTaskExpressionFields(task *scheduler.Task) *types.TaskExpressionFields {
+	status := "active"
+	if task.Dynamic.IsDeactivated {
+		status = "deactivated"
+	}
+	return &types.TaskExpressionFields{
+		Id:       task.Computed.Id,
+		Name:     task.FriendlyName,
+		Display:  task.Display.ComputeDisplayLabel(),
+		Tags:     task.Tags,
+		Cmdline:  strings.Join(task.Cmdline, " "),
+		Cwd:      task.Cwd,
+		Status:   status,
+		Reason:   task.Dynamic.DeactivatedReason,
+		Runs:     task.Dynamic.RunCount,
+		Failures: task.Dynamic.FailureCount,
+		Exit:     task.Dynamic.LastExitValue,
+	}
+}
+
+func getMessageSelectorFunc(request *packet.LogRequestBody) func(*types.BackendMessage) bool {
+	return func(message *types.BackendMessage) bool {
+		if message.Severity < request.MinSeverity {
+			return false
+		}
+		if request.Kind != "" && message.Kind != request.Kind {
+			return false
+		}
+		if request.TaskId != nil && (message.TaskId == nil || *message.TaskId != *request.TaskId) {
+			return false
+		}
+		if !request.Since.IsZero() && message.Time.Before(request.Since) {
+			return false
+		}
+		return true
+	}
+}
+
+func CmdLog(backendState *BackendState, frontendConnection net.Conn, request packet.LogRequestBody) error {
 	messages := backendState.messages
+	selector := getMessageSelectorFunc(&request)
+
+	sendResponse := func(response packet.LogResponseBody) error {
+		reponsePacket, err := packet.EncodeLogResponsePacket(response)
+		if err != nil {
+			return err
+		}
+		return packet.SendPacket(frontendConnection, reponsePacket)
+	}
 
-	messages.lock.Lock()
-	response := make(packet.LogResponseBody, len(messages.messages))
-	for i, message := range messages.messages {
-		response[i] = message.String()
+	// Subscribe before the first query, so no message is missed in between.
+	var newMessagesChannel chan struct{}
+	if request.Follow {
+		newMessagesChannel = make(chan struct{}, 1)
+		messages.Subscribe(newMessagesChannel)
+		defer messages.Unsubscribe(newMessagesChannel)
 	}
-	messages.lock.Unlock()
 
-	reponsePacket, err := packet.EncodeLogResponsePacket(response)
+	response, nextSequence := messages.Query(0, selector)
+	err := sendResponse(response)
+	if err != nil || !request.Follow {
+		return err
+	}
+
+	// Frontend doesn't send anything while following. Reading from the connection lets us notice it was closed.
+	frontendDisconnected := make(chan struct{})
+	backendState.sync.StartGoroutine(func() {
+		io.Copy(io.Discard, frontendConnection)
+		close(frontendDisconnected)
+	})
+
+	for {
+		select {
+		case <-newMessagesChannel:
+		case <-frontendDisconnected:
+			return nil
+		case <-(*backendState.sync.GetContext()).Done():
+			return nil
+		}
+
+		response, nextSequence = messages.Query(nextSequence, selector)
+		if len(response) > 0 {
+			if err := sendResponse(response); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func CmdEvents(backendState *BackendState, frontendConnection net.Conn, request packet.EventsRequestBody) error {
+	eventsChannel := backendState.events.Subscribe()
+	defer backendState.events.Unsubscribe(eventsChannel)
+
+	sendResponse := func(response packet.EventsResponseBody) error {
+		reponsePacket, err := packet.EncodeEventsResponsePacket(response)
+		if err != nil {
+			return err
+		}
+		return packet.SendPacket(frontendConnection, reponsePacket)
+	}
+
+	// Confirm the subscription, so frontend knows no events will be missed from now on
+	err := sendResponse(packet.EventsResponseBody{})
 	if err != nil {
 		return err
 	}
 
-	return packet.SendPacket(frontendConnection, reponsePacket)
+	// Frontend doesn't send anything while subscribed. Reading from the connection lets us notice it was closed.
+	frontendDisconnected := make(chan struct{})
+	backendState.sync.StartGoroutine(func() {
+		io.Copy(io.Discard, frontendConnection)
+		close(frontendDisconnected)
+	})
+
+	for {
+		var response packet.EventsResponseBody
+		select {
+		case event := <-eventsChannel:
+			if request.Filter.Matches(&event) {
+				response = append(response, event)
+			}
+		case <-frontendDisconnected:
+			return nil
+		case <-(*backendState.sync.GetContext()).Done():
+			return nil
+		}
+
+		// Send all pending events in one packet
+	drain:
+		for {
+			select {
+			case event := <-eventsChannel:
+				if request.Filter.Matches(&event) {
+					response = append(response, event)
+				}
+			default:
+				break drain
+			}
+		}
+
+		if len(response) > 0 {
+			if err := sendResponse(response); err != nil {
+				return err
+			}
+		}
+	}
 }
 
 func CmdList(backendState *BackendState, frontendConnection net.Conn, request packet.ListRequestBody) error {
+	response := computeListResponse(backendState, request)
+	responsePacket, err := packet.EncodeListResponsePacket(response)
+	if err != nil {
+		return err
+	}
+
+	return packet.SendPacket(frontendConnection, responsePacket)
+}
+
+func computeListResponse(backendState *BackendState, request packet.ListRequestBody) packet.ListResponseBody {
 	sched := &backendState.scheduler
 
 	namesMap := make(map[string][]int) // this map store a list of indices of task for each friendlyName
@@ -96,8 +255,14 @@ func CmdList(backendState *BackendState, frontendConnection net.Conn, request pa
 			SubsequentFailureCount: task.Dynamic.SubsequentFailureCount,
 			MaxSubsequentFailures:  task.MaxSubsequentFailures,
 			LastExitValue:          task.Dynamic.LastExitValue,
+			TotalUsage:             task.Dynamic.TotalUsage,
 			LastStdout:             stdout,
 			HasLastStdout:          hasStdout,
+			CurrentPid:             task.Dynamic.CurrentPid,
+			NextRunTime:            task.Dynamic.NextRunTime,
+		}
+		if item.CurrentPid != 0 {
+			item.CurrentStartTime = task.Dynamic.LastStartTime
 		}
 
 		if request.UniqueNames {
@@ -151,15 +316,20 @@ func CmdList(backendState *BackendState, frontendConnection net.Conn, request pa
 		response = newResponse
 	}
 
-	reponsePacket, err := packet.EncodeListResponsePacket(response)
+	return response
+}
+
+func CmdRun(backendState *BackendState, frontendConnection net.Conn, request packet.RunRequestBody) error {
+	response := computeRunResponse(backendState, request)
+	responsePacket, err := packet.EncodeRunResponsePacket(response)
 	if err != nil {
 		return err
 	}
 
-	return packet.SendPacket(frontendConnection, reponsePacket)
+	return packet.SendPacket(frontendConnection, responsePacket)
 }
 
-func CmdRun(backendState *BackendState, frontendConnection net.Conn, request packet.RunRequestBody) error {
+func computeRunResponse(backendState *BackendState, request packet.RunRequestBody) packet.RunResponseBody {
 	sched := &backendState.scheduler
 
 	task := scheduler.Task{
@@ -174,6 +344,9 @@ func CmdRun(backendState *BackendState, frontendConnection net.Conn, request pac
 		CaptureStderr:         request.CaptureStderr,
 		Display:               request.Display,
 		Tags:                  request.Tags,
+		LogRetention:          request.LogRetention.WithDefaults(backendState.logRetention),
+		LogFormat:             request.LogFormat,
+		LogSinks:              request.LogSinks,
 	}
 
 	sched.Lock()
@@ -188,47 +361,224 @@ func CmdRun(backendState *BackendState, frontendConnection net.Conn, request pac
 
 	switch responseStatus {
 	case types.RunResponseStatusSuccess:
-		backendState.messages.Add(i.BackendMessageInfo, &task, "Successfully run task")
+		backendState.messages.Add(i.BackendMessageInfo, i.MessageKindTask, &task, "Successfully run task")
 	case types.RunResponseStatusAlreadyRunning:
-		backendState.messages.Add(i.BackendMessageError, nil, "Task already running")
+		backendState.messages.Add(i.BackendMessageError, i.MessageKindRequest, nil, "Task already running")
 	case types.RunResponseStatusNameDisplayAlreadyRunning:
-		backendState.messages.AddF(i.BackendMessageError, nil, "Task named %v already present on \"%v\" display", task.FriendlyName, task.Display.ComputeDisplayLabel())
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Task named %v already present on \"%v\" display", task.FriendlyName, task.Display.ComputeDisplayLabel())
 	case types.RunResponseStatusInvalidDisplay:
-		backendState.messages.Add(i.BackendMessageError, nil, "Task uses invalid display")
+		backendState.messages.Add(i.BackendMessageError, i.MessageKindRequest, nil, "Task uses invalid display")
 	default:
 		// Shouldn't happen, but let's handle it gracefully
-		backendState.messages.Add(i.BackendMessageError, nil, "Unknown running error")
+		backendState.messages.Add(i.BackendMessageError, i.MessageKindRequest, nil, "Unknown running error")
 		response.Status = types.RunResponseStatusUnknown
 	}
 
-	responsePacket, err := packet.EncodeRunResponsePacket(response)
-	if err != nil {
-		return err
+	return response
+}
+
+func CmdFollowTaskLog(backendState *BackendState, frontendConnection net.Conn, request packet.FollowTaskLogRequestBody) error {
+	sendResponse := func(response packet.FollowTaskLogResponseBody) error {
+		responsePacket, err := packet.EncodeFollowTaskLogResponsePacket(response)
+		if err != nil {
+			return err
+		}
+		return packet.SendPacket(frontendConnection, responsePacket)
 	}
 
-	return packet.SendPacket(frontendConnection, responsePacket)
+	// Frontend doesn't send anything while following. Reading from the connection lets us notice it was closed.
+	frontendDisconnected := make(chan struct{})
+	backendState.sync.StartGoroutine(func() {
+		io.Copy(io.Discard, frontendConnection)
+		close(frontendDisconnected)
+	})
+
+	return followTaskLogs(backendState, request, sendResponse, frontendDisconnected)
 }
 
-func CmdQueryTaskActive(backendState *BackendState, frontendConnection net.Conn, request packet.QueryTaskActiveRequestBody) error {
+// followTaskLogs keeps passing new content of task logs to sendResponse until the followed task is deactivated or the
+// client disconnects.
+func followTaskLogs(
+	backendState *BackendState,
+	request packet.FollowTaskLogRequestBody,
+	sendResponse func(packet.FollowTaskLogResponseBody) error,
+	clientDisconnected <-chan struct{},
+) error {
 	sched := &backendState.scheduler
-	taskId := int(request)
 
-	var response packet.QueryTaskActiveResponseBody
+	// A single task is followed until it's deactivated. With a filter, we follow all active tasks matching it and
+	// periodically look for new ones.
+	followMany := request.Filter != nil
+	taskSelector := requestedTaskSelector(request.Task, request.TaskId)
+	var selector func(*scheduler.Task) bool
+	var trimmedTask *scheduler.Task // the followed task, if it was trimmed out of memory
+	var rescanChannel <-chan time.Time
+	if followMany {
+		filter := *request.Filter
+		filter.IncludeActive = true
+		filter.IncludeDeactivated = false
+		selector = getSelectorFunc(&filter)
 
-	sched.Lock()
-	if sched.IsValidId(taskId) {
-		response = packet.QueryTaskActiveResponseBodyInactive
+		rescanTicker := time.NewTicker(time.Second)
+		defer rescanTicker.Stop()
+		rescanChannel = rescanTicker.C
+	} else {
+		sched.Lock()
+		task, candidates := resolveTaskSelector(backendState, taskSelector)
+		if task != nil && sched.FindTask(task.Computed.Id) == nil {
+			trimmedTask = task
+		}
+		sched.Unlock()
+
+		switch {
+		case task != nil:
+			taskId := task.Computed.Id
+			selector = func(task *scheduler.Task) bool { return task.Computed.Id == taskId }
+		case candidates != nil:
+			logAmbiguousTaskSelector(backendState, taskSelector, candidates)
+			return sendResponse(packet.FollowTaskLogResponseBody{
+				Status:     packet.FollowTaskLogResponseStatusAmbiguousTask,
+				Candidates: candidates,
+			})
+		default:
+			selector = func(task *scheduler.Task) bool { return false }
+		}
+	}
+
+	// All followed tasks wake us up through the same channel, whenever there's something new in their logs.
+	wakeChannel := make(chan struct{}, 1)
+	stopChannel := make(chan struct{})
+	defer close(stopChannel)
+	followedTasks := make(map[int]*followedTaskLog)
+	defer func() {
+		for _, followed := range followedTasks {
+			followed.close()
+		}
+	}()
+
+	// With a filter, tasks matching at the start are followed from the beginning of their logs. Tasks picked up later
+	// are followed from the current end of their logs, so tasks which have been running for long don't dump everything
+	// they logged so far. Tasks created after the start are an exception, their whole logs are new.
+	isInitialPickUp := true
+	firstNewTaskId := 0
+	pickUpTasks := func() error {
+		var newTasks []*followedTaskLog
+
+		// Trimmed task is not in the scheduler anymore, but its log is still there. Send it and report the task as
+		// deactivated.
+		if trimmedTask != nil {
+			followed := createFinishedTaskLog(trimmedTask, request.Offset)
+			followedTasks[followed.taskId] = followed
+			newTasks = append(newTasks, followed)
+			trimmedTask = nil
+		}
+
+		// Subscribe for notifications under the lock, so we don't miss anything written after we read the current content.
+		sched.Lock()
+		if isInitialPickUp {
+			firstNewTaskId = sched.GetNextId()
+		}
 		for _, task := range sched.GetTasks() {
-			if task.Computed.Id == taskId && !task.Dynamic.IsDeactivated {
-				response = packet.QueryTaskActiveResponseBodyActive
+			if _, ok := followedTasks[task.Computed.Id]; ok || !selector(task) {
+				continue
+			}
+
+			offset := int64(0)
+			switch {
+			case !followMany:
+				offset = request.Offset
+			case !isInitialPickUp && task.Computed.Id < firstNewTaskId:
+				if fileInfo, err := os.Stat(task.Computed.OutFilePath); err == nil {
+					offset = fileInfo.Size()
+				}
 			}
+			followed := createFollowedTaskLog(task, offset, wakeChannel, stopChannel, backendState.sync)
+			followedTasks[followed.taskId] = followed
+			newTasks = append(newTasks, followed)
 		}
-	} else {
-		response = packet.QueryTaskActiveResponseInvalidTask
+		isInitialPickUp = false
+		sched.Unlock()
+
+		for _, followed := range newTasks {
+			err := sendResponse(packet.FollowTaskLogResponseBody{
+				Status:   packet.FollowTaskLogResponseStatusStarted,
+				TaskId:   followed.taskId,
+				TaskName: followed.taskName,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
 	}
-	sched.Unlock()
+	if err := pickUpTasks(); err != nil {
+		return err
+	}
+	if !followMany && len(followedTasks) == 0 {
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Task %v not found", taskSelector)
+		return sendResponse(packet.FollowTaskLogResponseBody{Status: packet.FollowTaskLogResponseStatusInvalidTask})
+	}
+
+	buffer := make([]byte, followTaskLogChunkSize)
+	for {
+		for taskId, followed := range followedTasks {
+			// Check if the task has ended before reading, so everything written before the end is sent.
+			taskEnded := followed.isDone()
+
+			for {
+				bytesRead, err := followed.reader.read(buffer)
+				if err != nil {
+					return sendResponse(packet.FollowTaskLogResponseBody{
+						Status: packet.FollowTaskLogResponseStatusReadError,
+						TaskId: taskId,
+						Error:  err.Error(),
+					})
+				}
+				if bytesRead == 0 {
+					break
+				}
+
+				err = sendResponse(packet.FollowTaskLogResponseBody{
+					Status: packet.FollowTaskLogResponseStatusChunk,
+					TaskId: taskId,
+					Data:   buffer[:bytesRead],
+				})
+				if err != nil {
+					return err
+				}
+			}
 
-	responsePacket, err := packet.EncodeQueryTaskActiveResponsePacket(response)
+			if taskEnded {
+				followed.close()
+				delete(followedTasks, taskId)
+
+				err := sendResponse(packet.FollowTaskLogResponseBody{
+					Status: packet.FollowTaskLogResponseStatusDeactivated,
+					TaskId: taskId,
+				})
+				if err != nil || !followMany {
+					return err
+				}
+			}
+		}
+
+		select {
+		case <-wakeChannel:
+		case <-rescanChannel:
+			if err := pickUpTasks(); err != nil {
+				return err
+			}
+		case <-clientDisconnected:
+			return nil
+		case <-(*backendState.sync.GetContext()).Done():
+			return nil
+		}
+	}
+}
+
+func CmdRefresh(backendState *BackendState, frontendConnection net.Conn, request packet.RefreshRequestBody) error {
+	response := computeRefreshResponse(backendState, request)
+	responsePacket, err := packet.EncodeRefreshResponsePacket(response)
 	if err != nil {
 		return err
 	}
@@ -236,7 +586,7 @@ func CmdQueryTaskActive(backendState *BackendState, frontendConnection net.Conn,
 	return packet.SendPacket(frontendConnection, responsePacket)
 }
 
-func CmdRefresh(backendState *BackendState, frontendConnection net.Conn, request packet.RefreshRequestBody) error {
+func computeRefreshResponse(backendState *BackendState, request packet.RefreshRequestBody) packet.RefreshResponseBody {
 	sched := &backendState.scheduler
 
 	var response packet.RefreshResponseBody
@@ -258,7 +608,12 @@ func CmdRefresh(backendState *BackendState, frontendConnection net.Conn, request
 
 	sched.Unlock()
 
-	responsePacket, err := packet.EncodeRefreshResponsePacket(response)
+	return response
+}
+
+func CmdResume(backendState *BackendState, frontendConnection net.Conn, request packet.ResumeRequestBody) error {
+	response := computeResumeResponse(backendState, request)
+	responsePacket, err := packet.EncodeResumeResponsePacket(response)
 	if err != nil {
 		return err
 	}
@@ -266,14 +621,30 @@ func CmdRefresh(backendState *BackendState, frontendConnection net.Conn, request
 	return packet.SendPacket(frontendConnection, responsePacket)
 }
 
-func CmdResume(backendState *BackendState, frontendConnection net.Conn, request packet.ResumeRequestBody) error {
+func computeResumeResponse(backendState *BackendState, request packet.ResumeRequestBody) packet.ResumeResponseBody {
+	if request.Filter != nil {
+		if !request.All && !hasSelectingFilter(backendState, request.Filter, "resume") {
+			return packet.ResumeResponseBody{RunResponseBody: packet.RunResponseBody{Status: types.RunResponseStatusEmptyFilter}}
+		}
+		return computeBulkResumeResponse(backendState, *request.Filter)
+	}
+
 	sched := &backendState.scheduler
 
 	var response packet.ResumeResponseBody
 
+	selector := requestedTaskSelector(request.Task, request.TaskId)
+
 	sched.Lock()
 
-	task, status := sched.ExtractDeactivatedTask(request.TaskId, backendState.files, backendState.messages)
+	task, candidates := resolveTaskSelector(backendState, selector)
+	status := types.RunResponseStatusTaskNotFound
+	if task != nil {
+		task, status = sched.ExtractDeactivatedTask(task.Computed.Id, backendState.files, backendState.messages)
+	} else if candidates != nil {
+		status = types.RunResponseStatusAmbiguousTask
+		response.Candidates = candidates
+	}
 	if status == types.RunResponseStatusSuccess {
 		response.Status = sched.TryResumeTask(task, backendState.files, backendState.displays, backendState.sync, backendState.messages)
 		response.LogFile = task.Computed.OutFilePath
@@ -287,24 +658,31 @@ func CmdResume(backendState *BackendState, frontendConnection net.Conn, request
 
 	switch response.Status {
 	case types.RunResponseStatusSuccess:
-		backendState.messages.AddF(i.BackendMessageInfo, task, "Resumed task %v", request.TaskId)
+		backendState.messages.AddF(i.BackendMessageInfo, i.MessageKindTask, task, "Resumed task %v", task.Computed.Id)
 	case types.RunResponseStatusAlreadyRunning:
-		backendState.messages.Add(i.BackendMessageError, nil, "Task already running")
+		backendState.messages.Add(i.BackendMessageError, i.MessageKindRequest, nil, "Task already running")
 	case types.RunResponseStatusNameDisplayAlreadyRunning:
-		backendState.messages.AddF(i.BackendMessageError, nil, "Task named %v already present on \"%v\" display", task.FriendlyName, task.Display.ComputeDisplayLabel())
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Task named %v already present on \"%v\" display", task.FriendlyName, task.Display.ComputeDisplayLabel())
 	case types.RunResponseStatusInvalidDisplay:
-		backendState.messages.Add(i.BackendMessageError, nil, "Task uses invalid display")
+		backendState.messages.Add(i.BackendMessageError, i.MessageKindRequest, nil, "Task uses invalid display")
 	case types.RunResponseStatusTaskNotFound:
-		backendState.messages.AddF(i.BackendMessageError, nil, "Task %v not found", request.TaskId)
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Task %v not found", selector)
 	case types.RunResponseStatusTaskNotDeactivated:
-		backendState.messages.AddF(i.BackendMessageError, nil, "Task %v is active, cannot resume", request.TaskId)
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Task %v is active, cannot resume", selector)
+	case types.RunResponseStatusAmbiguousTask:
+		logAmbiguousTaskSelector(backendState, selector, response.Candidates)
 	default:
 		// Shouldn't happen, but let's handle it gracefully
-		backendState.messages.Add(i.BackendMessageError, nil, "Unknown resuming error")
+		backendState.messages.Add(i.BackendMessageError, i.MessageKindRequest, nil, "Unknown resuming error")
 		response.Status = types.RunResponseStatusUnknown
 	}
 
-	responsePacket, err := packet.EncodeResumeResponsePacket(response)
+	return response
+}
+
+func CmdStop(backendState *BackendState, frontendConnection net.Conn, request packet.StopRequestBody) error {
+	response := computeStopResponse(backendState, request)
+	responsePacket, err := packet.EncodeStopResponsePacket(response)
 	if err != nil {
 		return err
 	}
@@ -312,58 +690,536 @@ func CmdResume(backendState *BackendState, frontendConnection net.Conn, request
 	return packet.SendPacket(frontendConnection, responsePacket)
 }
 
-func CmdStop(backendState *BackendState, frontendConnection net.Conn, request packet.StopRequestBody) error {
+func computeStopResponse(backendState *BackendState, request packet.StopRequestBody) packet.StopResponseBody {
+	if request.Filter != nil {
+		if !request.All && !hasSelectingFilter(backendState, request.Filter, "stop") {
+			return packet.StopResponseBody{Status: types.StopResponseStatusEmptyFilter}
+		}
+		return computeBulkStopResponse(backendState, *request.Filter)
+	}
+
 	sched := &backendState.scheduler
 
 	var response packet.StopResponseBody
-	var foundTask *scheduler.Task
+	selector := requestedTaskSelector(request.Task, request.TaskId)
 
 	sched.Lock()
 
-	// First check if task ID is valid
-	if sched.IsValidId(request.TaskId) {
-		// Look for the task in memory
-		for _, task := range sched.GetTasks() {
-			if task.Computed.Id == request.TaskId {
-				foundTask = task
-				break
-			}
+	foundTask, candidates := resolveTaskSelector(backendState, selector)
+	switch {
+	case foundTask != nil && !foundTask.Dynamic.IsDeactivated:
+		select {
+		case foundTask.Channels.StopChannel <- "manually stopped":
+		default:
+			// Channel is full, but that's okay - multiple stop signals wouldn't change anything
 		}
+		response.Status = types.StopResponseStatusSuccess
+	case foundTask != nil:
+		// Task deactivated - either paged out or still in memory. This means the task is already stopped.
+		response.Status = types.StopResponseStatusAlreadyStopped
+	case candidates != nil:
+		response.Status = types.StopResponseStatusAmbiguousTask
+		response.Candidates = candidates
+	default:
+		response.Status = types.StopResponseStatusTaskNotFound
+	}
+
+	sched.Unlock()
 
-		if foundTask != nil && !foundTask.Dynamic.IsDeactivated {
+	switch response.Status {
+	case types.StopResponseStatusSuccess:
+		backendState.messages.AddF(i.BackendMessageInfo, i.MessageKindTask, foundTask, "Stopped task %v", foundTask.Computed.Id)
+	case types.StopResponseStatusTaskNotFound:
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Task %v not found", selector)
+	case types.StopResponseStatusAlreadyStopped:
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Task %v is already stopped", selector)
+	case types.StopResponseStatusAmbiguousTask:
+		logAmbiguousTaskSelector(backendState, selector, response.Candidates)
+	default:
+		// Shouldn't happen, but let's handle it gracefully
+		backendState.messages.Add(i.BackendMessageError, i.MessageKindRequest, nil, "Unknown stop error")
+		response.Status = types.StopResponseStatusUnknown
+	}
+
+	return response
+}
+
+func computeBulkStopResponse(backendState *BackendState, filter types.TaskFilter) packet.StopResponseBody {
+	sched := &backendState.scheduler
+
+	var response packet.StopResponseBody
+	var stoppedTasks []*scheduler.Task
+
+	sched.Lock()
+
+	tasks := collectBulkTasks(backendState, filter)
+	response.Results = make([]types.BulkTaskResult, 0, len(tasks))
+	for _, task := range tasks {
+		result := createBulkTaskResult(task)
+		if task.Dynamic.IsDeactivated {
+			result.Outcome = types.BulkTaskOutcomeAlreadyStopped
+		} else {
 			select {
-			case foundTask.Channels.StopChannel <- "manually stopped":
+			case task.Channels.StopChannel <- "manually stopped":
 			default:
 				// Channel is full, but that's okay - multiple stop signals wouldn't change anything
 			}
-			response.Status = types.StopResponseStatusSuccess
+			result.Outcome = types.BulkTaskOutcomeStopped
+			stoppedTasks = append(stoppedTasks, task)
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	sched.Unlock()
+
+	for _, task := range stoppedTasks {
+		backendState.messages.AddF(i.BackendMessageInfo, i.MessageKindTask, task, "Stopped task %v", task.Computed.Id)
+	}
+	response.Status = types.StopResponseStatusSuccess
+	return response
+}
+
+func computeBulkResumeResponse(backendState *BackendState, filter types.TaskFilter) packet.ResumeResponseBody {
+	sched := &backendState.scheduler
+
+	var response packet.ResumeResponseBody
+	var resumedTasks []*scheduler.Task
+
+	sched.Lock()
+
+	tasks := collectBulkTasks(backendState, filter)
+	response.Results = make([]types.BulkTaskResult, 0, len(tasks))
+	for _, task := range tasks {
+		result := createBulkTaskResult(task)
+		if !task.Dynamic.IsDeactivated {
+			result.Outcome = types.BulkTaskOutcomeAlreadyActive
 		} else {
-			// Task deactivated - either paged out or still in memory. This means the task is already stopped.
-			response.Status = types.StopResponseStatusAlreadyStopped
+			resumedTask, status := sched.ExtractDeactivatedTask(task.Computed.Id, backendState.files, backendState.messages)
+			if status == types.RunResponseStatusSuccess {
+				status = sched.TryResumeTask(resumedTask, backendState.files, backendState.displays, backendState.sync, backendState.messages)
+			}
+			if status == types.RunResponseStatusSuccess {
+				result.Outcome = types.BulkTaskOutcomeResumed
+				resumedTasks = append(resumedTasks, resumedTask)
+			} else {
+				result.Outcome = types.BulkTaskOutcomeFailed
+				result.Error = describeRunStatus(status)
+			}
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	sched.Unlock()
+
+	for _, task := range resumedTasks {
+		backendState.messages.AddF(i.BackendMessageInfo, i.MessageKindTask, task, "Resumed task %v", task.Computed.Id)
+	}
+	for _, result := range response.Results {
+		if result.Outcome == types.BulkTaskOutcomeFailed {
+			backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Cannot resume task %v: %v", result.TaskId, result.Error)
+		}
+	}
+	response.Status = types.RunResponseStatusSuccess
+	return response
+}
+
+// hasSelectingFilter tells whether a filter of a bulk request selects tasks by anything else than their status. Such
+// filters select all tasks, so the request must ask for all tasks explicitly.
+func hasSelectingFilter(backendState *BackendState, filter *types.TaskFilter, action string) bool {
+	filter.Derive()
+	if !filter.HasAnyFilter {
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Refusing to %v all tasks without being asked for all of them", action)
+	}
+	return filter.HasAnyFilter
+}
+
+// collectBulkTasks returns tasks selected by the filter of a bulk request, see collectLatestTasks. Status selection of
+// the filter is applied after older runs of the same task are dropped, so resuming deactivated tasks doesn't pick an
+// old run of a task, which was run again and is still active.
+func collectBulkTasks(backendState *BackendState, filter types.TaskFilter) []*scheduler.Task {
+	includeActive, includeDeactivated := filter.IncludeActive, filter.IncludeDeactivated
+	filter.IncludeActive = true
+	filter.IncludeDeactivated = true
+	selector := getSelectorFunc(&filter)
+
+	tasks := collectLatestTasks(backendState, selector, includeDeactivated)
+	return slices.DeleteFunc(tasks, func(task *scheduler.Task) bool {
+		if task.Dynamic.IsDeactivated {
+			return !includeDeactivated
 		}
+		return !includeActive
+	})
+}
+
+func createBulkTaskResult(task *scheduler.Task) types.BulkTaskResult {
+	return types.BulkTaskResult{
+		TaskId:       task.Computed.Id,
+		FriendlyName: task.FriendlyName,
+		Display:      task.Display,
+	}
+}
+
+func describeRunStatus(status types.RunResponseStatus) string {
+	switch status {
+	case types.RunResponseStatusAlreadyRunning:
+		return "an identical task is already running"
+	case types.RunResponseStatusNameDisplayAlreadyRunning:
+		return "a task with the same name is already running on the display"
+	case types.RunResponseStatusInvalidDisplay:
+		return "the display is invalid"
+	case types.RunResponseStatusTaskNotFound:
+		return "task not found"
+	case types.RunResponseStatusTaskNotDeactivated:
+		return "task is active"
+	case types.RunResponseStatusEmptyFilter:
+		return "filter selects all tasks"
+	default:
+		return "unknown error"
+	}
+}
+
+func CmdMove(backendState *BackendState, frontendConnection net.Conn, request packet.MoveRequestBody) error {
+	response := computeMoveResponse(backendState, request)
+	responsePacket, err := packet.EncodeMoveResponsePacket(response)
+	if err != nil {
+		return err
+	}
+
+	return packet.SendPacket(frontendConnection, responsePacket)
+}
+
+func computeMoveResponse(backendState *BackendState, request packet.MoveRequestBody) packet.MoveResponseBody {
+	sched := &backendState.scheduler
+
+	var response packet.MoveResponseBody
+	var task *scheduler.Task
+	selector := requestedTaskSelector(request.Task, request.TaskId)
+	taskId := 0
+
+	sched.Lock()
+
+	// An active task has to be stopped first. We validate the new display before that, so a task is not stopped if
+	// it cannot be moved anyway. Stopping is asynchronous, so we have to release the lock and wait for the task to end.
+	response.Status = types.RunResponseStatusSuccess
+	if selectedTask, candidates := resolveTaskSelector(backendState, selector); selectedTask != nil {
+		taskId = selectedTask.Computed.Id
+	} else if candidates != nil {
+		response.Status = types.RunResponseStatusAmbiguousTask
+		response.Candidates = candidates
 	} else {
-		response.Status = types.StopResponseStatusTaskNotFound
+		response.Status = types.RunResponseStatusTaskNotFound
+	}
+	if response.Status == types.RunResponseStatusSuccess {
+		if task = sched.FindTask(taskId); task != nil && !task.Dynamic.IsDeactivated {
+			response.Status = sched.CheckTaskMovable(task, request.Display, backendState.displays, backendState.sync, backendState.messages)
+			if response.Status == types.RunResponseStatusSuccess {
+				select {
+				case task.Channels.StopChannel <- fmt.Sprintf("moving to %v", request.Display.ComputeDisplayLabelLong()):
+				default:
+					// Channel is full, but that's okay - the task is being stopped anyway
+				}
+				doneChannel := task.Channels.DoneChannel
+
+				sched.Unlock()
+				<-doneChannel
+				sched.Lock()
+			}
+		}
+	}
+
+	// At this point the task should be deactivated. Either it was already deactivated or we just stopped it. Restart it
+	// on the new display.
+	if response.Status == types.RunResponseStatusSuccess {
+		task, response.Status = sched.ExtractDeactivatedTask(taskId, backendState.files, backendState.messages)
+		if response.Status == types.RunResponseStatusSuccess {
+			response.Status = sched.TryMoveTask(task, request.Display, backendState.files, backendState.displays, backendState.sync, backendState.messages)
+			response.LogFile = task.Computed.OutFilePath
+			response.Id = task.Computed.Id
+		}
 	}
 
 	sched.Unlock()
 
 	switch response.Status {
-	case types.StopResponseStatusSuccess:
-		backendState.messages.AddF(i.BackendMessageInfo, foundTask, "Stopped task %v", request.TaskId)
-	case types.StopResponseStatusTaskNotFound:
-		backendState.messages.AddF(i.BackendMessageError, nil, "Task %v not found", request.TaskId)
-	case types.StopResponseStatusAlreadyStopped:
-		backendState.messages.AddF(i.BackendMessageError, nil, "Task %v is already stopped", request.TaskId)
+	case types.RunResponseStatusSuccess:
+		backendState.messages.AddF(i.BackendMessageInfo, i.MessageKindTask, task, "Moved task %v to \"%v\" display", taskId, request.Display.ComputeDisplayLabel())
+	case types.RunResponseStatusAlreadyRunning:
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Cannot move task %v, an identical task is already running on \"%v\" display", selector, request.Display.ComputeDisplayLabel())
+	case types.RunResponseStatusNameDisplayAlreadyRunning:
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Cannot move task %v, a task with the same name is already present on \"%v\" display", selector, request.Display.ComputeDisplayLabel())
+	case types.RunResponseStatusInvalidDisplay:
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Cannot move task %v to invalid display", selector)
+	case types.RunResponseStatusTaskNotFound:
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Task %v not found", selector)
+	case types.RunResponseStatusTaskNotDeactivated:
+		// The lock is released while the task is being stopped, so it could have been resumed in the meantime
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Cannot move task %v, it was resumed while being stopped", selector)
+	case types.RunResponseStatusAmbiguousTask:
+		logAmbiguousTaskSelector(backendState, selector, response.Candidates)
 	default:
 		// Shouldn't happen, but let's handle it gracefully
-		backendState.messages.Add(i.BackendMessageError, nil, "Unknown stop error")
-		response.Status = types.StopResponseStatusUnknown
+		backendState.messages.Add(i.BackendMessageError, i.MessageKindRequest, nil, "Unknown moving error")
+		response.Status = types.RunResponseStatusUnknown
 	}
 
-	responsePacket, err := packet.EncodeStopResponsePacket(response)
+	return response
+}
+
+func CmdTaskLogs(backendState *BackendState, frontendConnection net.Conn, request packet.TaskLogsRequestBody) error {
+	response := computeTaskLogsResponse(backendState, request)
+	responsePacket, err := packet.EncodeTaskLogsResponsePacket(response)
+	if err != nil {
+		return err
+	}
+
+	return packet.SendPacket(frontendConnection, responsePacket)
+}
+
+func computeTaskLogsResponse(backendState *BackendState, request packet.TaskLogsRequestBody) packet.TaskLogsResponseBody {
+	sched := &backendState.scheduler
+
+	var response packet.TaskLogsResponseBody
+	var grep *regexp.Regexp
+	var lastExecutionId int
+
+	// Validate the request
+	if request.Grep != "" {
+		var err error
+		grep, err = regexp.Compile(request.Grep)
+		if err != nil {
+			response.Status = types.TaskLogsResponseStatusInvalidRequest
+			response.Error = fmt.Sprintf("invalid grep expression: %v", err)
+		}
+	}
+	if request.Source != packet.TaskLogsSourceTaskLog && (!request.Since.IsZero() || !request.Until.IsZero()) {
+		// Captured output has no timestamps, so lines cannot be filtered by time
+		response.Status = types.TaskLogsResponseStatusInvalidRequest
+		response.Error = "since and until cannot be used with stdout or stderr, because captured output has no timestamps"
+	}
+
+	// Find the task. Only copy what we need, so the files can be read without holding the lock.
+	if response.Status == types.TaskLogsResponseStatusSuccess {
+		sched.Lock()
+		task, candidates := resolveTaskSelector(backendState, request.Task)
+		if task != nil {
+			response.TaskId = task.Computed.Id
+			response.LogFile = task.Computed.OutFilePath
+			response.IsActive = !task.Dynamic.IsDeactivated
+
+			// Active task has its current execution in progress. Deactivated task only has finished executions.
+			lastExecutionId = task.Dynamic.RunCount
+			if task.Dynamic.IsDeactivated {
+				lastExecutionId--
+			}
+		} else if candidates != nil {
+			response.Status = types.TaskLogsResponseStatusAmbiguousTask
+			response.Candidates = candidates
+		} else {
+			response.Status = types.TaskLogsResponseStatusTaskNotFound
+		}
+		sched.Unlock()
+	}
+
+	// Read and filter the logs. The last execution is resolved, so the frontend can follow the same execution.
+	if response.Status == types.TaskLogsResponseStatusSuccess {
+		if request.Execution == types.ExecutionSelectionLast {
+			request.Execution = lastExecutionId
+		}
+		response.Execution = request.Execution
+		isExecutionSelected := request.MatchesExecution
+
+		var records []types.TaskLogRecord
+		var err error
+		switch request.Source {
+		case packet.TaskLogsSourceTaskLog:
+			records, response.LogFileOffset, err = readTaskLogRecords(backendState.files, response.TaskId)
+		case packet.TaskLogsSourceStdout:
+			records, err = readExecutionOutputRecords(backendState.files, response.TaskId, types.TaskLogStreamStdout, isExecutionSelected)
+		case packet.TaskLogsSourceStderr:
+			records, err = readExecutionOutputRecords(backendState.files, response.TaskId, types.TaskLogStreamStderr, isExecutionSelected)
+		default:
+			response.Status = types.TaskLogsResponseStatusInvalidRequest
+			response.Error = "invalid log source"
+		}
+
+		// Follow mode always reads new records from the task log, so let the frontend know where to start.
+		if request.Source != packet.TaskLogsSourceTaskLog {
+			if fileInfo, err := os.Stat(response.LogFile); err == nil {
+				response.LogFileOffset = fileInfo.Size()
+			}
+		}
+
+		if err != nil {
+			response.Status = types.TaskLogsResponseStatusReadError
+			response.Error = err.Error()
+		} else {
+			response.Records = filterTaskLogRecords(records, &request, grep)
+		}
+	}
+
+	switch response.Status {
+	case types.TaskLogsResponseStatusSuccess:
+	case types.TaskLogsResponseStatusTaskNotFound:
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Task %v not found", request.Task)
+	case types.TaskLogsResponseStatusInvalidRequest:
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Invalid logs request: %v", response.Error)
+	case types.TaskLogsResponseStatusReadError:
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindLogs, nil, "Failed reading logs of task %v: %v", response.TaskId, response.Error)
+	case types.TaskLogsResponseStatusAmbiguousTask:
+		logAmbiguousTaskSelector(backendState, request.Task, response.Candidates)
+	default:
+		// Shouldn't happen, but let's handle it gracefully
+		backendState.messages.Add(i.BackendMessageError, i.MessageKindRequest, nil, "Unknown logs error")
+		response.Status = types.TaskLogsResponseStatusUnknown
+	}
+
+	return response
+}
+
+func CmdHistory(backendState *BackendState, frontendConnection net.Conn, request packet.HistoryRequestBody) error {
+	response := computeHistoryResponse(backendState, request)
+	responsePacket, err := packet.EncodeHistoryResponsePacket(response)
 	if err != nil {
 		return err
 	}
 
 	return packet.SendPacket(frontendConnection, responsePacket)
 }
+
+func computeHistoryResponse(backendState *BackendState, request packet.HistoryRequestBody) packet.HistoryResponseBody {
+	sched := &backendState.scheduler
+
+	var response packet.HistoryResponseBody
+
+	sched.Lock()
+	task, candidates := resolveTaskSelector(backendState, request.Task)
+	if task != nil {
+		response.TaskId = task.Computed.Id
+		response.FriendlyName = task.FriendlyName
+		response.IsActive = !task.Dynamic.IsDeactivated
+		response.RunCount = task.Dynamic.RunCount
+		response.TotalUsage = task.Dynamic.TotalUsage
+		response.Executions = task.Dynamic.History // never modified in place, safe to use without the lock
+	} else if candidates != nil {
+		response.Status = types.HistoryResponseStatusAmbiguousTask
+		response.Candidates = candidates
+	} else {
+		response.Status = types.HistoryResponseStatusTaskNotFound
+	}
+	sched.Unlock()
+
+	if response.Executions == nil {
+		response.Executions = []types.ExecutionRecord{}
+	}
+
+	switch response.Status {
+	case types.HistoryResponseStatusSuccess:
+	case types.HistoryResponseStatusTaskNotFound:
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Task %v not found", request.Task)
+	case types.HistoryResponseStatusAmbiguousTask:
+		logAmbiguousTaskSelector(backendState, request.Task, response.Candidates)
+	default:
+		// Shouldn't happen, but let's handle it gracefully
+		backendState.messages.Add(i.BackendMessageError, i.MessageKindRequest, nil, "Unknown history error")
+		response.Status = types.HistoryResponseStatusUnknown
+	}
+
+	return response
+}
+
+func CmdInspect(backendState *BackendState, frontendConnection net.Conn, request packet.InspectRequestBody) error {
+	response := computeInspectResponse(backendState, request)
+	responsePacket, err := packet.EncodeInspectResponsePacket(response)
+	if err != nil {
+		return err
+	}
+
+	return packet.SendPacket(frontendConnection, responsePacket)
+}
+
+// inspectedExecutionsCount is the number of most recent executions returned by the inspect command. Use the history
+// command to see all of them.
+const inspectedExecutionsCount = 5
+
+func computeInspectResponse(backendState *BackendState, request packet.InspectRequestBody) packet.InspectResponseBody {
+	sched := &backendState.scheduler
+	now := time.Now()
+
+	var response packet.InspectResponseBody
+	currentPid := 0
+
+	sched.Lock()
+	task, candidates := resolveTaskSelector(backendState, request.Task)
+	if task != nil {
+		response.Id = task.Computed.Id
+		response.FriendlyName = task.FriendlyName
+		response.Cmdline = task.Cmdline
+		response.Cwd = task.Cwd
+		response.Env = maskSecretEnvValues(task.Env)
+		response.Display = task.Display
+		response.Tags = task.Tags
+		response.CaptureStdout = task.CaptureStdout
+		response.CaptureStderr = task.CaptureStderr
+		response.DelayAfterSuccessMs = task.DelayAfterSuccessMs
+		response.DelayAfterFailureMs = task.DelayAfterFailureMs
+		response.MaxSubsequentFailures = task.MaxSubsequentFailures
+		response.LogRetention = task.LogRetention
+		response.LogFormat = task.LogFormat
+		response.LogSinks = task.LogSinks
+
+		response.Hash = task.Computed.Hash
+		response.NameDisplayHash = task.Computed.NameDisplayHash
+
+		response.IsDeactivated = task.Dynamic.IsDeactivated
+		response.DeactivationReason = task.Dynamic.DeactivatedReason
+		response.RunCount = task.Dynamic.RunCount
+		response.FailureCount = task.Dynamic.FailureCount
+		response.SubsequentFailureCount = task.Dynamic.SubsequentFailureCount
+		response.LastExitValue = task.Dynamic.LastExitValue
+		currentPid = task.Dynamic.CurrentPid
+		if currentPid != 0 {
+			response.CurrentStartTime = task.Dynamic.LastStartTime
+			response.CurrentUptime = now.Sub(task.Dynamic.LastStartTime)
+		}
+		if !task.Dynamic.NextRunTime.IsZero() {
+			response.NextRunTime = task.Dynamic.NextRunTime
+			response.TimeUntilNextRun = max(task.Dynamic.NextRunTime.Sub(now), 0)
+		}
+		history := task.Dynamic.History // never modified in place, safe to use without the lock
+		response.RecentExecutions = history[max(len(history)-inspectedExecutionsCount, 0):]
+		response.TotalUsage = task.Dynamic.TotalUsage
+
+		response.LogFile = task.Computed.OutFilePath
+		response.LastStdoutFilePath = task.Dynamic.LastStdoutFilePath
+		response.LastStderrFilePath = task.Dynamic.LastStderrFilePath
+	} else if candidates != nil {
+		response.Status = types.InspectResponseStatusAmbiguousTask
+		response.Candidates = candidates
+	} else {
+		response.Status = types.InspectResponseStatusTaskNotFound
+	}
+	sched.Unlock()
+
+	if response.Status == types.InspectResponseStatusSuccess {
+		// These don't need the scheduler, so don't block it while walking /proc
+		response.DisplayState = backendState.displays.GetDisplayState(response.Display)
+		response.Pids = getProcessTree(currentPid)
+	}
+	if response.RecentExecutions == nil {
+		response.RecentExecutions = []types.ExecutionRecord{}
+	}
+
+	switch response.Status {
+	case types.InspectResponseStatusSuccess:
+	case types.InspectResponseStatusTaskNotFound:
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Task %v not found", request.Task)
+	case types.InspectResponseStatusAmbiguousTask:
+		logAmbiguousTaskSelector(backendState, request.Task, response.Candidates)
+	default:
+		// Shouldn't happen, but let's handle it gracefully
+		backendState.messages.Add(i.BackendMessageError, i.MessageKindRequest, nil, "Unknown inspect error")
+		response.Status = types.InspectResponseStatusUnknown
+	}
+
+	return response
+}
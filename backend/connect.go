@@ -1,8 +1,10 @@
 package backend
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	i "spieven/backend/interfaces"
@@ -12,7 +14,9 @@ import (
 	"spieven/common/types"
 )
 
-func ValidateHandshake(connection net.Conn, backendState *BackendState) error {
+// ValidateHandshake receives the protocol version of the frontend and responds with its own. Versions are compatible,
+// if their major numbers match.
+func ValidateHandshake(connection net.Conn) error {
 	requestPacket, err := packet.ReceivePacket(connection)
 	if err != nil {
 		return err
@@ -22,32 +26,58 @@ func ValidateHandshake(connection net.Conn, backendState *BackendState) error {
 	if err != nil {
 		return err
 	}
-	handshakeValue := uint64(request)
 
-	if backendState.handshakeValue != handshakeValue {
-		return fmt.Errorf("invalid handshake value: expected %v, got %v", backendState.handshakeValue, handshakeValue)
+	response := packet.HandshakeResponseBody{
+		Status:         packet.HandshakeResponseStatusAccepted,
+		ProtocolMajor:  packet.ProtocolVersionMajor,
+		ProtocolMinor:  packet.ProtocolVersionMinor,
+		Capabilities:   packet.SupportedCapabilities,
+		BackendVersion: common.Version,
+	}
+	if request.ProtocolMajor != packet.ProtocolVersionMajor {
+		response.Status = packet.HandshakeResponseStatusIncompatibleVersion
+	}
+
+	responsePacket, err := packet.EncodeHandshakeResponsePacket(response)
+	if err != nil {
+		return err
+	}
+	err = packet.SendPacket(connection, responsePacket)
+	if err != nil {
+		return err
 	}
 
+	if response.Status != packet.HandshakeResponseStatusAccepted {
+		return fmt.Errorf("incompatible protocol version %v.%v", request.ProtocolMajor, request.ProtocolMinor)
+	}
 	return nil
 }
 
 func HandleConnection(backendState *BackendState, connection net.Conn) {
 	defer connection.Close()
+	backendState.connectionsServed.Add(1)
 
 	// Start a routine that will close the connection, when the backend is killed, so that below loop exits
 	backendState.sync.StartGoroutineAfterContextKill(func() {
 		connection.Close()
 	})
 
-	// Handle handshake with the frontend
-	if buildopts.HandshakeValidationEnabled {
-		err := ValidateHandshake(connection, backendState)
+	// Remote frontends have to authenticate first
+	if tlsConnection, ok := connection.(*tls.Conn); ok {
+		err := authenticateConnection(backendState, tlsConnection)
 		if err != nil {
-			backendState.messages.AddF(i.BackendMessageInfo, nil, "Rejecting frontend request due to invalid handshake")
+			backendState.messages.AddF(i.BackendMessageError, i.MessageKindConnection, nil, "Rejecting remote connection from %v: %v", connection.RemoteAddr(), err)
 			return
 		}
 	}
 
+	// Handle handshake with the frontend
+	err := ValidateHandshake(connection)
+	if err != nil {
+		backendState.messages.AddF(i.BackendMessageInfo, i.MessageKindConnection, nil, "Rejecting frontend request due to invalid handshake: %v", err)
+		return
+	}
+
 	// Handle any packets that are sent until connection is closed
 	for {
 		requestPacket, err := packet.ReceivePacket(connection)
@@ -57,11 +87,16 @@ func HandleConnection(backendState *BackendState, connection net.Conn) {
 
 		switch requestPacket.Id {
 		case packet.PacketIdLog:
-			err := packet.DecodeLogPacket(requestPacket)
+			request, err := packet.DecodeLogPacket(requestPacket)
 			if err != nil {
 				return
 			}
-			err = CmdLog(backendState, connection)
+			if request.Follow {
+				// Following takes over the connection until it's closed
+				CmdLog(backendState, connection, request)
+				return
+			}
+			err = CmdLog(backendState, connection, request)
 			if err != nil {
 				return
 			}
@@ -83,15 +118,14 @@ func HandleConnection(backendState *BackendState, connection net.Conn) {
 			if err != nil {
 				return
 			}
-		case packet.PacketIdQueryTaskActive:
-			request, err := packet.DecodeQueryTaskActivePacket(requestPacket)
-			if err != nil {
-				return
-			}
-			err = CmdQueryTaskActive(backendState, connection, request)
+		case packet.PacketIdFollowTaskLog:
+			request, err := packet.DecodeFollowTaskLogPacket(requestPacket)
 			if err != nil {
 				return
 			}
+			// Following takes over the connection until the task is deactivated
+			CmdFollowTaskLog(backendState, connection, request)
+			return
 		case packet.PacketIdRefresh:
 			request, err := packet.DecodeRefreshPacket(requestPacket)
 			if err != nil {
@@ -119,15 +153,74 @@ func HandleConnection(backendState *BackendState, connection net.Conn) {
 			if err != nil {
 				return
 			}
-		default:
-			backendState.messages.AddF(i.BackendMessageInfo, nil, "Rejecting frontend request due to invalid packet")
+		case packet.PacketIdMove:
+			request, err := packet.DecodeMovePacket(requestPacket)
+			if err != nil {
+				return
+			}
+			err = CmdMove(backendState, connection, request)
+			if err != nil {
+				return
+			}
+		case packet.PacketIdTaskLogs:
+			request, err := packet.DecodeTaskLogsPacket(requestPacket)
+			if err != nil {
+				return
+			}
+			err = CmdTaskLogs(backendState, connection, request)
+			if err != nil {
+				return
+			}
+		case packet.PacketIdHistory:
+			request, err := packet.DecodeHistoryPacket(requestPacket)
+			if err != nil {
+				return
+			}
+			err = CmdHistory(backendState, connection, request)
+			if err != nil {
+				return
+			}
+		case packet.PacketIdInspect:
+			request, err := packet.DecodeInspectPacket(requestPacket)
+			if err != nil {
+				return
+			}
+			err = CmdInspect(backendState, connection, request)
+			if err != nil {
+				return
+			}
+		case packet.PacketIdEvents:
+			request, err := packet.DecodeEventsPacket(requestPacket)
+			if err != nil {
+				return
+			}
+			// Subscription takes over the connection until it's closed
+			CmdEvents(backendState, connection, request)
 			return
+		default:
+			// Packet body has already been read, so the connection can still be used after responding with an error
+			backendState.messages.AddF(i.BackendMessageInfo, i.MessageKindConnection, nil, "Rejecting unknown frontend request %v", requestPacket.Id)
+			err = sendUnknownPacketError(connection, requestPacket.Id)
+			if err != nil {
+				return
+			}
 		}
 
 	}
 }
 
-func RunServer(frequentTrim bool, allowRemoteConnections bool, displayKillGracePeriod time.Duration, port int) error {
+// ListenOptions describe how frontends can connect to the backend. Unix socket is always used. It's the default
+// transport and is protected by checking credentials of the connecting process.
+type ListenOptions struct {
+	Tcp          bool   // additionally listen on a TCP port on localhost
+	Remote       bool   // additionally listen on a TCP port on all interfaces with TLS and accept remote connections
+	Socket       string // path of an additional unix socket, which can be shared with AllowedGroup
+	AllowedGroup string // group whose members can connect to Socket and to the HTTP API socket, in addition to the owner
+	AuthDir      string // directory with credentials for remote connections, empty means the default one
+	Http         string // address of the HTTP API, empty means it's disabled
+}
+
+func RunServer(frequentTrim bool, listenOptions ListenOptions, displayKillGracePeriod time.Duration, port int, logRetention types.LogRetention, logSinks types.LogSinks) error {
 	common.SetDisplayEnvVarsForCurrentProcess(types.DisplaySelection{Type: types.DisplaySelectionTypeHeadless})
 
 	// Determine port to use
@@ -136,50 +229,159 @@ func RunServer(frequentTrim bool, allowRemoteConnections bool, displayKillGraceP
 		portStr = fmt.Sprintf("%d", port)
 	}
 
-	backendState, err := CreateBackendState(frequentTrim, displayKillGracePeriod, portStr)
+	allowedGroupId := -1
+	if listenOptions.AllowedGroup != "" {
+		var err error
+		allowedGroupId, err = lookupGroupId(listenOptions.AllowedGroup)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Remote connections require TLS and authentication
+	var tlsConfig *tls.Config
+	var authToken string
+	var httpAuthToken string
+	isHttpOnTcp := listenOptions.Http != "" && !strings.HasPrefix(listenOptions.Http, "unix://")
+	if listenOptions.Remote || isHttpOnTcp {
+		authDir := listenOptions.AuthDir
+		if authDir == "" {
+			var err error
+			authDir, err = common.GetDefaultAuthDir()
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if listenOptions.Remote {
+			tlsConfig, authToken, err = loadServerAuth(authDir)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Any local user can connect to a TCP port, so the HTTP API on TCP requires the token as well
+		if isHttpOnTcp {
+			httpAuthToken, err = loadAuthToken(authDir)
+			if err != nil {
+				return fmt.Errorf("HTTP API on a TCP address requires a token: %w", err)
+			}
+		}
+	}
+
+	backendState, err := CreateBackendState(frequentTrim, displayKillGracePeriod, portStr, logRetention, logSinks)
 	if err != nil {
 		return err
 	}
 
-	// Calculate hash used for verifying frontend requests
-	handshakeValue, err := common.CalculateSpievenFileHash()
+	backendState.authToken = authToken
+	backendState.httpAuthToken = httpAuthToken
+
+	// Create sockets. The default unix socket is in a private runtime directory, which only the owner can access, so
+	// members of the allowed group can only use the additional socket.
+	var listeners []net.Listener
+	var sharedListener net.Listener
+	defer func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}()
+	unixSocketPath, err := common.GetUnixSocketPath(portStr)
 	if err != nil {
-		return nil
+		return err
 	}
-	backendState.handshakeValue = handshakeValue
-
-	// Create socket
-	listener, err := net.Listen("tcp4", fmt.Sprintf("localhost:%s", portStr))
+	unixListener, err := listenUnixSocket(unixSocketPath, -1)
 	if err != nil {
 		return err
 	}
-	defer listener.Close()
+	listeners = append(listeners, unixListener)
+	if listenOptions.Socket != "" {
+		sharedListener, err = listenUnixSocket(listenOptions.Socket, allowedGroupId)
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, sharedListener)
+	}
+	if listenOptions.Remote {
+		tcpListener, err := net.Listen("tcp4", fmt.Sprintf("0.0.0.0:%s", portStr))
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, tls.NewListener(tcpListener, tlsConfig))
+	} else if listenOptions.Tcp {
+		tcpListener, err := net.Listen("tcp4", fmt.Sprintf("localhost:%s", portStr))
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, tcpListener)
+	}
+
+	var httpListener net.Listener
+	if listenOptions.Http != "" {
+		httpListener, err = listenHttpApi(backendState, listenOptions.Http, allowedGroupId)
+		if err != nil {
+			return err
+		}
+	}
 
-	// Start a routine that will close the socket, when the backend is killed, so that below loop exits
+	// Start a routine that will close the sockets, when the backend is killed, so that accepting loops exit
 	backendState.sync.StartGoroutineAfterContextKill(func() {
-		listener.Close()
+		for _, listener := range listeners {
+			listener.Close()
+		}
 	})
 
-	// Listen for connections
-	var serverErr error
+	// Listen for connections until any of the sockets fails or the backend is killed
+	serverErrors := make(chan error, len(listeners)+1)
+	for _, listener := range listeners {
+		listenerGroupId := -1
+		if listener == sharedListener {
+			listenerGroupId = allowedGroupId
+		}
+		go func() {
+			serverErrors <- acceptConnections(backendState, listener, listenerGroupId)
+		}()
+	}
+	if httpListener != nil {
+		go func() {
+			serverErrors <- serveHttpApi(backendState, httpListener)
+		}()
+	}
+	serverErr := <-serverErrors
+
+	// Notify all goroutines that we have to exit and wait for them.
+	backendState.sync.killContext()
+	backendState.sync.waitGroup.Wait()
+
+	return serverErr
+}
+
+func acceptConnections(backendState *BackendState, listener net.Listener, allowedGroupId int) error {
 	for {
 		connection, err := listener.Accept()
 		if err != nil {
 			if backendState.sync.IsContextKilled() {
 				// We canceled the server for some reason. Not an error. We could store some error
 				// in the future and return it here, though.
-				serverErr = fmt.Errorf("user interrupt detected")
+				return fmt.Errorf("user interrupt detected")
 			} else {
 				// The socket really returned an error. Return it to caller.
-				serverErr = fmt.Errorf("server failure %w", err)
+				return fmt.Errorf("server failure %w", err)
 			}
-			break
 		}
 
-		if !allowRemoteConnections {
-			ip := connection.RemoteAddr().(*net.TCPAddr).IP
-			if !ip.IsLoopback() {
-				backendState.messages.Add(i.BackendMessageError, nil, "Rejecting remote connection")
+		switch typedConnection := connection.(type) {
+		case *net.UnixConn:
+			if err := checkPeerCredentials(typedConnection, allowedGroupId); err != nil {
+				backendState.messages.AddF(i.BackendMessageError, i.MessageKindConnection, nil, "Rejecting unix socket connection: %v", err)
+				connection.Close()
+				continue
+			}
+		case *net.TCPConn:
+			// Plain TCP is only for local connections. Remote ones come through TLS and are authenticated later.
+			if !typedConnection.RemoteAddr().(*net.TCPAddr).IP.IsLoopback() {
+				backendState.messages.Add(i.BackendMessageError, i.MessageKindConnection, nil, "Rejecting remote connection")
 				connection.Close()
 				continue
 			}
@@ -189,10 +391,17 @@ func RunServer(frequentTrim bool, allowRemoteConnections bool, displayKillGraceP
 			HandleConnection(backendState, connection)
 		})
 	}
+}
 
-	// Notify all goroutines that we have to exit and wait for them.
-	backendState.sync.killContext()
-	backendState.sync.waitGroup.Wait()
-
-	return serverErr
+func sendUnknownPacketError(connection net.Conn, requestId packet.PacketId) error {
+	response := packet.ErrorResponseBody{
+		RequestId: requestId,
+		Error: fmt.Sprintf("unknown request %v, backend %v supports protocol version %v.%v",
+			requestId, common.Version, packet.ProtocolVersionMajor, packet.ProtocolVersionMinor),
+	}
+	responsePacket, err := packet.EncodeErrorResponsePacket(response)
+	if err != nil {
+		return err
+	}
+	return packet.SendPacket(connection, responsePacket)
 }
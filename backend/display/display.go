@@ -25,6 +25,7 @@ func newDisplay(
 	scheduler i.IScheduler,
 	goroutines i.IGoroutines,
 	messages i.IMessages,
+	events i.IEvents,
 	killGracePeriod time.Duration,
 ) (*Display, error) {
 	// First try to connect to the display server. If it cannot be done, the passed display name is invalid.
@@ -72,7 +73,8 @@ func newDisplay(
 
 		// If we are here, it means the display server is dead, but spieven is still running. Kill all tasks running on
 		// this display. Give them some grace period to detect closure of the display and terminate nicely.
-		messages.AddF(i.BackendMessageInfo, nil, "Display %v has been closed. Killing all its tasks in %s", displaySelection.ComputeDisplayLabelLong(), killGracePeriod)
+		events.EmitDisplay(i.EventDisplayVanished, displaySelection)
+		messages.AddF(i.BackendMessageInfo, i.MessageKindDisplay, nil, "Display %v has been closed. Killing all its tasks in %s", displaySelection.ComputeDisplayLabelLong(), killGracePeriod)
 		timer := time.NewTimer(killGracePeriod)
 		defer timer.Stop()
 		select {
@@ -84,7 +86,7 @@ func newDisplay(
 		}
 
 		// Display is closed. Stop all tasks using it.
-		messages.AddF(i.BackendMessageInfo, nil, "Killing all tasks on display %v", displaySelection.ComputeDisplayLabelLong())
+		messages.AddF(i.BackendMessageInfo, i.MessageKindDisplay, nil, "Killing all tasks on display %v", displaySelection.ComputeDisplayLabelLong())
 		displaysLock.Lock()
 		scheduler.Lock()
 		result.isDeactivated = true
@@ -93,5 +95,6 @@ func newDisplay(
 		displaysLock.Unlock()
 	})
 
+	events.EmitDisplay(i.EventDisplayAppeared, displaySelection)
 	return &result, nil
 }
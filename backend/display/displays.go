@@ -9,6 +9,7 @@ import (
 )
 
 type Displays struct {
+	events           i.IEvents
 	killGracePeriod  time.Duration
 	xorgSupported    bool
 	waylandSupported bool
@@ -18,22 +19,23 @@ type Displays struct {
 	_    common.NoCopy
 }
 
-func CreateDisplays(messages i.IMessages, killGracePeriod time.Duration) *Displays {
+func CreateDisplays(messages i.IMessages, events i.IEvents, killGracePeriod time.Duration) *Displays {
 	xorgSupported := true
 	xorgErr := common.LoadXorgLibs()
 	if xorgErr != nil {
 		xorgSupported = false
-		messages.Add(i.BackendMessageInfo, nil, "Xorg libraries could not be loaded. Tasks with xorg display will not be accepted")
+		messages.Add(i.BackendMessageInfo, i.MessageKindBackend, nil, "Xorg libraries could not be loaded. Tasks with xorg display will not be accepted")
 	}
 
 	waylandSupported := true
 	waylandErr := common.LoadWaylandLibs()
 	if waylandErr != nil {
 		waylandSupported = false
-		messages.Add(i.BackendMessageInfo, nil, "Wayland libraries could not be loaded. Tasks with wayland display will not be accepted")
+		messages.Add(i.BackendMessageInfo, i.MessageKindBackend, nil, "Wayland libraries could not be loaded. Tasks with wayland display will not be accepted")
 	}
 
 	return &Displays{
+		events:           events,
 		killGracePeriod:  killGracePeriod,
 		xorgSupported:    xorgSupported,
 		waylandSupported: waylandSupported,
@@ -71,7 +73,7 @@ func (displays *Displays) InitDisplay(displaySelection types.DisplaySelection, s
 	}
 
 	// Create a new display and store it
-	newDisplay, err := newDisplay(displaySelection, &displays.lock, scheduler, goroutines, messages, displays.killGracePeriod)
+	newDisplay, err := newDisplay(displaySelection, &displays.lock, scheduler, goroutines, messages, displays.events, displays.killGracePeriod)
 	if err != nil {
 		return err
 	}
@@ -80,6 +82,48 @@ func (displays *Displays) InitDisplay(displaySelection types.DisplaySelection, s
 	return nil
 }
 
+// GetActiveDisplayCount returns the number of displays which are watched and can be used by tasks.
+func (displays *Displays) GetActiveDisplayCount() int {
+	displays.lock.Lock()
+	defer displays.lock.Unlock()
+
+	count := 0
+	for _, currDisplay := range displays.displays {
+		if !currDisplay.isDeactivated {
+			count++
+		}
+	}
+	return count
+}
+
+// GetDisplayState tells whether tasks can currently run on a display.
+func (displays *Displays) GetDisplayState(displaySelection types.DisplaySelection) types.DisplayState {
+	displays.lock.Lock()
+	defer displays.lock.Unlock()
+
+	switch displaySelection.Type {
+	case types.DisplaySelectionTypeHeadless:
+		return types.DisplayStateHeadless
+	case types.DisplaySelectionTypeXorg:
+		if !displays.xorgSupported {
+			return types.DisplayStateUnsupported
+		}
+	case types.DisplaySelectionTypeWayland:
+		if !displays.waylandSupported {
+			return types.DisplayStateUnsupported
+		}
+	default:
+		return types.DisplayStateUnsupported
+	}
+
+	for _, currDisplay := range displays.displays {
+		if !currDisplay.isDeactivated && currDisplay.selection == displaySelection {
+			return types.DisplayStateActive
+		}
+	}
+	return types.DisplayStateInactive
+}
+
 func (displays *Displays) Trim() {
 	displays.lock.Lock()
 	defer displays.lock.Unlock()
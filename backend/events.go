@@ -0,0 +1,74 @@
+package backend
+
+import (
+	i "spieven/backend/interfaces"
+	"spieven/common"
+	"spieven/common/types"
+	"sync"
+	"time"
+)
+
+// eventSubscriberBuffer is the number of events which can wait for a slow subscriber, before newer ones are dropped.
+const eventSubscriberBuffer = 256
+
+// BackendEvents distributes events to subscribers. Events are not stored, so subscribers only receive events emitted
+// after they subscribed.
+type BackendEvents struct {
+	messages    i.IMessages
+	subscribers map[chan types.Event]struct{}
+	lock        sync.Mutex
+
+	_ common.NoCopy
+}
+
+func CreateBackendEvents(messages i.IMessages) *BackendEvents {
+	return &BackendEvents{
+		messages:    messages,
+		subscribers: make(map[chan types.Event]struct{}),
+	}
+}
+
+func (events *BackendEvents) Emit(eventType i.EventType, task i.ITask, event types.Event) {
+	event.Time = time.Now()
+	event.Type = eventType
+	if task != nil {
+		taskId := task.GetId()
+		display := task.GetDisplay()
+		event.TaskId = &taskId
+		event.TaskName = task.GetFriendlyName()
+		event.Display = display.ComputeDisplayLabel()
+	}
+
+	events.lock.Lock()
+	defer events.lock.Unlock()
+
+	for channel := range events.subscribers {
+		select {
+		case channel <- event:
+		default:
+			events.messages.AddF(i.BackendMessageError, i.MessageKindConnection, task, "Dropping %v event for a subscriber not keeping up", eventType)
+		}
+	}
+}
+
+func (events *BackendEvents) EmitDisplay(eventType i.EventType, display types.DisplaySelection) {
+	events.Emit(eventType, nil, types.Event{Display: display.ComputeDisplayLabel()})
+}
+
+// Subscribe returns a channel receiving all events emitted from now on. It must be passed to Unsubscribe when no
+// longer used.
+func (events *BackendEvents) Subscribe() chan types.Event {
+	events.lock.Lock()
+	defer events.lock.Unlock()
+
+	channel := make(chan types.Event, eventSubscriberBuffer)
+	events.subscribers[channel] = struct{}{}
+	return channel
+}
+
+func (events *BackendEvents) Unsubscribe(channel chan types.Event) {
+	events.lock.Lock()
+	defer events.lock.Unlock()
+
+	delete(events.subscribers, channel)
+}
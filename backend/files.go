@@ -7,6 +7,8 @@ import (
 	"path"
 	"path/filepath"
 	"spieven/common"
+	"strconv"
+	"strings"
 )
 
 type FilePathProvider struct {
@@ -77,6 +79,80 @@ func (files *FilePathProvider) GetTaskLogFile(taskId int) string {
 	return path.Join(files.TaskLogsDir, fileName)
 }
 
+func (files *FilePathProvider) GetRotatedTaskLogFile(taskId int) string {
+	return files.GetTaskLogFile(taskId) + ".1"
+}
+
+// GetExecutionLogFiles returns paths to all stdout/stderr files of a given task that currently exist on disk, including
+// compressed ones. The paths are grouped by execution id.
+func (files *FilePathProvider) GetExecutionLogFiles(taskId int) (map[int][]string, error) {
+	allFiles, err := files.GetAllExecutionLogFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	result := allFiles[taskId]
+	if result == nil {
+		result = make(map[int][]string)
+	}
+	return result, nil
+}
+
+// GetAllExecutionLogFiles is like GetExecutionLogFiles, but for all tasks at once, so the directory is only read once.
+// The paths are grouped by task id and then by execution id.
+func (files *FilePathProvider) GetAllExecutionLogFiles() (map[int]map[int][]string, error) {
+	dirEntries, err := os.ReadDir(files.TaskLogsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int]map[int][]string)
+	for _, entry := range dirEntries {
+		taskId, executionId, found := parseExecutionLogFileName(entry.Name())
+		if !found {
+			continue
+		}
+
+		if result[taskId] == nil {
+			result[taskId] = make(map[int][]string)
+		}
+		result[taskId][executionId] = append(result[taskId][executionId], path.Join(files.TaskLogsDir, entry.Name()))
+	}
+	return result, nil
+}
+
+// parseExecutionLogFileName extracts ids from a name created by GetStdoutStderrLogFiles, optionally compressed.
+func parseExecutionLogFileName(name string) (taskId int, executionId int, found bool) {
+	name, found = strings.CutPrefix(name, "task_")
+	if !found {
+		return 0, 0, false
+	}
+
+	name = strings.TrimSuffix(name, ".gz")
+	name, found = strings.CutSuffix(name, ".log")
+	if !found {
+		return 0, 0, false
+	}
+
+	taskIdStr, name, found := strings.Cut(name, "_")
+	if !found {
+		return 0, 0, false
+	}
+	if !strings.HasPrefix(name, "stdout_") && !strings.HasPrefix(name, "stderr_") {
+		return 0, 0, false
+	}
+
+	taskId, err := strconv.Atoi(taskIdStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	executionId, err = strconv.Atoi(name[len("stdout_"):])
+	if err != nil {
+		return 0, 0, false
+	}
+	return taskId, executionId, true
+}
+
 func (files *FilePathProvider) GetStdoutStderrLogFiles(taskId int, executionId int) (string, string) {
 	stdoutFileName := fmt.Sprintf("task_%03d_stdout_%03d.log", taskId, executionId)
 	stderrFileName := fmt.Sprintf("task_%03d_stderr_%03d.log", taskId, executionId)
@@ -0,0 +1,38 @@
+package backend
+
+import (
+	"testing"
+)
+
+func TestParseExecutionLogFileName(t *testing.T) {
+	tests := []struct {
+		name                string
+		expectedFound       bool
+		expectedTaskId      int
+		expectedExecutionId int
+	}{
+		{name: "task_003_stdout_012.log", expectedFound: true, expectedTaskId: 3, expectedExecutionId: 12},
+		{name: "task_003_stderr_000.log.gz", expectedFound: true, expectedTaskId: 3, expectedExecutionId: 0},
+		{name: "task_1234_stdout_5678.log", expectedFound: true, expectedTaskId: 1234, expectedExecutionId: 5678},
+		{name: "task_003.log", expectedFound: false},
+		{name: "task_003.log.1", expectedFound: false},
+		{name: "task_003.log.1.gz", expectedFound: false},
+		{name: "task_003_stdout_012.txt", expectedFound: false},
+		{name: "task_003_other_012.log", expectedFound: false},
+		{name: "task_abc_stdout_012.log", expectedFound: false},
+		{name: "task_003_stdout_abc.log", expectedFound: false},
+		{name: "backend_messages.log", expectedFound: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			taskId, executionId, found := parseExecutionLogFileName(test.name)
+			if found != test.expectedFound {
+				t.Fatalf("expected found %v, got %v", test.expectedFound, found)
+			}
+			if found && (taskId != test.expectedTaskId || executionId != test.expectedExecutionId) {
+				t.Fatalf("expected task %v execution %v, got task %v execution %v", test.expectedTaskId, test.expectedExecutionId, taskId, executionId)
+			}
+		})
+	}
+}
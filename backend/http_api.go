@@ -0,0 +1,576 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"regexp"
+	i "spieven/backend/interfaces"
+	"spieven/common/packet"
+	"spieven/common/types"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The HTTP API exposes the same operations as the packet protocol for tools which cannot speak it. Handlers call the
+// same compute*Response functions as commands in commands.go, so both behave identically. The API can only listen on
+// localhost or on a unix socket checked with peer credentials. Any local user can connect to a TCP port, so requests
+// over TCP must also carry the token created by "spieven auth init" as "Authorization: Bearer TOKEN".
+
+const HttpAddressHelpString = "Use HOST:PORT with a loopback address, e.g. 127.0.0.1:8080, or unix://PATH. " +
+	"Requests over TCP must carry the token created by spieven auth init as \"Authorization: Bearer TOKEN\"."
+
+func listenHttpApi(backendState *BackendState, address string, allowedGroupId int) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(address, "unix://"); ok {
+		listener, err := listenUnixSocket(path, allowedGroupId)
+		if err != nil {
+			return nil, err
+		}
+		return &peerCheckingListener{Listener: listener, backendState: backendState, allowedGroupId: allowedGroupId}, nil
+	}
+
+	tcpAddr, err := net.ResolveTCPAddr("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP address %q. %v", address, HttpAddressHelpString)
+	}
+	if !tcpAddr.IP.IsLoopback() {
+		return nil, fmt.Errorf("HTTP API can only listen on a loopback address, got %q. %v", address, HttpAddressHelpString)
+	}
+	return net.ListenTCP("tcp", tcpAddr)
+}
+
+// peerCheckingListener drops unix socket connections from processes which are not allowed to use the backend.
+type peerCheckingListener struct {
+	net.Listener
+	backendState   *BackendState
+	allowedGroupId int
+}
+
+func (listener *peerCheckingListener) Accept() (net.Conn, error) {
+	for {
+		connection, err := listener.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkPeerCredentials(connection.(*net.UnixConn), listener.allowedGroupId); err != nil {
+			listener.backendState.messages.AddF(i.BackendMessageError, i.MessageKindConnection, nil, "Rejecting HTTP connection: %v", err)
+			connection.Close()
+			continue
+		}
+		return connection, nil
+	}
+}
+
+func serveHttpApi(backendState *BackendState, listener net.Listener) error {
+	_, isUnixSocket := listener.(*peerCheckingListener)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", func(w http.ResponseWriter, r *http.Request) { httpListTasks(backendState, w, r) })
+	mux.HandleFunc("POST /tasks", func(w http.ResponseWriter, r *http.Request) { httpRunTask(backendState, w, r) })
+	mux.HandleFunc("GET /tasks/{id}", func(w http.ResponseWriter, r *http.Request) { httpInspectTask(backendState, w, r) })
+	mux.HandleFunc("POST /tasks/stop", func(w http.ResponseWriter, r *http.Request) { httpStopTasks(backendState, w, r) })
+	mux.HandleFunc("POST /tasks/resume", func(w http.ResponseWriter, r *http.Request) { httpResumeTasks(backendState, w, r) })
+	mux.HandleFunc("POST /tasks/{id}/stop", func(w http.ResponseWriter, r *http.Request) { httpStopTask(backendState, w, r) })
+	mux.HandleFunc("POST /tasks/{id}/resume", func(w http.ResponseWriter, r *http.Request) { httpResumeTask(backendState, w, r) })
+	mux.HandleFunc("POST /tasks/{id}/refresh", func(w http.ResponseWriter, r *http.Request) { httpRefreshTask(backendState, w, r) })
+	mux.HandleFunc("GET /tasks/{id}/logs", func(w http.ResponseWriter, r *http.Request) { httpTaskLogs(backendState, w, r) })
+	mux.HandleFunc("GET /tasks/{id}/history", func(w http.ResponseWriter, r *http.Request) { httpTaskHistory(backendState, w, r) })
+	mux.HandleFunc("GET /messages", func(w http.ResponseWriter, r *http.Request) { httpMessages(backendState, w, r) })
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(backendState, w)
+	})
+
+	// Browsers can send requests to localhost from any website. Requiring a json body for POST requests makes them
+	// subject to CORS preflight, which we never allow. Checking the Host header protects against DNS rebinding.
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isUnixSocket && !isLoopbackHost(r.Host) {
+			writeHttpError(w, http.StatusForbidden, "requests must be addressed to localhost")
+			return
+		}
+		if !isUnixSocket {
+			token, hasToken := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !hasToken || !isValidAuthToken(token, backendState.httpAuthToken) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeHttpError(w, http.StatusUnauthorized, "requests must carry the token created by spieven auth init")
+				return
+			}
+		}
+		if r.Method == http.MethodPost {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType != "application/json" {
+				writeHttpError(w, http.StatusUnsupportedMediaType, "POST requests must have Content-Type: application/json")
+				return
+			}
+		}
+		backendState.httpRequestsServed.Add(1)
+		mux.ServeHTTP(w, r)
+	})
+
+	server := &http.Server{Handler: handler}
+	backendState.sync.StartGoroutineAfterContextKill(func() {
+		server.Close()
+	})
+
+	err := server.Serve(listener)
+	if backendState.sync.IsContextKilled() {
+		return fmt.Errorf("user interrupt detected")
+	}
+	return fmt.Errorf("HTTP server failure %w", err)
+}
+
+func isLoopbackHost(hostWithPort string) bool {
+	host, _, err := net.SplitHostPort(hostWithPort)
+	if err != nil {
+		host = hostWithPort
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+type httpErrorBody struct {
+	Error string
+}
+
+func writeHttpJson(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeHttpError(w http.ResponseWriter, statusCode int, message string) {
+	writeHttpJson(w, statusCode, httpErrorBody{Error: message})
+}
+
+// writeHttpAmbiguousTask reports a task selector matching multiple tasks, listing them in the message.
+func writeHttpAmbiguousTask(w http.ResponseWriter, r *http.Request, candidates []types.TaskCandidate) {
+	writeHttpError(w, http.StatusConflict, types.FormatTaskCandidates(r.PathValue("id"), candidates))
+}
+
+func parseHttpTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	result, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 format", value)
+	}
+	return result, nil
+}
+
+// getQueryValues returns all values of a query parameter. Values can be repeated or separated with commas.
+func getQueryValues(r *http.Request, key string) []string {
+	var result []string
+	for _, value := range r.URL.Query()[key] {
+		for _, item := range strings.Split(value, ",") {
+			if item != "" {
+				result = append(result, item)
+			}
+		}
+	}
+	return result
+}
+
+func runStatusToHttp(status types.RunResponseStatus) (int, string) {
+	switch status {
+	case types.RunResponseStatusSuccess:
+		return http.StatusOK, ""
+	case types.RunResponseStatusAlreadyRunning:
+		return http.StatusConflict, "task already running"
+	case types.RunResponseStatusNameDisplayAlreadyRunning:
+		return http.StatusConflict, "task with the same name already present on the display"
+	case types.RunResponseStatusInvalidDisplay:
+		return http.StatusBadRequest, "invalid display"
+	case types.RunResponseStatusTaskNotFound:
+		return http.StatusNotFound, "task not found"
+	case types.RunResponseStatusTaskNotDeactivated:
+		return http.StatusConflict, "task is active"
+	case types.RunResponseStatusEmptyFilter:
+		return http.StatusBadRequest, httpEmptyFilterError
+	default:
+		return http.StatusInternalServerError, "unknown error"
+	}
+}
+
+// httpListTasks accepts filters as query parameters: id, name, display, tag, status (all, active or deactivated) and
+// unique.
+func httpListTasks(backendState *BackendState, w http.ResponseWriter, r *http.Request) {
+	filter, err := parseHttpTaskFilter(r)
+	if err != nil {
+		writeHttpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	request := packet.ListRequestBody{
+		Filter:      filter,
+		UniqueNames: r.URL.Query().Get("unique") == "true",
+	}
+	writeHttpJson(w, http.StatusOK, computeListResponse(backendState, request))
+}
+
+// parseHttpTaskFilter reads a task filter from the query parameters id, name, display, tag, where and status.
+func parseHttpTaskFilter(r *http.Request) (types.TaskFilter, error) {
+	query := r.URL.Query()
+	filter := types.TaskFilter{
+		IdFilter:      math.MaxInt,
+		AnyNameFilter: getQueryValues(r, "name"),
+		AllTagsFilter: getQueryValues(r, "tag"),
+	}
+
+	if value := query.Get("id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid id %q", value)
+		}
+		filter.IdFilter = id
+	}
+	if value := query.Get("where"); value != "" {
+		if _, err := types.ParseTaskExpression(value); err != nil {
+			return filter, fmt.Errorf("invalid where expression: %v", err)
+		}
+		filter.WhereFilter = value
+	}
+	if err := filter.DisplayFilter.ParseDisplaySelection(query.Get("display"), true); err != nil {
+		return filter, err
+	}
+	switch query.Get("status") {
+	case "", "all":
+		filter.IncludeActive = true
+		filter.IncludeDeactivated = true
+	case "active":
+		filter.IncludeActive = true
+	case "deactivated":
+		filter.IncludeDeactivated = true
+	default:
+		return filter, errors.New("invalid status, expected one of: all, active, deactivated")
+	}
+	return filter, nil
+}
+
+// httpRunTask expects the same json body as the run packet.
+func httpRunTask(backendState *BackendState, w http.ResponseWriter, r *http.Request) {
+	var request packet.RunRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeHttpError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if len(request.Cmdline) == 0 {
+		writeHttpError(w, http.StatusBadRequest, "Cmdline cannot be empty")
+		return
+	}
+	if request.Display.Type == types.DisplaySelectionTypeNone {
+		writeHttpError(w, http.StatusBadRequest, "Display is required")
+		return
+	}
+
+	response := computeRunResponse(backendState, request)
+	statusCode, message := runStatusToHttp(response.Status)
+	if response.Status != types.RunResponseStatusSuccess {
+		writeHttpError(w, statusCode, message)
+		return
+	}
+	writeHttpJson(w, http.StatusCreated, response)
+}
+
+func httpStopTask(backendState *BackendState, w http.ResponseWriter, r *http.Request) {
+	response := computeStopResponse(backendState, packet.StopRequestBody{Task: r.PathValue("id")})
+	switch response.Status {
+	case types.StopResponseStatusSuccess:
+		writeHttpJson(w, http.StatusOK, response)
+	case types.StopResponseStatusTaskNotFound:
+		writeHttpError(w, http.StatusNotFound, "task not found")
+	case types.StopResponseStatusAlreadyStopped:
+		writeHttpError(w, http.StatusConflict, "task is already stopped")
+	case types.StopResponseStatusAmbiguousTask:
+		writeHttpAmbiguousTask(w, r, response.Candidates)
+	default:
+		writeHttpError(w, http.StatusInternalServerError, "unknown error")
+	}
+}
+
+func httpResumeTask(backendState *BackendState, w http.ResponseWriter, r *http.Request) {
+	response := computeResumeResponse(backendState, packet.ResumeRequestBody{Task: r.PathValue("id")})
+	if response.Status == types.RunResponseStatusAmbiguousTask {
+		writeHttpAmbiguousTask(w, r, response.Candidates)
+		return
+	}
+	statusCode, message := runStatusToHttp(response.Status)
+	if response.Status != types.RunResponseStatusSuccess {
+		writeHttpError(w, statusCode, message)
+		return
+	}
+	writeHttpJson(w, statusCode, response)
+}
+
+const httpEmptyFilterError = "filter selects all tasks, add all=true to select them"
+
+// httpStopTasks stops all tasks matching a filter given like in httpListTasks. A filter selecting tasks only by status
+// also requires all=true.
+func httpStopTasks(backendState *BackendState, w http.ResponseWriter, r *http.Request) {
+	filter, err := parseHttpTaskFilter(r)
+	if err != nil {
+		writeHttpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	all := r.URL.Query().Get("all") == "true"
+	response := computeStopResponse(backendState, packet.StopRequestBody{Filter: &filter, All: all})
+	if response.Status == types.StopResponseStatusEmptyFilter {
+		writeHttpError(w, http.StatusBadRequest, httpEmptyFilterError)
+		return
+	}
+	writeHttpJson(w, http.StatusOK, response)
+}
+
+// httpResumeTasks resumes all tasks matching a filter given like in httpListTasks. A filter selecting tasks only by
+// status also requires all=true.
+func httpResumeTasks(backendState *BackendState, w http.ResponseWriter, r *http.Request) {
+	filter, err := parseHttpTaskFilter(r)
+	if err != nil {
+		writeHttpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	all := r.URL.Query().Get("all") == "true"
+	response := computeResumeResponse(backendState, packet.ResumeRequestBody{Filter: &filter, All: all})
+	if response.Status == types.RunResponseStatusEmptyFilter {
+		statusCode, message := runStatusToHttp(response.Status)
+		writeHttpError(w, statusCode, message)
+		return
+	}
+	writeHttpJson(w, http.StatusOK, response)
+}
+
+func httpRefreshTask(backendState *BackendState, w http.ResponseWriter, r *http.Request) {
+	backendState.scheduler.Lock()
+	task, candidates := resolveTaskSelector(backendState, r.PathValue("id"))
+	backendState.scheduler.Unlock()
+	if candidates != nil {
+		writeHttpAmbiguousTask(w, r, candidates)
+		return
+	}
+	if task == nil {
+		writeHttpError(w, http.StatusNotFound, "task not found or not active")
+		return
+	}
+
+	request := packet.RefreshRequestBody{
+		Filter: types.TaskFilter{
+			IdFilter:      task.Computed.Id,
+			IncludeActive: true,
+		},
+	}
+	response := computeRefreshResponse(backendState, request)
+	if response.RefreshedTasksCount == 0 {
+		writeHttpError(w, http.StatusNotFound, "task not found or not active")
+		return
+	}
+	writeHttpJson(w, http.StatusOK, response)
+}
+
+func httpInspectTask(backendState *BackendState, w http.ResponseWriter, r *http.Request) {
+	response := computeInspectResponse(backendState, packet.InspectRequestBody{Task: r.PathValue("id")})
+	switch response.Status {
+	case types.InspectResponseStatusSuccess:
+		writeHttpJson(w, http.StatusOK, response)
+	case types.InspectResponseStatusTaskNotFound:
+		writeHttpError(w, http.StatusNotFound, "task not found")
+	case types.InspectResponseStatusAmbiguousTask:
+		writeHttpAmbiguousTask(w, r, response.Candidates)
+	default:
+		writeHttpError(w, http.StatusInternalServerError, "unknown error")
+	}
+}
+
+func httpTaskHistory(backendState *BackendState, w http.ResponseWriter, r *http.Request) {
+	response := computeHistoryResponse(backendState, packet.HistoryRequestBody{Task: r.PathValue("id")})
+	switch response.Status {
+	case types.HistoryResponseStatusSuccess:
+		writeHttpJson(w, http.StatusOK, response)
+	case types.HistoryResponseStatusTaskNotFound:
+		writeHttpError(w, http.StatusNotFound, "task not found")
+	case types.HistoryResponseStatusAmbiguousTask:
+		writeHttpAmbiguousTask(w, r, response.Candidates)
+	default:
+		writeHttpError(w, http.StatusInternalServerError, "unknown error")
+	}
+}
+
+// httpTaskLogs accepts query parameters source (log, stdout or stderr), execution, tail, since, until, grep and follow.
+// Task can be given by id or friendly name. Since and until are rejected for stdout and stderr, which have no
+// timestamps. With follow, records are sent as server-sent events, until the task is deactivated.
+func httpTaskLogs(backendState *BackendState, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	request := packet.TaskLogsRequestBody{
+		Task: r.PathValue("id"),
+		Grep: query.Get("grep"),
+	}
+
+	var err error
+	switch query.Get("source") {
+	case "", "log":
+		request.Source = packet.TaskLogsSourceTaskLog
+	case "stdout":
+		request.Source = packet.TaskLogsSourceStdout
+	case "stderr":
+		request.Source = packet.TaskLogsSourceStderr
+	default:
+		err = errors.New("invalid source, expected one of: log, stdout, stderr")
+	}
+	if err == nil {
+		execution := query.Get("execution")
+		if execution == "" {
+			execution = "all"
+		}
+		request.Execution, err = types.ParseExecutionSelection(execution)
+	}
+	if err == nil && query.Get("tail") != "" {
+		request.Tail, err = strconv.Atoi(query.Get("tail"))
+		if err != nil || request.Tail < 0 {
+			err = errors.New("tail must be a non-negative number")
+		}
+	}
+	if err == nil {
+		request.Since, err = parseHttpTime(query.Get("since"))
+	}
+	if err == nil {
+		request.Until, err = parseHttpTime(query.Get("until"))
+	}
+	if err != nil {
+		writeHttpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := computeTaskLogsResponse(backendState, request)
+	switch response.Status {
+	case types.TaskLogsResponseStatusSuccess:
+	case types.TaskLogsResponseStatusTaskNotFound:
+		writeHttpError(w, http.StatusNotFound, "task not found")
+		return
+	case types.TaskLogsResponseStatusAmbiguousTask:
+		writeHttpAmbiguousTask(w, r, response.Candidates)
+		return
+	case types.TaskLogsResponseStatusInvalidRequest:
+		writeHttpError(w, http.StatusBadRequest, response.Error)
+		return
+	case types.TaskLogsResponseStatusReadError:
+		writeHttpError(w, http.StatusInternalServerError, response.Error)
+		return
+	default:
+		writeHttpError(w, http.StatusInternalServerError, "unknown error")
+		return
+	}
+
+	if query.Get("follow") != "true" {
+		writeHttpJson(w, http.StatusOK, response)
+		return
+	}
+	followHttpTaskLogs(backendState, w, r, &request, &response)
+}
+
+func followHttpTaskLogs(backendState *BackendState, w http.ResponseWriter, r *http.Request, request *packet.TaskLogsRequestBody, response *packet.TaskLogsResponseBody) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeHttpError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	sendEvent := func(event string, data any) error {
+		serialized, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %v\ndata: %s\n\n", event, serialized); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	for index := range response.Records {
+		if err := sendEvent("record", &response.Records[index]); err != nil {
+			return
+		}
+	}
+	if !response.IsActive {
+		sendEvent("deactivated", struct{}{})
+		return
+	}
+
+	// New lines are read from the task log, which also contains output of the task, so the filters are applied here.
+	var grep *regexp.Regexp
+	if request.Grep != "" {
+		grep = regexp.MustCompile(request.Grep) // already validated by computeTaskLogsResponse
+	}
+	request.Execution = response.Execution
+
+	var incompleteLine []byte
+	sendResponse := func(chunk packet.FollowTaskLogResponseBody) error {
+		switch chunk.Status {
+		case packet.FollowTaskLogResponseStatusChunk:
+			incompleteLine = append(incompleteLine, chunk.Data...)
+			for {
+				lineEnd := bytes.IndexByte(incompleteLine, '\n')
+				if lineEnd < 0 {
+					return nil
+				}
+				line := string(incompleteLine[:lineEnd])
+				incompleteLine = incompleteLine[lineEnd+1:]
+
+				record, err := types.ParseTaskLogRecord(line)
+				if err != nil || !request.MatchesRecord(&record, grep) {
+					continue
+				}
+				if err := sendEvent("record", &record); err != nil {
+					return err
+				}
+			}
+		case packet.FollowTaskLogResponseStatusDeactivated:
+			return sendEvent("deactivated", struct{}{})
+		case packet.FollowTaskLogResponseStatusInvalidTask, packet.FollowTaskLogResponseStatusReadError:
+			return sendEvent("error", httpErrorBody{Error: chunk.Error})
+		}
+		return nil
+	}
+
+	followRequest := packet.FollowTaskLogRequestBody{TaskId: response.TaskId, Offset: response.LogFileOffset}
+	followTaskLogs(backendState, followRequest, sendResponse, r.Context().Done())
+}
+
+// httpMessages accepts query parameters severity, kind, task and since.
+func httpMessages(backendState *BackendState, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var request packet.LogRequestBody
+
+	var err error
+	if value := query.Get("severity"); value != "" {
+		request.MinSeverity, err = types.ParseBackendMessageSeverity(value)
+	}
+	if value := query.Get("kind"); err == nil && value != "" {
+		request.Kind, err = types.ParseBackendMessageKind(value)
+	}
+	if value := query.Get("task"); err == nil && value != "" {
+		var taskId int
+		taskId, err = strconv.Atoi(value)
+		request.TaskId = &taskId
+	}
+	if err == nil {
+		request.Since, err = parseHttpTime(query.Get("since"))
+	}
+	if err != nil {
+		writeHttpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	messages, _ := backendState.messages.Query(0, getMessageSelectorFunc(&request))
+	writeHttpJson(w, http.StatusOK, messages)
+}
@@ -0,0 +1,113 @@
+package backend
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maskedEnvValue replaces values of environment variables which look like secrets in the inspect response.
+const maskedEnvValue = "********"
+
+// secretEnvNameParts are parts of environment variable names, separated by underscores, which suggest the value is a
+// secret, e.g. GITHUB_TOKEN or DB_PASS.
+var secretEnvNameParts = map[string]struct{}{
+	"PASSWORD":    {},
+	"PASSWD":      {},
+	"PASS":        {},
+	"PWD":         {},
+	"SECRET":      {},
+	"TOKEN":       {},
+	"KEY":         {},
+	"APIKEY":      {},
+	"CREDENTIAL":  {},
+	"CREDENTIALS": {},
+	"AUTH":        {},
+	"COOKIE":      {},
+	"PRIVATE":     {},
+}
+
+// secretEnvNameSubstrings are matched anywhere in the name, to also catch names like MYSQLPASSWORD or APPSECRET.
+var secretEnvNameSubstrings = []string{"PASSWORD", "SECRET", "TOKEN"}
+
+func isSecretEnvName(name string) bool {
+	name = strings.ToUpper(name)
+	if name == "PWD" || name == "OLDPWD" {
+		// Working directories, not passwords
+		return false
+	}
+	for _, part := range strings.Split(name, "_") {
+		if _, found := secretEnvNameParts[part]; found {
+			return true
+		}
+	}
+	for _, substring := range secretEnvNameSubstrings {
+		if strings.Contains(name, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+func maskSecretEnvValues(env []string) []string {
+	result := make([]string, len(env))
+	for index, entry := range env {
+		name, _, hasValue := strings.Cut(entry, "=")
+		if hasValue && isSecretEnvName(name) {
+			entry = name + "=" + maskedEnvValue
+		}
+		result[index] = entry
+	}
+	return result
+}
+
+// getProcessTree returns the pid followed by pids of all its descendants. Processes which changed their parent, e.g.
+// daemons which double forked, are not found. It returns an empty slice for pid 0.
+func getProcessTree(pid int) []int {
+	result := []int{}
+	if pid == 0 {
+		return result
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return append(result, pid)
+	}
+	children := make(map[int][]int)
+	for _, entry := range entries {
+		currPid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		parentPid, err := readParentPid(currPid)
+		if err != nil {
+			continue // the process may have ended in the meantime
+		}
+		children[parentPid] = append(children[parentPid], currPid)
+	}
+
+	queue := []int{pid}
+	for len(queue) > 0 {
+		currPid := queue[0]
+		queue = queue[1:]
+		result = append(result, currPid)
+		queue = append(queue, children[currPid]...)
+	}
+	return result
+}
+
+func readParentPid(pid int) (int, error) {
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return 0, err
+	}
+
+	// The format is "pid (comm) state ppid ...", where comm may contain spaces and parentheses
+	content := string(stat)
+	commEnd := strings.LastIndexByte(content, ')')
+	fields := strings.Fields(content[commEnd+1:])
+	if commEnd < 0 || len(fields) < 2 {
+		return 0, os.ErrInvalid
+	}
+	return strconv.Atoi(fields[1])
+}
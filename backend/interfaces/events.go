@@ -0,0 +1,24 @@
+package interfaces
+
+import "spieven/common/types"
+
+type EventType = types.EventType
+
+const (
+	EventTaskScheduled   = types.EventTypeTaskScheduled
+	EventTaskResumed     = types.EventTypeTaskResumed
+	EventTaskMoved       = types.EventTypeTaskMoved
+	EventTaskStarted     = types.EventTypeTaskStarted
+	EventTaskExited      = types.EventTypeTaskExited
+	EventTaskFailed      = types.EventTypeTaskFailed
+	EventTaskDeactivated = types.EventTypeTaskDeactivated
+	EventDisplayAppeared = types.EventTypeDisplayAppeared
+	EventDisplayVanished = types.EventTypeDisplayVanished
+)
+
+// IEvents delivers events to subscribed frontends. Emitting never blocks. Event time and task fields are filled in
+// by the implementation.
+type IEvents interface {
+	Emit(eventType EventType, task ITask, event types.Event)
+	EmitDisplay(eventType EventType, display types.DisplaySelection)
+}
@@ -6,6 +6,8 @@ type IFiles interface {
 	GetTmpFile() (*os.File, error)
 	GetDeactivatedTasksFile() string
 	GetTaskLogFile(taskId int) string
+	GetRotatedTaskLogFile(taskId int) string
+	GetAllExecutionLogFiles() (map[int]map[int][]string, error)
 	GetStdoutStderrLogFiles(taskId int, executionId int) (string, string)
 	GetBackendMessagesLogFile() string
 }
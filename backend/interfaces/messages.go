@@ -1,13 +1,27 @@
 package interfaces
 
-type MessageSeverity int
+import "spieven/common/types"
+
+type MessageSeverity = types.BackendMessageSeverity
+
+const (
+	BackendMessageInfo  = types.BackendMessageSeverityInfo
+	BackendMessageError = types.BackendMessageSeverityError
+)
+
+type MessageKind = types.BackendMessageKind
 
 const (
-	BackendMessageInfo MessageSeverity = iota
-	BackendMessageError
+	MessageKindTask       = types.BackendMessageKindTask
+	MessageKindRequest    = types.BackendMessageKindRequest
+	MessageKindDisplay    = types.BackendMessageKindDisplay
+	MessageKindLogs       = types.BackendMessageKindLogs
+	MessageKindStorage    = types.BackendMessageKindStorage
+	MessageKindConnection = types.BackendMessageKindConnection
+	MessageKindBackend    = types.BackendMessageKindBackend
 )
 
 type IMessages interface {
-	Add(severity MessageSeverity, task ITask, content string)
-	AddF(severity MessageSeverity, task ITask, format string, args ...any)
+	Add(severity MessageSeverity, kind MessageKind, task ITask, content string)
+	AddF(severity MessageSeverity, kind MessageKind, task ITask, format string, args ...any)
 }
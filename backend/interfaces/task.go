@@ -1,5 +1,9 @@
 package interfaces
 
+import "spieven/common/types"
+
 type ITask interface {
-	GetLogLabel() string
+	GetId() int
+	GetFriendlyName() string
+	GetDisplay() types.DisplaySelection
 }
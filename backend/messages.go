@@ -1,52 +1,25 @@
 package backend
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	i "spieven/backend/interfaces"
 	"spieven/common"
+	"spieven/common/types"
 	"sync"
 	"time"
 )
 
-// BackendMessage is a description of a failure encountered by the backend that is to be stored for later retrieval by
-// an appropriate frontend command.
-type BackendMessage struct {
-	date     time.Time
-	severity i.MessageSeverity
-	content  string
-	task     i.ITask
-}
-
-func (msg *BackendMessage) String() string {
-	result := msg.content
-
-	var severity string
-
-	switch msg.severity {
-	case i.BackendMessageInfo:
-		severity = " INFO"
-	case i.BackendMessageError:
-		severity = "ERROR"
-	default:
-		severity = "     " // Should not happen, but let's handle it gracefully
-	}
-
-	date := msg.date.Format("2006-01-02 15-04-05")
-	taskLabel := ""
-	if msg.task != nil {
-		taskLabel = fmt.Sprintf(" (%s)", msg.task.GetLogLabel())
-	}
-	result = fmt.Sprintf("[%v][%v] %v%v", severity, date, result, taskLabel)
-
-	return result
-}
-
-// BackendMessages stores instances of BackendMessage and exposes methods to retrieve and manage them.
+// BackendMessages stores instances of BackendMessage and exposes methods to retrieve and manage them. Messages are
+// also written to a log file as ndjson.
 type BackendMessages struct {
-	messages []BackendMessage
-	logFile  *os.File
-	lock     sync.Mutex
+	messages     []types.BackendMessage
+	nextSequence uint64
+	counts       map[types.BackendMessageSeverity]uint64 // all messages ever added, including trimmed ones
+	logFile      *os.File
+	subscribers  map[chan struct{}]struct{}
+	lock         sync.Mutex
 
 	_ common.NoCopy
 }
@@ -57,7 +30,9 @@ func CreateBackendMessages(logFilePath string) (*BackendMessages, error) {
 		return nil, err
 	}
 	return &BackendMessages{
-		logFile: logFile,
+		logFile:     logFile,
+		counts:      make(map[types.BackendMessageSeverity]uint64),
+		subscribers: make(map[chan struct{}]struct{}),
 	}, nil
 }
 
@@ -68,45 +43,99 @@ func (messages *BackendMessages) Cleanup() {
 	}
 }
 
-func (messages *BackendMessages) String() string {
-	messages.lock.Lock()
-	defer messages.lock.Unlock()
-
-	var result string
-	for _, BackendMessage := range messages.messages {
-		result += BackendMessage.content
+func (messages *BackendMessages) Add(severity i.MessageSeverity, kind i.MessageKind, task i.ITask, content string) {
+	msg := types.BackendMessage{
+		Time:     time.Now(),
+		Severity: severity,
+		Kind:     kind,
+		Content:  content,
 	}
-	return result
-}
-
-func (messages *BackendMessages) Add(severity i.MessageSeverity, task i.ITask, content string) {
-	msg := BackendMessage{
-		date:     time.Now(),
-		severity: severity,
-		content:  content,
-		task:     task,
+	if task != nil {
+		taskId := task.GetId()
+		display := task.GetDisplay()
+		msg.TaskId = &taskId
+		msg.TaskName = task.GetFriendlyName()
+		msg.Display = display.ComputeDisplayLabel()
 	}
 
-	fmt.Println(msg.String())
+	// Stdout can block, e.g. when the backend was started from a terminal which is not read anymore. Print after
+	// unlocking, so other goroutines adding messages are not stalled by it.
+	defer func() { fmt.Println(msg.String()) }()
 
 	messages.lock.Lock()
+	defer messages.lock.Unlock()
+
+	msg.Sequence = messages.nextSequence
+	messages.nextSequence++
+	messages.counts[severity]++
 	messages.messages = append(messages.messages, msg)
 
 	if messages.logFile != nil {
-		msgWithNewline := msg.String() + "\n"
-		err := common.WriteBytesToWriter(messages.logFile, []byte(msgWithNewline))
+		serialized, err := json.Marshal(&msg)
+		if err == nil {
+			err = common.WriteBytesToWriter(messages.logFile, append(serialized, '\n'))
+		}
 		if err != nil {
 			messages.logFile.Close()
 			messages.logFile = nil
 		}
 	}
 
-	messages.lock.Unlock()
+	for channel := range messages.subscribers {
+		select {
+		case channel <- struct{}{}:
+		default:
+			// Subscriber already has a pending notification
+		}
+	}
 }
 
-func (messages *BackendMessages) AddF(severity i.MessageSeverity, task i.ITask, format string, args ...any) {
+func (messages *BackendMessages) AddF(severity i.MessageSeverity, kind i.MessageKind, task i.ITask, format string, args ...any) {
 	content := fmt.Sprintf(format, args...)
-	messages.Add(severity, task, content)
+	messages.Add(severity, kind, task, content)
+}
+
+// Query returns messages with sequence number of at least fromSequence, for which the selector returns true. It also
+// returns the sequence number to pass in the next call to get only messages added since this call.
+func (messages *BackendMessages) Query(fromSequence uint64, selector func(*types.BackendMessage) bool) ([]types.BackendMessage, uint64) {
+	messages.lock.Lock()
+	defer messages.lock.Unlock()
+
+	result := make([]types.BackendMessage, 0)
+	for index := range messages.messages {
+		msg := &messages.messages[index]
+		if msg.Sequence >= fromSequence && selector(msg) {
+			result = append(result, *msg)
+		}
+	}
+	return result, messages.nextSequence
+}
+
+func (messages *BackendMessages) GetCountsBySeverity() map[types.BackendMessageSeverity]uint64 {
+	messages.lock.Lock()
+	defer messages.lock.Unlock()
+
+	result := make(map[types.BackendMessageSeverity]uint64, len(messages.counts))
+	for severity, count := range messages.counts {
+		result[severity] = count
+	}
+	return result
+}
+
+// Subscribe registers a channel to be notified about new messages. It should be buffered, because notifications are
+// never blocking.
+func (messages *BackendMessages) Subscribe(channel chan struct{}) {
+	messages.lock.Lock()
+	defer messages.lock.Unlock()
+
+	messages.subscribers[channel] = struct{}{}
+}
+
+func (messages *BackendMessages) Unsubscribe(channel chan struct{}) {
+	messages.lock.Lock()
+	defer messages.lock.Unlock()
+
+	delete(messages.subscribers, channel)
 }
 
 func (messages *BackendMessages) Trim(maxAge time.Duration) {
@@ -115,9 +144,9 @@ func (messages *BackendMessages) Trim(maxAge time.Duration) {
 
 	now := time.Now()
 
-	var newBackendMessages []BackendMessage
+	var newBackendMessages []types.BackendMessage
 	for _, BackendMessage := range messages.messages {
-		deadline := BackendMessage.date.Add(maxAge)
+		deadline := BackendMessage.Time.Add(maxAge)
 		if now.Before(deadline) {
 			newBackendMessages = append(newBackendMessages, BackendMessage)
 		}
@@ -0,0 +1,137 @@
+package backend
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"spieven/common/types"
+	"strings"
+)
+
+// metricsWriter produces the Prometheus text exposition format. Help and type lines are written once per metric,
+// before its first sample.
+type metricsWriter struct {
+	writer  io.Writer
+	written map[string]bool
+}
+
+func (metrics *metricsWriter) sample(name string, metricType string, help string, labels [][2]string, value float64) {
+	if !metrics.written[name] {
+		metrics.written[name] = true
+		fmt.Fprintf(metrics.writer, "# HELP %v %v\n# TYPE %v %v\n", name, help, name, metricType)
+	}
+
+	var labelStrings []string
+	for _, label := range labels {
+		labelStrings = append(labelStrings, fmt.Sprintf("%v=\"%v\"", label[0], escapeMetricLabel(label[1])))
+	}
+	if len(labelStrings) > 0 {
+		fmt.Fprintf(metrics.writer, "%v{%v} %v\n", name, strings.Join(labelStrings, ","), value)
+	} else {
+		fmt.Fprintf(metrics.writer, "%v %v\n", name, value)
+	}
+}
+
+func escapeMetricLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func boolToMetric(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// writeMetrics writes metrics of tasks kept in memory and of the backend itself. Trimmed tasks are only counted, since
+// they are deactivated and reading them requires parsing a file.
+func writeMetrics(backendState *BackendState, writer io.Writer) {
+	metrics := metricsWriter{writer: writer, written: make(map[string]bool)}
+
+	type taskMetrics struct {
+		labels                 [][2]string
+		runCount               int
+		failureCount           int
+		subsequentFailureCount int
+		isActive               bool
+		lastExitValue          int
+		lastRunDuration        float64
+		lastStartTime          float64
+		usage                  types.ResourceUsage
+	}
+	var tasks []taskMetrics
+
+	sched := &backendState.scheduler
+	sched.Lock()
+	for _, task := range sched.GetTasks() {
+		labels := [][2]string{
+			{"id", fmt.Sprint(task.Computed.Id)},
+			{"name", task.FriendlyName},
+			{"display", task.Display.ComputeDisplayLabel()},
+		}
+		lastStartTime := 0.0
+		if !task.Dynamic.LastStartTime.IsZero() {
+			lastStartTime = float64(task.Dynamic.LastStartTime.UnixMilli()) / 1000
+		}
+		tasks = append(tasks, taskMetrics{
+			labels:                 labels,
+			runCount:               task.Dynamic.RunCount,
+			failureCount:           task.Dynamic.FailureCount,
+			subsequentFailureCount: task.Dynamic.SubsequentFailureCount,
+			isActive:               !task.Dynamic.IsDeactivated,
+			lastExitValue:          task.Dynamic.LastExitValue,
+			lastRunDuration:        task.Dynamic.LastRunDuration.Seconds(),
+			lastStartTime:          lastStartTime,
+			usage:                  task.Dynamic.TotalUsage,
+		})
+	}
+	tasksInMemory := len(sched.GetTasks())
+	tasksTrimmed := sched.GetTrimmedCount()
+	sched.Unlock()
+
+	// Samples of one metric have to be grouped together, so iterate over tasks once per metric
+	for _, task := range tasks {
+		metrics.sample("spieven_task_runs_total", "counter", "Number of finished executions of the task.", task.labels, float64(task.runCount))
+	}
+	for _, task := range tasks {
+		metrics.sample("spieven_task_failures_total", "counter", "Number of failed executions of the task.", task.labels, float64(task.failureCount))
+	}
+	for _, task := range tasks {
+		metrics.sample("spieven_task_subsequent_failures", "gauge", "Number of failed executions of the task in a row.", task.labels, float64(task.subsequentFailureCount))
+	}
+	for _, task := range tasks {
+		metrics.sample("spieven_task_active", "gauge", "Whether the task is active (1) or deactivated (0).", task.labels, boolToMetric(task.isActive))
+	}
+	for _, task := range tasks {
+		metrics.sample("spieven_task_last_exit_code", "gauge", "Exit code of the last finished execution of the task.", task.labels, float64(task.lastExitValue))
+	}
+	for _, task := range tasks {
+		metrics.sample("spieven_task_last_run_duration_seconds", "gauge", "Duration of the last finished execution of the task.", task.labels, task.lastRunDuration)
+	}
+	for _, task := range tasks {
+		userLabels := append(slices.Clone(task.labels), [2]string{"mode", "user"})
+		systemLabels := append(slices.Clone(task.labels), [2]string{"mode", "system"})
+		metrics.sample("spieven_task_cpu_seconds_total", "counter", "CPU time used by finished executions of the task.", userLabels, task.usage.UserTime.Seconds())
+		metrics.sample("spieven_task_cpu_seconds_total", "counter", "", systemLabels, task.usage.SystemTime.Seconds())
+	}
+	for _, task := range tasks {
+		metrics.sample("spieven_task_max_rss_bytes", "gauge", "Highest peak resident set size of all finished executions of the task.", task.labels, float64(task.usage.MaxRssKb*1024))
+	}
+	for _, task := range tasks {
+		metrics.sample("spieven_task_major_faults_total", "counter", "Major page faults of finished executions of the task.", task.labels, float64(task.usage.MajorFaults))
+	}
+	for _, task := range tasks {
+		metrics.sample("spieven_task_last_start_timestamp_seconds", "gauge", "Unix time of the start of the last execution of the task, 0 if it never started.", task.labels, task.lastStartTime)
+	}
+
+	metrics.sample("spieven_tasks", "gauge", "Number of tasks known to the backend.", [][2]string{{"location", "memory"}}, float64(tasksInMemory))
+	metrics.sample("spieven_tasks", "gauge", "", [][2]string{{"location", "trimmed"}}, float64(tasksTrimmed))
+	metrics.sample("spieven_displays", "gauge", "Number of displays watched by the backend.", nil, float64(backendState.displays.GetActiveDisplayCount()))
+	metrics.sample("spieven_connections_total", "counter", "Number of frontend connections accepted.", nil, float64(backendState.connectionsServed.Load()))
+	metrics.sample("spieven_http_requests_total", "counter", "Number of HTTP API requests served.", nil, float64(backendState.httpRequestsServed.Load()))
+
+	messageCounts := backendState.messages.GetCountsBySeverity()
+	for _, severity := range []types.BackendMessageSeverity{types.BackendMessageSeverityInfo, types.BackendMessageSeverityError} {
+		metrics.sample("spieven_messages_total", "counter", "Number of backend messages added.", [][2]string{{"severity", severity.String()}}, float64(messageCounts[severity]))
+	}
+}
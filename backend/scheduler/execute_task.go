@@ -2,29 +2,30 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	i "spieven/backend/interfaces"
 	"spieven/common"
+	"spieven/common/types"
 	"sync"
+	"syscall"
 	"time"
 )
 
 func ExecuteTask(
 	task *Task,
 	schedulerLock *common.CheckedLock,
+	backendLogSinks []LogSink,
 	files i.IFiles,
 	goroutines i.IGoroutines,
 	messages i.IMessages,
+	events i.IEvents,
+	trigger types.ExecutionTrigger,
 ) {
-	// Initialize per-task logger
-	perTaskLogger := CreateFileLogger(files, goroutines, task.Computed.Id, task.CaptureStdout, task.CaptureStderr)
-	err := perTaskLogger.run()
-	if err != nil {
-		messages.Add(i.BackendMessageError, task, "failed to create per-task logger")
-		return
-	}
-	defer perTaskLogger.stop()
+	// Notify anyone waiting for this task to finish, e.g. when it's being moved to another display
+	defer close(task.Channels.DoneChannel)
 
 	// Copy the dynamic portion of task structure. Updates to it must be synchronized. We will be updating a local
 	// copy and assign it to the actual task struct under a lock in one go every time something changes. Technically
@@ -34,6 +35,21 @@ func ExecuteTask(
 	shadowDynamicState := task.Dynamic
 	schedulerLock.Unlock()
 
+	// Create log sinks requested for this task. They're closed after the per-task logger stops using them. Errors
+	// are reported after the logger is initialized.
+	taskLogSinks, logSinksErr := CreateLogSinks(task.LogSinks, messages)
+	defer CloseLogSinks(taskLogSinks)
+
+	// Initialize per-task logger
+	logSinks := append(append([]LogSink{}, backendLogSinks...), taskLogSinks...)
+	perTaskLogger := CreateFileLogger(files, goroutines, task, shadowDynamicState.RunCount, logSinks)
+	err := perTaskLogger.run()
+	if err != nil {
+		messages.Add(i.BackendMessageError, i.MessageKindLogs, task, "failed to create per-task logger")
+		return
+	}
+	defer perTaskLogger.stop()
+
 	// Logging in this function is a bit complicated. We have 3 possible places where logs can go:
 	//  1. FileLogger - per-task file with detailed info about the current task as well as stdout/stderr. All messages
 	//    will go there.
@@ -66,7 +82,7 @@ func ExecuteTask(
 			if hasFlag(LogFlagErr) {
 				severity = i.BackendMessageError
 			}
-			messages.Add(severity, task, content)
+			messages.Add(severity, i.MessageKindTask, task, content)
 		}
 		if hasFlag(LogDeactivation | LogBackend | LogTask) {
 			perTaskLogger.channel <- diagnosticMessage(content, hasFlag(LogFlagTaskSeparator))
@@ -85,9 +101,14 @@ func ExecuteTask(
 	logF(LogTask, "  Cmdline: %v", task.Cmdline)
 	logF(LogTask, "  Cwd: %v", task.Cwd)
 	logF(LogTask, "  DisplayType=%v DisplayName=%v", task.Display.Type, task.Display.Name)
+	if logSinksErr != nil {
+		logF(LogTask|LogBackend|LogFlagErr, "Failed to create log sinks: %v", logSinksErr)
+	}
 
 	// Execute the main loop until the task becomes deactivated.
 	for !shadowDynamicState.IsDeactivated {
+		executionId := shadowDynamicState.RunCount
+
 		// Initialize the command struct
 		cmdContext, cmdCancel := context.WithCancel(*goroutines.GetContext())
 		defer cmdCancel()
@@ -106,13 +127,31 @@ func ExecuteTask(
 		}
 
 		// Start the command
+		record := types.ExecutionRecord{
+			Execution: executionId,
+			Trigger:   trigger,
+			StartTime: time.Now(),
+		}
 		err = cmd.Start()
 		if err != nil {
 			log(LogDeactivation|LogFlagErr, "Failed to start the command.")
+			events.Emit(i.EventTaskFailed, task, types.Event{Execution: &executionId, Reason: err.Error()})
+			record.EndTime = record.StartTime
+			record.Result = types.ExecutionResultStartFailed
+			shadowDynamicState.History = appendExecutionRecord(shadowDynamicState.History, record)
 			break
 		}
 		log(LogTask, "Command started.")
 
+		// Publish start time right away, so it's visible while the command is running
+		shadowDynamicState.LastStartTime = record.StartTime
+		shadowDynamicState.CurrentPid = cmd.Process.Pid
+		shadowDynamicState.NextRunTime = time.Time{}
+		schedulerLock.Lock()
+		task.Dynamic = shadowDynamicState
+		schedulerLock.Unlock()
+		events.Emit(i.EventTaskStarted, task, types.Event{Execution: &executionId})
+
 		// Run pipe reading goroutines
 		var pipeWaitGroup sync.WaitGroup
 		pipeWaitGroup.Add(2)
@@ -127,39 +166,108 @@ func ExecuteTask(
 
 		// Wait for the command in a separate goroutine and signal when it ends. It's important to first wait for the
 		// goroutines streaming the output. Otherwise, cmd.Wait() will close the pipes leading to a race condition.
-		commandResultChannel := make(chan int, 1)
+		// A killed command is waited for after a timeout at most, because its children can keep the pipes open
+		// indefinitely.
+		type commandResult struct {
+			exitCode         int
+			usage            *types.ResourceUsage
+			isOutputComplete bool // false if the pipes were closed before the output was fully read
+		}
+		commandResultChannel := make(chan commandResult, 1)
+		outputStreamed := make(chan struct{})
+		commandKilled := make(chan struct{})
 		goroutines.StartGoroutine(func() {
 			pipeWaitGroup.Wait()
+			close(outputStreamed)
+		})
+		goroutines.StartGoroutine(func() {
+			var result commandResult
+			select {
+			case <-outputStreamed:
+				result.isOutputComplete = true
+			case <-commandKilled:
+				select {
+				case <-outputStreamed:
+					result.isOutputComplete = true
+				case <-time.After(outputDrainTimeout):
+				}
+			}
 
-			status := 0
 			err := cmd.Wait()
 
-			if err != nil {
-				status = err.(*exec.ExitError).ExitCode()
+			// Killing the command can make Wait return an error of the context instead of the exit status
+			var exitError *exec.ExitError
+			if errors.As(err, &exitError) {
+				result.exitCode = exitError.ExitCode()
+			} else if err != nil {
+				result.exitCode = -1
 			}
-			commandResultChannel <- status
+			result.usage = getResourceUsage(cmd.ProcessState)
+			commandResultChannel <- result
 		})
 
+		recordUsage := func(usage *types.ResourceUsage) {
+			if usage != nil {
+				logF(LogTask, "Resource usage: %v.", usage.String())
+				record.Usage = usage
+				shadowDynamicState.TotalUsage.Add(*usage)
+			}
+		}
+
 		// Block until something happens
 		commandSuccess := false
+		commandEnded := false
 		select {
 		case <-cmdContext.Done():
 			// cmdContext derives from BackendState's context, which is killed by Ctrl+C interrupt
 			logF(LogTask|LogDeactivation, "Backend killed.")
-		case exitCode := <-commandResultChannel:
+			record.Result = types.ExecutionResultBackendKilled
+		case result := <-commandResultChannel:
 			// Command ended normally
+			commandEnded = true
+			exitCode := result.exitCode
 			logF(LogTask, "Command ended with code %v.", exitCode)
 			shadowDynamicState.LastExitValue = exitCode
+			record.ExitCode = exitCode
+			recordUsage(result.usage)
 			if exitCode == 0 {
 				commandSuccess = true
+				record.Result = types.ExecutionResultSuccess
+				events.Emit(i.EventTaskExited, task, types.Event{Execution: &executionId, ExitCode: &exitCode})
+			} else {
+				record.Result = types.ExecutionResultFailure
+				events.Emit(i.EventTaskFailed, task, types.Event{Execution: &executionId, ExitCode: &exitCode})
 			}
 		case response := <-perTaskLogger.outChannel:
 			// Logger failed. We don't want to execute the command without logging. Kill it and return error.
 			logF(LogDeactivation|LogFlagErr, "Failed logging: %v", response.err.Error())
+			record.Result = types.ExecutionResultLogFailed
 		case reason := <-task.Channels.StopChannel:
 			logF(LogDeactivation, "Task killed (%v).", reason)
+			record.Result = types.ExecutionResultStopped
 		}
 
+		// Kill the command, if it didn't end by itself, and collect its resource usage. Long-running commands usually
+		// end this way.
+		if !commandEnded {
+			cmdCancel()
+			close(commandKilled)
+			select {
+			case result := <-commandResultChannel:
+				recordUsage(result.usage)
+				if !result.isOutputComplete {
+					log(LogTask, "Output of the command is incomplete, because its children kept it open after it was killed.")
+					<-outputStreamed // cmd.Wait() closed the pipes, so streaming ends right away
+				}
+			case <-time.After(outputDrainTimeout + commandReapTimeout):
+				log(LogTask, "Command did not exit after being killed, its resource usage is unknown.")
+			}
+		}
+
+		record.EndTime = time.Now()
+		record.Duration = record.EndTime.Sub(record.StartTime)
+		shadowDynamicState.LastRunDuration = record.Duration
+
 		// Send a separator to the per-task logger to notify it that the task execution ended. Wait for its response via channel.
 		// It will respond with paths of stdout/stderr files that were just closed. If they are valid, assign them to the task's
 		// dynamic state.
@@ -176,7 +284,10 @@ func ExecuteTask(
 
 			shadowDynamicState.LastStdoutFilePath = response.stdoutFilePath
 			shadowDynamicState.LastStderrFilePath = response.stderrFilePath
+			record.StdoutFilePath = response.stdoutFilePath
+			record.StderrFilePath = response.stderrFilePath
 		}
+		shadowDynamicState.History = appendExecutionRecord(shadowDynamicState.History, record)
 
 		// Update execution and failure counts
 		shadowDynamicState.RunCount++
@@ -192,6 +303,16 @@ func ExecuteTask(
 			logF(LogDeactivation, "Task reached subsequent failure count limit of %v.", task.MaxSubsequentFailures)
 		}
 
+		// Compute delay before the next execution
+		delay := time.Millisecond * time.Duration(task.DelayAfterFailureMs)
+		if commandSuccess {
+			delay = time.Millisecond * time.Duration(task.DelayAfterSuccessMs)
+		}
+		shadowDynamicState.CurrentPid = 0
+		if !shadowDynamicState.IsDeactivated {
+			shadowDynamicState.NextRunTime = time.Now().Add(delay)
+		}
+
 		// Update dynamic state
 		schedulerLock.Lock()
 		task.Dynamic = shadowDynamicState
@@ -199,19 +320,16 @@ func ExecuteTask(
 
 		// Perform delay between command executions
 		if !shadowDynamicState.IsDeactivated {
-			delay := task.DelayAfterFailureMs
-			if commandSuccess {
-				delay = task.DelayAfterSuccessMs
-			}
-
 			// Start a timer
-			timer := time.NewTimer(time.Millisecond * time.Duration(delay))
+			timer := time.NewTimer(delay)
 			defer timer.Stop()
 
 			// Wait for either the timer or the stop channel
 			select {
 			case <-timer.C:
+				trigger = types.ExecutionTriggerTimer
 			case <-task.Channels.RefreshChannel:
+				trigger = types.ExecutionTriggerRefresh
 			case reason := <-task.Channels.StopChannel:
 				logF(LogDeactivation, "Task killed (%v).", reason)
 			case <-cmdContext.Done():
@@ -221,7 +339,38 @@ func ExecuteTask(
 	}
 
 	// Update dynamic state in case we broke from the loop
+	shadowDynamicState.CurrentPid = 0
+	shadowDynamicState.NextRunTime = time.Time{}
 	schedulerLock.Lock()
 	task.Dynamic = shadowDynamicState
 	schedulerLock.Unlock()
+
+	events.Emit(i.EventTaskDeactivated, task, types.Event{Reason: shadowDynamicState.DeactivatedReason})
+}
+
+// Time to wait for the last output of a killed command, e.g. its shutdown messages. Children of the command can keep
+// the pipes open for much longer.
+const outputDrainTimeout = time.Second
+
+// Time to wait for a killed command to be reaped after its output was read, so its resource usage can be recorded.
+const commandReapTimeout = time.Second * 2
+
+// getResourceUsage converts rusage of an exited process. It returns nil if the platform does not provide it.
+func getResourceUsage(state *os.ProcessState) *types.ResourceUsage {
+	if state == nil {
+		return nil
+	}
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || rusage == nil {
+		return nil
+	}
+
+	return &types.ResourceUsage{
+		UserTime:                   time.Duration(rusage.Utime.Nano()),
+		SystemTime:                 time.Duration(rusage.Stime.Nano()),
+		MaxRssKb:                   int64(rusage.Maxrss), // kilobytes on Linux
+		MajorFaults:                int64(rusage.Majflt),
+		VoluntaryContextSwitches:   int64(rusage.Nvcsw),
+		InvoluntaryContextSwitches: int64(rusage.Nivcsw),
+	}
 }
@@ -0,0 +1,45 @@
+package scheduler
+
+import "sync"
+
+// LogNotifier wakes up everyone following the task log, whenever something is written to it. Notifications do not
+// carry any data, subscribers are expected to read the file on their own. Multiple writes can be coalesced into one
+// notification, if the subscriber is not keeping up. The same channel can be subscribed to logs of many tasks.
+type LogNotifier struct {
+	lock        sync.Mutex
+	subscribers map[chan struct{}]struct{}
+}
+
+func CreateLogNotifier() *LogNotifier {
+	return &LogNotifier{
+		subscribers: make(map[chan struct{}]struct{}),
+	}
+}
+
+// Subscribe registers a channel to be notified. It should be buffered, because notifications are never blocking.
+func (notifier *LogNotifier) Subscribe(channel chan struct{}) {
+	notifier.lock.Lock()
+	defer notifier.lock.Unlock()
+
+	notifier.subscribers[channel] = struct{}{}
+}
+
+func (notifier *LogNotifier) Unsubscribe(channel chan struct{}) {
+	notifier.lock.Lock()
+	defer notifier.lock.Unlock()
+
+	delete(notifier.subscribers, channel)
+}
+
+func (notifier *LogNotifier) Notify() {
+	notifier.lock.Lock()
+	defer notifier.lock.Unlock()
+
+	for channel := range notifier.subscribers {
+		select {
+		case channel <- struct{}{}:
+		default:
+			// Subscriber already has a pending notification
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package scheduler
+
+import (
+	"os"
+	i "spieven/backend/interfaces"
+	"spieven/common"
+	"spieven/common/types"
+	"strings"
+	"time"
+)
+
+// LogTrimJob is a snapshot of everything needed to trim logs of a single task. Jobs are collected under the scheduler
+// lock, but run without holding it, so file operations do not block frontend commands and running tasks.
+type LogTrimJob struct {
+	id                      int
+	friendlyName            string
+	display                 types.DisplaySelection
+	retention               types.LogRetention
+	lastFinishedExecutionId int
+	isTrimmed               bool // the task was trimmed out of memory, so its logs will not grow anymore
+	isSettled               bool // set by Run, if the logs of a trimmed task will not change on later runs
+}
+
+func newLogTrimJob(task *Task, isTrimmed bool) *LogTrimJob {
+	return &LogTrimJob{
+		id:                      task.Computed.Id,
+		friendlyName:            task.FriendlyName,
+		display:                 task.Display,
+		retention:               task.LogRetention,
+		lastFinishedExecutionId: task.Dynamic.RunCount - 1,
+		isTrimmed:               isTrimmed,
+	}
+}
+
+func (job *LogTrimJob) GetId() int                         { return job.id }
+func (job *LogTrimJob) GetFriendlyName() string            { return job.friendlyName }
+func (job *LogTrimJob) GetDisplay() types.DisplaySelection { return job.display }
+
+// CollectLogTrimJobs returns jobs trimming logs of all tasks. Tasks trimmed out of memory still have their logs on
+// disk, so they are processed as well, until their logs are settled.
+func (scheduler *Scheduler) CollectLogTrimJobs() []*LogTrimJob {
+	scheduler.lock.AssertLocked()
+
+	jobs := make([]*LogTrimJob, 0, len(scheduler.tasks)+len(scheduler.trimmedLogTrimJobs))
+	for _, task := range scheduler.tasks {
+		jobs = append(jobs, newLogTrimJob(task, false))
+	}
+	for _, job := range scheduler.trimmedLogTrimJobs {
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// ForgetSettledLogTrimJobs stops processing logs of trimmed tasks, which were settled by the jobs.
+func (scheduler *Scheduler) ForgetSettledLogTrimJobs(jobs []*LogTrimJob) {
+	scheduler.lock.AssertLocked()
+
+	for _, job := range jobs {
+		// The task could have been resumed and trimmed again while the job was running, so it could have a new job
+		if job.isSettled && scheduler.trimmedLogTrimJobs[job.id] == job {
+			delete(scheduler.trimmedLogTrimJobs, job.id)
+		}
+	}
+}
+
+// RunLogTrimJobs runs all jobs. The directory with stdout/stderr files is only read once for all of them. It must not be
+// called with the scheduler lock held.
+func RunLogTrimJobs(jobs []*LogTrimJob, messages i.IMessages, files i.IFiles) {
+	allExecutionFiles, err := files.GetAllExecutionLogFiles()
+	if err != nil {
+		messages.AddF(i.BackendMessageError, i.MessageKindLogs, nil, "Failed listing log files: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		job.run(messages, files, allExecutionFiles[job.id])
+	}
+}
+
+// run removes and compresses log files of the task according to its retention settings. Stdout/stderr files of the task
+// are passed grouped by execution id, as returned by GetAllExecutionLogFiles.
+func (job *LogTrimJob) run(messages i.IMessages, files i.IFiles, executionFiles map[int][]string) {
+	retention := job.retention
+	now := time.Now()
+	hasFailed := false
+	hasFilesLeft := false
+
+	isExpired := func(path string) bool {
+		if !retention.HasMaxAge() {
+			return false
+		}
+		info, err := os.Stat(path)
+		return err == nil && now.Sub(info.ModTime()) > retention.MaxAge
+	}
+	removeFile := func(path string) {
+		if err := os.Remove(path); err != nil {
+			messages.AddF(i.BackendMessageError, i.MessageKindLogs, job, "Failed removing log file %v", path)
+			hasFailed = true
+		}
+	}
+	compressFile := func(path string) {
+		if err := common.CompressFile(path); err != nil {
+			messages.AddF(i.BackendMessageError, i.MessageKindLogs, job, "Failed compressing log file %v", path)
+			hasFailed = true
+		}
+		hasFilesLeft = true
+	}
+
+	// Stdout and stderr files. Files of the execution in progress are still being written to, so we cannot touch them.
+	// The most recent finished execution is never compressed, because it's used to read the last stdout of the task.
+	lastFinishedExecutionId := job.lastFinishedExecutionId
+	for executionId, paths := range executionFiles {
+		if executionId > lastFinishedExecutionId {
+			continue
+		}
+
+		isOutdated := retention.HasMaxExecutions() && executionId <= lastFinishedExecutionId-retention.MaxExecutions
+		for _, path := range paths {
+			if isOutdated || isExpired(path) {
+				removeFile(path)
+			} else if retention.Compress && executionId < lastFinishedExecutionId && !strings.HasSuffix(path, ".gz") {
+				compressFile(path)
+			} else {
+				hasFilesLeft = true
+			}
+		}
+	}
+
+	// Rotated task log. The current task log is never touched, it's rotated by the per-task logger itself.
+	rotatedTaskLogFile := files.GetRotatedTaskLogFile(job.id)
+	for _, path := range []string{rotatedTaskLogFile, rotatedTaskLogFile + ".gz"} {
+		if !common.FileExists(path) {
+			continue
+		}
+
+		if isExpired(path) {
+			removeFile(path)
+		} else if retention.Compress && !strings.HasSuffix(path, ".gz") {
+			compressFile(path)
+		} else {
+			hasFilesLeft = true
+		}
+	}
+
+	// Logs of a trimmed task can only change by expiring, so without a max age a single successful run is enough
+	job.isSettled = job.isTrimmed && !hasFailed && (!retention.HasMaxAge() || !hasFilesLeft)
+}
@@ -0,0 +1,317 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	i "spieven/backend/interfaces"
+	"spieven/common"
+	"spieven/common/types"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LogSinkRecord is a line of a task log along with metadata describing the task it comes from.
+type LogSinkRecord struct {
+	types.TaskLogRecord
+	TaskId   int
+	TaskName string
+	Display  string
+}
+
+// LogSink receives lines of task logs, in addition to the task log file. Sinks can be shared by many tasks, so they
+// have to be safe to use from multiple goroutines. Failing to write to a sink never stops the task.
+type LogSink interface {
+	Write(record *LogSinkRecord) error
+	Close()
+}
+
+// logSinkDestination is the actual receiver of records of a LogSink. Writing to it can block, so it's only used by
+// a single goroutine of queuedLogSink.
+type logSinkDestination interface {
+	write(record *LogSinkRecord) error
+	close()
+}
+
+// CreateLogSinks creates all sinks configured in LogSinks. If some of them fail, the remaining ones are still returned
+// along with the error. Errors occurring later and dropped records are reported as backend messages.
+func CreateLogSinks(config types.LogSinks, messages i.IMessages) ([]LogSink, error) {
+	var sinks []LogSink
+	var errs []error
+
+	if config.Syslog != "" {
+		destination, err := createSyslogSink(config.Syslog, config.GetSyslogMaxLength())
+		if err == nil {
+			sinks = append(sinks, createQueuedLogSink("syslog "+config.Syslog, destination, messages))
+		} else {
+			errs = append(errs, err)
+		}
+	}
+	if config.LogPipe != "" {
+		destination, err := createPipeSink(config.LogPipe)
+		if err == nil {
+			sinks = append(sinks, createQueuedLogSink("log pipe", destination, messages))
+		} else {
+			errs = append(errs, err)
+		}
+	}
+
+	return sinks, errors.Join(errs...)
+}
+
+func CloseLogSinks(sinks []LogSink) {
+	for _, sink := range sinks {
+		sink.Close()
+	}
+}
+
+// Number of records waiting for a slow destination, after which new records are dropped.
+const logSinkQueueLength = 1024
+
+// Time given to a destination to receive the queued records when the sink is closed.
+const logSinkCloseTimeout = time.Second * 2
+
+var errLogSinkQueueFull = errors.New("log sink queue is full")
+
+// queuedLogSink passes records to a destination from its own goroutine, so a slow or stuck destination cannot block
+// the per-task logger and with it the task. Records which do not fit into the queue are dropped.
+type queuedLogSink struct {
+	name         string
+	destination  logSinkDestination
+	messages     i.IMessages
+	lock         sync.Mutex
+	queue        chan LogSinkRecord
+	isClosed     bool
+	droppedCount atomic.Uint64
+	writerDone   chan struct{}
+}
+
+func createQueuedLogSink(name string, destination logSinkDestination, messages i.IMessages) *queuedLogSink {
+	sink := &queuedLogSink{
+		name:        name,
+		destination: destination,
+		messages:    messages,
+		queue:       make(chan LogSinkRecord, logSinkQueueLength),
+		writerDone:  make(chan struct{}),
+	}
+	go sink.runWriter()
+	return sink
+}
+
+// Write queues the record without waiting for the destination.
+func (sink *queuedLogSink) Write(record *LogSinkRecord) error {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+
+	if sink.isClosed {
+		return errors.New("log sink is closed")
+	}
+	select {
+	case sink.queue <- *record:
+		return nil
+	default:
+		// The writer reports the number of dropped records once the destination catches up, which may never happen.
+		// Report the first one right away.
+		if sink.droppedCount.Add(1) == 1 {
+			sink.messages.AddF(i.BackendMessageError, i.MessageKindLogs, nil, "Dropping records, because %v cannot keep up", sink.name)
+		}
+		return errLogSinkQueueFull
+	}
+}
+
+// Close lets the destination receive the queued records and closes it. A destination which doesn't take them in time
+// is closed anyway.
+func (sink *queuedLogSink) Close() {
+	sink.lock.Lock()
+	if sink.isClosed {
+		sink.lock.Unlock()
+		return
+	}
+	sink.isClosed = true
+	close(sink.queue)
+	sink.lock.Unlock()
+
+	select {
+	case <-sink.writerDone:
+	case <-time.After(logSinkCloseTimeout):
+	}
+	sink.destination.close()
+	<-sink.writerDone
+	sink.reportDroppedRecords()
+}
+
+// runWriter passes queued records to the destination. Errors are reported once, until the destination recovers, so
+// a broken destination doesn't flood backend messages.
+func (sink *queuedLogSink) runWriter() {
+	defer close(sink.writerDone)
+
+	isFailing := false
+	for record := range sink.queue {
+		sink.reportDroppedRecords()
+
+		err := sink.destination.write(&record)
+		if err != nil && !isFailing {
+			sink.messages.AddF(i.BackendMessageError, i.MessageKindLogs, nil, "Failed writing to %v: %v", sink.name, err)
+		}
+		isFailing = err != nil
+	}
+}
+
+func (sink *queuedLogSink) reportDroppedRecords() {
+	if droppedCount := sink.droppedCount.Swap(0); droppedCount > 0 {
+		sink.messages.AddF(i.BackendMessageError, i.MessageKindLogs, nil, "Dropped %v records, because %v could not keep up", droppedCount, sink.name)
+	}
+}
+
+// Private enterprise number reserved for documentation by RFC 5612. It's used to name our structured data element.
+const syslogStructuredDataId = "spieven@32473"
+
+type syslogSink struct {
+	network    string
+	address    string
+	connection net.Conn // nil after a failed reconnect, until the next write
+	hostname   string
+	maxLength  int
+}
+
+func createSyslogSink(address string, maxLength int) (*syslogSink, error) {
+	network, address, err := types.ParseSyslogAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	connection, err := net.Dial(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed connecting to syslog: %w", err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	return &syslogSink{
+		network:    network,
+		address:    address,
+		connection: connection,
+		hostname:   hostname,
+		maxLength:  maxLength,
+	}, nil
+}
+
+// Write sends the record as a single RFC 5424 message with facility "user". Stream of the record is used as MSGID
+// and task metadata is passed as structured data. Messages longer than the maximum length are truncated.
+func (sink *syslogSink) write(record *LogSinkRecord) error {
+	severity := 6 // informational
+	switch record.Stream {
+	case types.TaskLogStreamStderr:
+		severity = 3 // error
+	case types.TaskLogStreamDiagnostic:
+		severity = 5 // notice
+	}
+	const facility = 1 // user-level messages
+
+	escapeParam := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace
+	structuredData := fmt.Sprintf(`[%v taskId="%v" taskName="%v" display="%v" execution="%v"]`,
+		syslogStructuredDataId, record.TaskId, escapeParam(record.TaskName), escapeParam(record.Display), record.Execution)
+
+	message := fmt.Sprintf("<%v>1 %v %v spieven - %v %v %v",
+		facility*8+severity,
+		record.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		sink.hostname,
+		record.Stream,
+		structuredData,
+		record.Line)
+	message = common.TruncateUtf8(message, sink.maxLength)
+
+	return sink.send([]byte(message))
+}
+
+// send writes a message, reconnecting once if it fails. The syslog daemon can be restarted, which recreates its socket,
+// so the old connection would fail forever.
+func (sink *syslogSink) send(message []byte) error {
+	if sink.connection != nil {
+		_, err := sink.connection.Write(message)
+		if err == nil {
+			return nil
+		}
+		sink.connection.Close()
+		sink.connection = nil
+	}
+
+	connection, err := net.Dial(sink.network, sink.address)
+	if err != nil {
+		return fmt.Errorf("failed reconnecting to syslog: %w", err)
+	}
+	sink.connection = connection
+	_, err = connection.Write(message)
+	return err
+}
+
+func (sink *syslogSink) close() {
+	if sink.connection != nil {
+		sink.connection.Close()
+	}
+}
+
+type pipeSink struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	encoder *json.Encoder
+	broken  bool
+}
+
+func createPipeSink(command string) (*pipeSink, error) {
+	cmd := exec.Command("sh", "-c", command)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed starting log pipe command: %w", err)
+	}
+
+	return &pipeSink{
+		cmd:     cmd,
+		stdin:   stdin,
+		encoder: json.NewEncoder(stdin),
+	}, nil
+}
+
+// Write sends the record as a json object followed by a newline. If the command exits, the sink stops writing.
+func (sink *pipeSink) write(record *LogSinkRecord) error {
+	if sink.broken {
+		return errors.New("log pipe command is not running")
+	}
+
+	err := sink.encoder.Encode(record)
+	if err != nil {
+		sink.broken = true
+	}
+	return err
+}
+
+// close closes stdin of the command and waits for it to exit. Commands which do not exit on their own are killed.
+// It can be called while a write is blocked, which then fails.
+func (sink *pipeSink) close() {
+	sink.stdin.Close()
+
+	waitResult := make(chan error, 1)
+	go func() {
+		waitResult <- sink.cmd.Wait()
+	}()
+	select {
+	case <-waitResult:
+	case <-time.After(time.Second * 2):
+		sink.cmd.Process.Kill()
+		<-waitResult
+	}
+}
@@ -1,17 +1,31 @@
 package scheduler
 
 import (
-	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	i "spieven/backend/interfaces"
 	"spieven/common"
+	"spieven/common/types"
+	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 )
 
+// Size of chunks read from stdout/stderr of the task. Output is not interpreted in any way while reading, so it can
+// contain arbitrarily long lines and binary data.
+const outputChunkSize = 32 * 1024
+
+// Lines of task output longer than this are split into multiple lines in the task log. Stdout/stderr files always
+// contain the output unchanged.
+const maxTaskLogLineLength = 16 * 1024
+
 type LogMessage struct {
-	msg          string
+	msg          string // diagnostic message or raw chunk of output
+	time         time.Time
 	isDiagnostic bool
 	isSeparator  bool
 	isStop       bool
@@ -21,6 +35,7 @@ type LogMessage struct {
 func diagnosticMessage(content string, isSeparator bool) LogMessage {
 	return LogMessage{
 		msg:          content,
+		time:         time.Now(),
 		isDiagnostic: true,
 		isSeparator:  isSeparator,
 	}
@@ -32,13 +47,68 @@ func stopMessage() LogMessage {
 	}
 }
 
-func outMessage(message string, isStderr bool) LogMessage {
+func outMessage(chunk []byte, isStderr bool) LogMessage {
 	return LogMessage{
-		msg:      fmt.Sprintf("%v\n", message),
+		msg:      string(chunk),
+		time:     time.Now(),
 		isStderr: isStderr,
 	}
 }
 
+// outputLineSplitter accumulates raw chunks of output of one stream and splits them into lines for the task log.
+type outputLineSplitter struct {
+	pending     []byte
+	pendingTime time.Time // time of the first chunk of the pending line
+}
+
+func (splitter *outputLineSplitter) write(chunk string, chunkTime time.Time, emitLine func(time.Time, string) error) error {
+	if len(splitter.pending) == 0 {
+		splitter.pendingTime = chunkTime
+	}
+	splitter.pending = append(splitter.pending, chunk...)
+
+	for {
+		lineLength := bytes.IndexByte(splitter.pending, '\n')
+		if lineLength < 0 {
+			if len(splitter.pending) <= maxTaskLogLineLength {
+				return nil
+			}
+			lineLength = longLineCut(splitter.pending)
+		} else if lineLength > maxTaskLogLineLength {
+			lineLength = longLineCut(splitter.pending)
+		}
+
+		line := string(splitter.pending[:lineLength])
+		splitter.pending = bytes.TrimPrefix(splitter.pending[lineLength:], []byte{'\n'})
+		if err := emitLine(splitter.pendingTime, line); err != nil {
+			return err
+		}
+		splitter.pendingTime = chunkTime
+	}
+}
+
+// longLineCut returns the length of the first part of a line longer than maxTaskLogLineLength. The cut is moved back to
+// the start of a rune, so a multi-byte character is not split between two lines of the task log.
+func longLineCut(line []byte) int {
+	for cut := maxTaskLogLineLength; cut > maxTaskLogLineLength-utf8.UTFMax; cut-- {
+		if utf8.RuneStart(line[cut]) {
+			return cut
+		}
+	}
+	return maxTaskLogLineLength // not UTF-8, so there is nothing to keep together
+}
+
+// flush emits the pending line, which is not terminated with a newline. Called when the task execution ends.
+func (splitter *outputLineSplitter) flush(emitLine func(time.Time, string) error) error {
+	if len(splitter.pending) == 0 {
+		return nil
+	}
+
+	line := string(splitter.pending)
+	splitter.pending = splitter.pending[:0]
+	return emitLine(splitter.pendingTime, line)
+}
+
 type LogResponse struct {
 	err            error
 	stdoutFilePath string
@@ -46,28 +116,48 @@ type LogResponse struct {
 }
 
 type FileLogger struct {
-	files         i.IFiles
-	goroutines    i.IGoroutines
-	channel       chan LogMessage  // input channel for incoming messages
-	outChannel    chan LogResponse // output channel for errors or diagnostics
-	waitGroup     sync.WaitGroup
-	taskId        int
-	captureStdout bool
-	captureStderr bool
+	files            i.IFiles
+	goroutines       i.IGoroutines
+	channel          chan LogMessage  // input channel for incoming messages
+	outChannel       chan LogResponse // output channel for errors or diagnostics
+	waitGroup        sync.WaitGroup
+	taskId           int
+	taskName         string
+	display          string
+	firstExecutionId int
+	captureStdout    bool
+	captureStderr    bool
+	maxTaskLogBytes  int64
+	logFormat        types.TaskLogFormat
+	logNotifier      *LogNotifier
+	sinks            []LogSink // additional destinations for records of the task log
 
 	_ common.NoCopy
 }
 
-func CreateFileLogger(files i.IFiles, goroutines i.IGoroutines, taskId int, captureStdout bool, captureStderr bool) FileLogger {
+func CreateFileLogger(
+	files i.IFiles,
+	goroutines i.IGoroutines,
+	task *Task,
+	firstExecutionId int,
+	sinks []LogSink,
+) FileLogger {
 	return FileLogger{
-		files:         files,
-		goroutines:    goroutines,
-		channel:       make(chan LogMessage),
-		outChannel:    make(chan LogResponse, 1),
-		waitGroup:     sync.WaitGroup{},
-		taskId:        taskId,
-		captureStdout: captureStdout,
-		captureStderr: captureStderr,
+		files:            files,
+		goroutines:       goroutines,
+		channel:          make(chan LogMessage),
+		outChannel:       make(chan LogResponse, 1),
+		waitGroup:        sync.WaitGroup{},
+		taskId:           task.Computed.Id,
+		taskName:         task.FriendlyName,
+		display:          task.Display.ComputeDisplayLabel(),
+		firstExecutionId: firstExecutionId,
+		captureStdout:    task.CaptureStdout,
+		captureStderr:    task.CaptureStderr,
+		maxTaskLogBytes:  task.LogRetention.MaxTaskLogBytes,
+		logFormat:        task.LogFormat,
+		logNotifier:      task.Channels.LogNotifier,
+		sinks:            sinks,
 	}
 }
 
@@ -77,14 +167,61 @@ func (log *FileLogger) run() error {
 	defer log.waitGroup.Done()
 
 	// Open task file for writing
-	taskFile, err := os.Create(log.files.GetTaskLogFile(log.taskId))
+	taskFilePath := log.files.GetTaskLogFile(log.taskId)
+	taskFile, err := os.Create(taskFilePath)
 	if err != nil {
 		return fmt.Errorf("failed opening task log file")
 	}
 
+	// Task file can grow indefinitely for long-running tasks, so rotate it after reaching the size limit. Only one
+	// rotated file is kept. It's cleaned up later by the trim goroutine according to the retention settings.
+	var taskFileSize int64
+	writeToTaskFile := func(content string) error {
+		if err := common.WriteStringToWriter(taskFile, content); err != nil {
+			return err
+		}
+		log.logNotifier.Notify()
+
+		taskFileSize += int64(len(content))
+		if log.maxTaskLogBytes > 0 && taskFileSize >= log.maxTaskLogBytes {
+			taskFile.Close()
+			if err := os.Rename(taskFilePath, log.files.GetRotatedTaskLogFile(log.taskId)); err != nil {
+				return err
+			}
+			newTaskFile, err := os.Create(taskFilePath)
+			if err != nil {
+				return err
+			}
+			taskFile = newTaskFile
+			taskFileSize = 0
+		}
+		return nil
+	}
+
+	// Every line of the task file is tagged with a timestamp, its origin and the execution it belongs to. The same
+	// records are forwarded to log sinks. Errors of the sinks are ignored, they must not affect the task.
+	writeRecordToTaskFile := func(recordTime time.Time, stream types.TaskLogStream, executionId int, line string) error {
+		record := types.TaskLogRecord{
+			Time:      recordTime,
+			Stream:    stream,
+			Execution: executionId,
+			Line:      line,
+		}
+		for _, sink := range log.sinks {
+			sink.Write(&LogSinkRecord{
+				TaskLogRecord: record,
+				TaskId:        log.taskId,
+				TaskName:      log.taskName,
+				Display:       log.display,
+			})
+		}
+		return writeToTaskFile(record.Format(log.logFormat))
+	}
+
 	// Open stdout/stderr files for writing. We're going to reopen them as soon as task execution ends, so each execution gets
-	// its own stdout/stderr files.
-	taskExecutionId := 0
+	// its own stdout/stderr files. Execution ids continue from previous activations of the task, so resuming a task does
+	// not overwrite files of earlier executions.
+	taskExecutionId := log.firstExecutionId
 	stdoutFilePath, stderrFilePath := log.files.GetStdoutStderrLogFiles(log.taskId, taskExecutionId)
 	var stdoutFile *os.File
 	var stderrFile *os.File
@@ -104,6 +241,22 @@ func (log *FileLogger) run() error {
 		}
 	}
 
+	// Task output arrives in raw chunks, so lines have to be reassembled. Task log is meant to be readable, so we
+	// sanitize the lines as well. Invalid UTF-8 is replaced and carriage returns are removed.
+	var stdoutSplitter, stderrSplitter outputLineSplitter
+	emitOutputLine := func(stream types.TaskLogStream) func(time.Time, string) error {
+		return func(lineTime time.Time, line string) error {
+			line = strings.ToValidUTF8(strings.TrimSuffix(line, "\r"), "\uFFFD")
+			return writeRecordToTaskFile(lineTime, stream, taskExecutionId, line)
+		}
+	}
+	flushOutputLines := func() error {
+		if err := stdoutSplitter.flush(emitOutputLine(types.TaskLogStreamStdout)); err != nil {
+			return err
+		}
+		return stderrSplitter.flush(emitOutputLine(types.TaskLogStreamStderr))
+	}
+
 	// Start the main loop in a goroutine. It will run until it receives a stop message.
 	log.goroutines.StartGoroutine(func() {
 		// Main loop
@@ -117,9 +270,18 @@ func (log *FileLogger) run() error {
 				break
 			}
 
+			// Output of the task that is not terminated with a newline has to be written before any diagnostic message.
+			if message.isDiagnostic {
+				loggingErr = flushOutputLines()
+				if loggingErr != nil {
+					break
+				}
+			}
+
 			// Handle separator message, meaning the task execution ended. String content of separator messages is ignored.
 			if message.isSeparator {
 				// Reopen stdout and stderr files for the next execution with an incremented execution ID.
+				finishedExecutionId := taskExecutionId
 				taskExecutionId++
 				var oldStdoutFilePath, oldStderrFilePath string
 				newStdoutFilePath, newStderrFilePath := log.files.GetStdoutStderrLogFiles(log.taskId, taskExecutionId)
@@ -135,39 +297,47 @@ func (log *FileLogger) run() error {
 					break
 				}
 
-				// Task file - write separator lines
+				// Task file - write separator lines. Empty lines are only for readability, so skip them in json format.
 				if oldStdoutFilePath != "" {
 					stdoutMsg := fmt.Sprintf("Stdout written to %v", oldStdoutFilePath)
-					common.WriteStringToWriter(taskFile, log.WrapWithDiagnosticDecorations(&stdoutMsg))
+					writeRecordToTaskFile(message.time, types.TaskLogStreamDiagnostic, finishedExecutionId, stdoutMsg)
 				}
 				if oldStderrFilePath != "" {
 					stderrMsg := fmt.Sprintf("Stderr written to %v", oldStderrFilePath)
-					common.WriteStringToWriter(taskFile, log.WrapWithDiagnosticDecorations(&stderrMsg))
+					writeRecordToTaskFile(message.time, types.TaskLogStreamDiagnostic, finishedExecutionId, stderrMsg)
+				}
+				if log.logFormat == types.TaskLogFormatText {
+					writeToTaskFile("\n\n\n")
 				}
-				common.WriteStringToWriter(taskFile, "\n\n\n")
 
 				continue
 			}
 
-			// Stdout file
-			if !message.isDiagnostic {
-				if message.isStderr && stderrFile != nil {
-					loggingErr = common.WriteStringToWriter(stderrFile, message.msg)
-				} else if stdoutFile != nil {
-					loggingErr = common.WriteStringToWriter(stdoutFile, message.msg)
-				}
+			// Diagnostic messages only go to the task file
+			if message.isDiagnostic {
+				loggingErr = writeRecordToTaskFile(message.time, types.TaskLogStreamDiagnostic, taskExecutionId, message.msg)
 				if loggingErr != nil {
-					fmt.Printf("BBB for isStderr=%v  err: %v\n", message.isStderr, loggingErr.Error())
 					break
 				}
+				continue
 			}
 
-			// Task file
-			taskFileContent := message.msg
-			if message.isDiagnostic {
-				taskFileContent = log.WrapWithDiagnosticDecorations(&taskFileContent)
+			// Stdout/stderr files get raw output
+			if message.isStderr && stderrFile != nil {
+				loggingErr = common.WriteStringToWriter(stderrFile, message.msg)
+			} else if !message.isStderr && stdoutFile != nil {
+				loggingErr = common.WriteStringToWriter(stdoutFile, message.msg)
+			}
+			if loggingErr != nil {
+				break
+			}
+
+			// Task file gets output split into lines
+			if message.isStderr {
+				loggingErr = stderrSplitter.write(message.msg, message.time, emitOutputLine(types.TaskLogStreamStderr))
+			} else {
+				loggingErr = stdoutSplitter.write(message.msg, message.time, emitOutputLine(types.TaskLogStreamStdout))
 			}
-			loggingErr = common.WriteStringToWriter(taskFile, taskFileContent)
 			if loggingErr != nil {
 				break
 			}
@@ -188,10 +358,6 @@ func (log *FileLogger) run() error {
 	return nil
 }
 
-func (log *FileLogger) WrapWithDiagnosticDecorations(message *string) string {
-	return fmt.Sprintf("--------------------- %v ---------------------\n", *message)
-}
-
 func (log *FileLogger) FinalizeStdoutAndStderr(stdoutFile **os.File, stderrFile **os.File, newStdoutPath *string, newStderrPath *string) (string, string, error) {
 	oldStdoutFilePath := ""
 	oldStderrFilePath := ""
@@ -243,13 +409,24 @@ func (log *FileLogger) stop() {
 }
 
 func (log *FileLogger) streamOutput(reader io.Reader, isStderr bool) {
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		t := scanner.Text()
-		log.channel <- outMessage(t, isStderr)
-	}
+	buffer := make([]byte, outputChunkSize)
+	for {
+		bytesRead, err := reader.Read(buffer)
+		if bytesRead > 0 {
+			log.channel <- outMessage(buffer[:bytesRead], isStderr)
+		}
 
-	if err := scanner.Err(); err != nil {
-		panic("Classic \"This will never happen moment\". See you in a bit :)")
+		if err != nil {
+			// Pipes are closed by the backend, if children of a killed command keep them open for too long. It's
+			// reported when the command is killed.
+			if err != io.EOF && !errors.Is(err, os.ErrClosed) {
+				streamName := "stdout"
+				if isStderr {
+					streamName = "stderr"
+				}
+				log.channel <- diagnosticMessage(fmt.Sprintf("Failed reading %v: %v", streamName, err), false)
+			}
+			return
+		}
 	}
 }
@@ -5,15 +5,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	i "spieven/backend/interfaces"
 	"spieven/common"
 	"spieven/common/types"
 )
 
 type Scheduler struct {
-	tasks     []*Task
-	currentId int
-	lock      common.CheckedLock
+	tasks              []*Task
+	currentId          int
+	trimmedCount       int                 // number of deactivated tasks pushed out of memory to a file
+	trimmedLogTrimJobs map[int]*LogTrimJob // jobs trimming logs of trimmed tasks, until their logs are settled
+	trimmedTasksCache  []*Task             // parsed file of trimmed tasks, nil until read and after it changes
+	lock               common.CheckedLock
+	backendLogSinks    []LogSink // sinks receiving logs of all tasks, set once before any task is run
+	events             i.IEvents // set once before any task is run
 
 	_ common.NoCopy
 }
@@ -22,10 +28,19 @@ func (scheduler *Scheduler) Lock()                 { scheduler.lock.Lock() }
 func (scheduler *Scheduler) Unlock()               { scheduler.lock.Unlock() }
 func (scheduler *Scheduler) GetTasks() []*Task     { return scheduler.tasks }
 func (scheduler *Scheduler) IsValidId(id int) bool { return id < scheduler.currentId }
+func (scheduler *Scheduler) GetNextId() int        { return scheduler.currentId }
+func (scheduler *Scheduler) GetTrimmedCount() int  { return scheduler.trimmedCount }
+
+func (scheduler *Scheduler) SetBackendLogSinks(sinks []LogSink) { scheduler.backendLogSinks = sinks }
+func (scheduler *Scheduler) CloseBackendLogSinks()              { CloseLogSinks(scheduler.backendLogSinks) }
+func (scheduler *Scheduler) SetEvents(events i.IEvents)         { scheduler.events = events }
 
 func (scheduler *Scheduler) Trim(messages i.IMessages, files i.IFiles) {
 	scheduler.lock.AssertLocked()
 
+	// Dropped on every trim, even if no task is pushed out, so trimmed tasks don't stay in memory for long
+	scheduler.trimmedTasksCache = nil
+
 	var tasksToKeep []*Task
 	var tasksToDeactivate []*Task
 
@@ -43,7 +58,7 @@ func (scheduler *Scheduler) Trim(messages i.IMessages, files i.IFiles) {
 		filePath := files.GetDeactivatedTasksFile()
 		file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
 		if err != nil {
-			messages.AddF(i.BackendMessageError, nil, "Failed to open %s. Cannot push deactivated tasks out of memory to a file.", filePath)
+			messages.AddF(i.BackendMessageError, i.MessageKindStorage, nil, "Failed to open %s. Cannot push deactivated tasks out of memory to a file.", filePath)
 			tasksToKeep = scheduler.tasks // Keep all tasks, so we don't lose data
 		} else {
 			for _, currTask := range tasksToDeactivate {
@@ -58,9 +73,14 @@ func (scheduler *Scheduler) Trim(messages i.IMessages, files i.IFiles) {
 				}
 
 				if err == nil {
-					messages.Add(i.BackendMessageInfo, currTask, "Trimmed task")
+					scheduler.trimmedCount++
+					if scheduler.trimmedLogTrimJobs == nil {
+						scheduler.trimmedLogTrimJobs = make(map[int]*LogTrimJob)
+					}
+					scheduler.trimmedLogTrimJobs[currTask.Computed.Id] = newLogTrimJob(currTask, true)
+					messages.Add(i.BackendMessageInfo, i.MessageKindStorage, currTask, "Trimmed task")
 				} else {
-					messages.AddF(i.BackendMessageError, currTask, "Failed to trim task: %s", err)
+					messages.AddF(i.BackendMessageError, i.MessageKindStorage, currTask, "Failed to trim task: %s", err)
 					tasksToKeep = append(tasksToKeep, currTask)
 				}
 			}
@@ -73,30 +93,38 @@ func (scheduler *Scheduler) Trim(messages i.IMessages, files i.IFiles) {
 	scheduler.tasks = tasksToKeep
 }
 
+// ReadTrimmedTasks returns tasks pushed out of memory to a file. The file is parsed once and cached until the next
+// trim, so the returned tasks are shared and must not be modified.
 func (scheduler *Scheduler) ReadTrimmedTasks(messages i.IMessages, files i.IFiles) []*Task {
 	scheduler.lock.AssertLocked()
 
-	var result []*Task
+	if scheduler.trimmedTasksCache != nil {
+		return scheduler.trimmedTasksCache
+	}
+
+	result := []*Task{}
 
 	filePath := files.GetDeactivatedTasksFile()
 	file, err := os.OpenFile(filePath, os.O_RDONLY, 0644)
 	if err != nil {
-		messages.AddF(i.BackendMessageError, nil, "Failed reading trimmed tasks: %s", err.Error())
+		messages.AddF(i.BackendMessageError, i.MessageKindStorage, nil, "Failed reading trimmed tasks: %s", err.Error())
 		return result
 	}
+	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		var task Task
 		err := json.Unmarshal(scanner.Bytes(), &task)
 		if err != nil {
-			messages.AddF(i.BackendMessageError, nil, "Failed decoding a task from %s: %s", filePath, err.Error())
+			messages.AddF(i.BackendMessageError, i.MessageKindStorage, nil, "Failed decoding a task from %s: %s", filePath, err.Error())
 			continue
 		}
 
 		result = append(result, &task)
 	}
 
+	scheduler.trimmedTasksCache = result
 	return result
 }
 
@@ -141,7 +169,7 @@ func (scheduler *Scheduler) ExtractDeactivatedTask(
 		inputFilePath := files.GetDeactivatedTasksFile()
 		inputFile, err := os.OpenFile(inputFilePath, os.O_RDONLY, 0644)
 		if err != nil {
-			messages.AddF(i.BackendMessageError, nil, "Failed reading trimmed tasks: %s", err.Error())
+			messages.AddF(i.BackendMessageError, i.MessageKindStorage, nil, "Failed reading trimmed tasks: %s", err.Error())
 			return nil, types.RunResponseStatusTaskNotFound
 		}
 		defer inputFile.Close()
@@ -151,7 +179,7 @@ func (scheduler *Scheduler) ExtractDeactivatedTask(
 		defer os.Remove(outputFilePath)
 		defer outputFile.Close()
 		if err != nil {
-			messages.AddF(i.BackendMessageError, nil, "Failed opening tmp file: %s", err.Error())
+			messages.AddF(i.BackendMessageError, i.MessageKindStorage, nil, "Failed opening tmp file: %s", err.Error())
 			return nil, types.RunResponseStatusTaskNotFound
 		}
 
@@ -161,7 +189,7 @@ func (scheduler *Scheduler) ExtractDeactivatedTask(
 			var currentTask Task
 			err := json.Unmarshal(line, &currentTask)
 			if err != nil {
-				messages.AddF(i.BackendMessageError, nil, "Failed decoding a task from %s: %s", inputFilePath, err.Error())
+				messages.AddF(i.BackendMessageError, i.MessageKindStorage, nil, "Failed decoding a task from %s: %s", inputFilePath, err.Error())
 				continue
 			}
 
@@ -170,7 +198,7 @@ func (scheduler *Scheduler) ExtractDeactivatedTask(
 			} else {
 				line = append(line, '\n')
 				if err := common.WriteBytesToWriter(outputFile, line); err != nil {
-					messages.AddF(i.BackendMessageError, nil, "Failed writing to tmp file")
+					messages.AddF(i.BackendMessageError, i.MessageKindStorage, nil, "Failed writing to tmp file")
 					return nil, types.RunResponseStatusTaskNotFound
 				}
 			}
@@ -180,9 +208,12 @@ func (scheduler *Scheduler) ExtractDeactivatedTask(
 			inputFile.Close()
 			outputFile.Close()
 			if err := common.CopyFile(outputFilePath, inputFilePath); err != nil {
-				messages.AddF(i.BackendMessageError, nil, "Failed copying tmp file to ndjson")
+				messages.AddF(i.BackendMessageError, i.MessageKindStorage, nil, "Failed copying tmp file to ndjson")
 				return nil, types.RunResponseStatusTaskNotFound
 			}
+			scheduler.trimmedCount--
+			delete(scheduler.trimmedLogTrimJobs, taskId)
+			scheduler.trimmedTasksCache = nil
 
 			return extractedTask, types.RunResponseStatusSuccess
 		}
@@ -192,15 +223,30 @@ func (scheduler *Scheduler) ExtractDeactivatedTask(
 	return nil, types.RunResponseStatusTaskNotFound
 }
 
+func (scheduler *Scheduler) FindTask(taskId int) *Task {
+	scheduler.lock.AssertLocked()
+
+	for _, currTask := range scheduler.tasks {
+		if currTask.Computed.Id == taskId {
+			return currTask
+		}
+	}
+	return nil
+}
+
 func (scheduler *Scheduler) CheckForTaskConflict(newTask *Task) types.RunResponseStatus {
 	scheduler.lock.AssertLocked()
 
+	return scheduler.checkForTaskConflict(newTask.Computed.Hash, newTask.Computed.NameDisplayHash, nil)
+}
+
+func (scheduler *Scheduler) checkForTaskConflict(hash int, nameDisplayHash int, ignoredTask *Task) types.RunResponseStatus {
 	for _, currTask := range scheduler.tasks {
-		if !currTask.Dynamic.IsDeactivated {
-			if currTask.Computed.Hash == newTask.Computed.Hash {
+		if currTask != ignoredTask && !currTask.Dynamic.IsDeactivated {
+			if currTask.Computed.Hash == hash {
 				return types.RunResponseStatusAlreadyRunning
 			}
-			if currTask.FriendlyName != "" && currTask.Computed.NameDisplayHash == newTask.Computed.NameDisplayHash {
+			if currTask.FriendlyName != "" && currTask.Computed.NameDisplayHash == nameDisplayHash {
 				return types.RunResponseStatusNameDisplayAlreadyRunning
 			}
 		}
@@ -217,20 +263,50 @@ func (scheduler *Scheduler) CheckForDisplay(
 ) types.RunResponseStatus {
 	scheduler.lock.AssertLocked()
 
-	switch newTask.Display.Type {
+	return scheduler.checkForDisplay(newTask, newTask.Display, displays, goroutines, messages)
+}
+
+func (scheduler *Scheduler) checkForDisplay(
+	task *Task,
+	display types.DisplaySelection,
+	displays i.IDisplays,
+	goroutines i.IGoroutines,
+	messages i.IMessages,
+) types.RunResponseStatus {
+	switch display.Type {
 	case types.DisplaySelectionTypeHeadless:
 	case types.DisplaySelectionTypeXorg, types.DisplaySelectionTypeWayland:
-		err := displays.InitDisplay(newTask.Display, scheduler, goroutines, messages)
+		err := displays.InitDisplay(display, scheduler, goroutines, messages)
 		if err != nil {
 			return types.RunResponseStatusInvalidDisplay
 		}
 	default:
-		messages.Add(i.BackendMessageError, newTask, "Invalid display type")
+		messages.Add(i.BackendMessageError, i.MessageKindDisplay, task, "Invalid display type")
 	}
 
 	return types.RunResponseStatusSuccess
 }
 
+// CheckTaskMovable verifies whether a task can be moved to a different display. It validates the display and looks
+// for conflicts with other tasks as if the task was already running on the new display. It should be called before
+// stopping the task, so we don't stop it only to find out it cannot be restarted.
+func (scheduler *Scheduler) CheckTaskMovable(
+	task *Task,
+	display types.DisplaySelection,
+	displays i.IDisplays,
+	goroutines i.IGoroutines,
+	messages i.IMessages,
+) types.RunResponseStatus {
+	scheduler.lock.AssertLocked()
+
+	hash, nameDisplayHash := task.ComputeHashesForDisplay(display)
+	if status := scheduler.checkForTaskConflict(hash, nameDisplayHash, task); status != types.RunResponseStatusSuccess {
+		return status
+	}
+
+	return scheduler.checkForDisplay(task, display, displays, goroutines, messages)
+}
+
 func (scheduler *Scheduler) TryRunTask(
 	newTask *Task,
 	files i.IFiles,
@@ -257,11 +333,14 @@ func (scheduler *Scheduler) TryRunTask(
 	// Schedule
 	scheduler.tasks = append(scheduler.tasks, newTask)
 	goroutines.StartGoroutine(func() {
-		ExecuteTask(newTask, &scheduler.lock, files, goroutines, messages)
+		ExecuteTask(newTask, &scheduler.lock, scheduler.backendLogSinks, files, goroutines, messages, scheduler.events, types.ExecutionTriggerScheduled)
 	})
+	scheduler.events.Emit(i.EventTaskScheduled, newTask, types.Event{})
 	return types.RunResponseStatusSuccess
 }
 
+// TryResumeTask restarts a deactivated task, keeping its id. The task must have been extracted from the scheduler with
+// ExtractDeactivatedTask. If the task cannot be restarted, it is put back to the scheduler as deactivated.
 func (scheduler *Scheduler) TryResumeTask(
 	newTask *Task,
 	files i.IFiles,
@@ -271,6 +350,47 @@ func (scheduler *Scheduler) TryResumeTask(
 ) types.RunResponseStatus {
 	scheduler.lock.AssertLocked()
 
+	putBack := scheduler.snapshotDeactivatedTask(newTask)
+	status := scheduler.tryRestartTask(newTask, types.ExecutionTriggerResume, files, displays, goroutines, messages)
+	if status == types.RunResponseStatusSuccess {
+		scheduler.events.Emit(i.EventTaskResumed, newTask, types.Event{})
+	} else {
+		putBack()
+	}
+	return status
+}
+
+// snapshotDeactivatedTask remembers the environment, computed values and dynamic state of a deactivated task, which
+// Init rewrites when restarting it. The returned function puts the task back to the scheduler unchanged, if it cannot
+// be restarted. The task must have been extracted from the scheduler with ExtractDeactivatedTask.
+func (scheduler *Scheduler) snapshotDeactivatedTask(task *Task) (putBack func()) {
+	display := task.Display
+	env := slices.Clone(task.Env)
+	computed := task.Computed
+	dynamic := task.Dynamic
+
+	return func() {
+		task.Display = display
+		task.Env = env
+		task.Computed = computed
+		task.Dynamic = dynamic
+		task.Dynamic.IsDeactivated = true
+		close(task.Channels.DoneChannel) // the task will not be executed, so nobody else will close it
+		scheduler.tasks = append(scheduler.tasks, task)
+	}
+}
+
+// tryRestartTask schedules a deactivated task again, keeping its id.
+func (scheduler *Scheduler) tryRestartTask(
+	newTask *Task,
+	trigger types.ExecutionTrigger,
+	files i.IFiles,
+	displays i.IDisplays,
+	goroutines i.IGoroutines,
+	messages i.IMessages,
+) types.RunResponseStatus {
+	scheduler.lock.AssertLocked()
+
 	// Calculate internal properties
 	newTask.Init(newTask.Computed.Id, files.GetTaskLogFile(newTask.Computed.Id))
 
@@ -287,11 +407,39 @@ func (scheduler *Scheduler) TryResumeTask(
 	// Schedule
 	scheduler.tasks = append(scheduler.tasks, newTask)
 	goroutines.StartGoroutine(func() {
-		ExecuteTask(newTask, &scheduler.lock, files, goroutines, messages)
+		ExecuteTask(newTask, &scheduler.lock, scheduler.backendLogSinks, files, goroutines, messages, scheduler.events, trigger)
 	})
 	return types.RunResponseStatusSuccess
 }
 
+// TryMoveTask restarts a deactivated task on a different display, keeping its id and dynamic state. The task must have
+// been extracted from the scheduler with ExtractDeactivatedTask. If the task cannot be restarted, it is put back to
+// the scheduler as deactivated on its old display.
+func (scheduler *Scheduler) TryMoveTask(
+	task *Task,
+	display types.DisplaySelection,
+	files i.IFiles,
+	displays i.IDisplays,
+	goroutines i.IGoroutines,
+	messages i.IMessages,
+) types.RunResponseStatus {
+	scheduler.lock.AssertLocked()
+
+	oldDisplay := task.Display
+	putBack := scheduler.snapshotDeactivatedTask(task)
+	task.Display = display
+
+	status := scheduler.tryRestartTask(task, types.ExecutionTriggerMove, files, displays, goroutines, messages)
+	if status == types.RunResponseStatusSuccess {
+		scheduler.events.Emit(i.EventTaskMoved, task, types.Event{Reason: fmt.Sprintf("moved from %v", oldDisplay.ComputeDisplayLabel())})
+	} else {
+		putBack()
+		task.Dynamic.DeactivatedReason = fmt.Sprintf("Failed moving to %v display %v.", display.Type.String(), display.Name)
+	}
+
+	return status
+}
+
 func (scheduler *Scheduler) StopTasksByDisplay(display types.DisplaySelection) {
 	scheduler.lock.AssertLocked()
 
@@ -9,6 +9,7 @@ import (
 	"spieven/common"
 	"spieven/common/types"
 	"strconv"
+	"time"
 )
 
 // Task struct describes a command that is scheduled to be running in background. For each Task Spieven creates a
@@ -29,6 +30,9 @@ type Task struct {
 	CaptureStderr         bool
 	Display               types.DisplaySelection
 	Tags                  []string
+	LogRetention          types.LogRetention
+	LogFormat             types.TaskLogFormat
+	LogSinks              types.LogSinks
 
 	Computed struct {
 		Id          int
@@ -42,6 +46,8 @@ type Task struct {
 	Channels struct {
 		StopChannel    chan string   `json:"-"`
 		RefreshChannel chan struct{} `json:"-"`
+		DoneChannel    chan struct{} `json:"-"` // closed when ExecuteTask returns
+		LogNotifier    *LogNotifier  `json:"-"` // notified after each write to the task log
 	}
 
 	Dynamic struct {
@@ -53,11 +59,20 @@ type Task struct {
 		LastStderrFilePath     string
 		IsDeactivated          bool
 		DeactivatedReason      string
+		LastStartTime          time.Time               // start of the current or the last execution
+		LastRunDuration        time.Duration           // duration of the last finished execution
+		History                []types.ExecutionRecord // most recent executions, oldest first
+		TotalUsage             types.ResourceUsage     // resources used by all executions
+		CurrentPid             int                     // pid of the running command, 0 if it's not running
+		NextRunTime            time.Time               // end of the delay before the next execution, zero if not waiting
 	}
 
 	_ common.NoCopy
 }
 
+// ExecutionHistoryLength is the number of most recent executions kept in the history of each task.
+const ExecutionHistoryLength = 50
+
 func (task *Task) Init(id int, outFilePath string) {
 	// Set some derived values
 	task.Computed.Id = id
@@ -68,6 +83,8 @@ func (task *Task) Init(id int, outFilePath string) {
 	// Create channels used for communicating with the task
 	task.Channels.StopChannel = make(chan string, 1)
 	task.Channels.RefreshChannel = make(chan struct{})
+	task.Channels.DoneChannel = make(chan struct{})
+	task.Channels.LogNotifier = CreateLogNotifier()
 
 	// Reset some dynamic state in case we're reactivating a deactivated task
 	task.Dynamic.SubsequentFailureCount = 0
@@ -79,6 +96,12 @@ func (task *Task) Init(id int, outFilePath string) {
 }
 
 func (task *Task) ComputeHashes() (int, int) {
+	return task.ComputeHashesForDisplay(task.Display)
+}
+
+// ComputeHashesForDisplay calculates the same hashes as ComputeHashes, but as if the task was running on a different
+// display. It allows checking for conflicts before actually changing the display of a task.
+func (task *Task) ComputeHashesForDisplay(display types.DisplaySelection) (int, int) {
 	var h hash.Hash32
 	writeInt := func(val int) {
 		h.Write([]byte(strconv.Itoa(val)))
@@ -107,16 +130,16 @@ func (task *Task) ComputeHashes() (int, int) {
 	writeString(task.FriendlyName)
 	writeBool(task.CaptureStdout)
 	writeStrings(task.Tags)
-	writeInt(int(task.Display.Type))
-	writeString(task.Display.Name)
+	writeInt(int(display.Type))
+	writeString(display.Name)
 	hash1 := int(h.Sum32())
 
 	// This hash includes user-passed friendly name and display information pulled from env. It ensures
 	// that we only have one task with a given name per display.
 	h = fnv.New32a()
 	writeString(task.FriendlyName)
-	writeInt(int(task.Display.Type))
-	writeString(task.Display.Name)
+	writeInt(int(display.Type))
+	writeString(display.Name)
 	hash2 := int(h.Sum32())
 
 	return hash1, hash2
@@ -153,6 +176,23 @@ func (task *Task) ReadLastStdout() (stdout string, err error) {
 	return
 }
 
-func (task *Task) GetLogLabel() string {
-	return task.Computed.LogLabel
+func (task *Task) GetId() int {
+	return task.Computed.Id
+}
+
+func (task *Task) GetFriendlyName() string {
+	return task.FriendlyName
+}
+
+func (task *Task) GetDisplay() types.DisplaySelection {
+	return task.Display
+}
+
+// appendExecutionRecord returns a new history with the record added and the oldest records dropped over the limit.
+// The old slice is not modified, because copies of the dynamic state may still be read by other goroutines.
+func appendExecutionRecord(history []types.ExecutionRecord, record types.ExecutionRecord) []types.ExecutionRecord {
+	start := max(0, len(history)+1-ExecutionHistoryLength)
+	result := make([]types.ExecutionRecord, 0, len(history)+1-start)
+	result = append(result, history[start:]...)
+	return append(result, record)
 }
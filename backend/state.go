@@ -4,6 +4,8 @@ import (
 	"spieven/backend/display"
 	"spieven/backend/scheduler"
 	"spieven/common"
+	"spieven/common/types"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,15 +15,21 @@ type BackendState struct {
 	sync      *BackendSync
 	files     *FilePathProvider
 	messages  *BackendMessages
+	events    *BackendEvents
 	displays  *display.Displays
 	scheduler scheduler.Scheduler
 
-	handshakeValue uint64
+	connectionsServed  atomic.Uint64 // frontend connections accepted since start
+	httpRequestsServed atomic.Uint64
+
+	authToken     string             // token accepted from remote frontends, empty if remote connections are disabled
+	httpAuthToken string             // token required by the HTTP API on a TCP address
+	logRetention  types.LogRetention // defaults for tasks which do not specify their own retention
 
 	_ common.NoCopy
 }
 
-func CreateBackendState(frequentTrim bool, displayKillGracePeriod time.Duration, port string) (*BackendState, error) {
+func CreateBackendState(frequentTrim bool, displayKillGracePeriod time.Duration, port string, logRetention types.LogRetention, logSinks types.LogSinks) (*BackendState, error) {
 	sync, err := CreateBackendSync()
 	if err != nil {
 		return nil, err
@@ -37,14 +45,27 @@ func CreateBackendState(frequentTrim bool, displayKillGracePeriod time.Duration,
 		return nil, err
 	}
 
-	displays := display.CreateDisplays(messages, displayKillGracePeriod)
+	events := CreateBackendEvents(messages)
+
+	displays := display.CreateDisplays(messages, events, displayKillGracePeriod)
+
+	backendLogSinks, err := scheduler.CreateLogSinks(logSinks, messages)
+	if err != nil {
+		scheduler.CloseLogSinks(backendLogSinks)
+		return nil, err
+	}
 
 	backendState := BackendState{
 		sync:     sync,
 		files:    files,
 		messages: messages,
+		events:   events,
 		displays: displays,
+
+		logRetention: logRetention,
 	}
+	backendState.scheduler.SetBackendLogSinks(backendLogSinks)
+	backendState.scheduler.SetEvents(events)
 	backendState.StartTrimGoroutine(frequentTrim)
 	backendState.StartCleanupGorotuine()
 
@@ -71,6 +92,14 @@ func (state *BackendState) StartTrimGoroutine(frequentTrim bool) {
 
 				state.scheduler.Lock()
 				state.scheduler.Trim(state.messages, state.files)
+				logTrimJobs := state.scheduler.CollectLogTrimJobs()
+				state.scheduler.Unlock()
+
+				// Trimming logs reads directories and compresses files, so it's done without holding the lock
+				scheduler.RunLogTrimJobs(logTrimJobs, state.messages, state.files)
+
+				state.scheduler.Lock()
+				state.scheduler.ForgetSettledLogTrimJobs(logTrimJobs)
 				state.scheduler.Unlock()
 
 				state.displays.Trim()
@@ -83,6 +112,7 @@ func (state *BackendState) StartTrimGoroutine(frequentTrim bool) {
 func (state *BackendState) StartCleanupGorotuine() {
 	body := func() {
 		state.displays.Cleanup()
+		state.scheduler.CloseBackendLogSinks() // before messages, so errors of the sinks can still be logged
 		state.messages.Cleanup()
 		state.files.Cleanup()
 	}
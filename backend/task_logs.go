@@ -0,0 +1,289 @@
+package backend
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"spieven/backend/scheduler"
+	"spieven/common/packet"
+	"spieven/common/types"
+	"strings"
+)
+
+// openLogFile opens a log file for reading, transparently decompressing it if it was compressed during trimming.
+func openLogFile(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return file, nil
+	}
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{reader, file}, nil
+}
+
+// readLines calls a callback for each line of a file. It returns the number of bytes consumed, which excludes the last
+// line if it's not terminated with a newline and skipIncompleteLine is set.
+func readLines(path string, skipIncompleteLine bool, callback func(line string)) (int64, error) {
+	file, err := openLogFile(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var bytesConsumed int64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return bytesConsumed, err
+		}
+
+		isComplete := strings.HasSuffix(line, "\n")
+		if line != "" && (isComplete || !skipIncompleteLine) {
+			bytesConsumed += int64(len(line))
+			callback(strings.TrimSuffix(line, "\n"))
+		}
+
+		if err == io.EOF {
+			return bytesConsumed, nil
+		}
+	}
+}
+
+// readTaskLogRecords parses the task log, including its rotated part. It also returns the offset in the current task
+// log file up to which the records were read, so the caller can follow the file from there.
+func readTaskLogRecords(files *FilePathProvider, taskId int) ([]types.TaskLogRecord, int64, error) {
+	var records []types.TaskLogRecord
+	appendRecord := func(line string) {
+		// Empty lines are only separators for readability and lines that cannot be parsed come from an older version
+		// of the log format. Skip them both.
+		if record, err := types.ParseTaskLogRecord(line); err == nil {
+			records = append(records, record)
+		}
+	}
+
+	rotatedTaskLogFile := files.GetRotatedTaskLogFile(taskId)
+	for _, path := range []string{rotatedTaskLogFile + ".gz", rotatedTaskLogFile} {
+		if _, err := os.Stat(path); err == nil {
+			if _, err := readLines(path, false, appendRecord); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+
+	offset, err := readLines(files.GetTaskLogFile(taskId), true, appendRecord)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, 0, err
+	}
+	return records, offset, nil
+}
+
+// readExecutionOutputRecords reads stdout or stderr files of selected executions and converts their lines to records.
+// These files contain raw output without timestamps, so modification time of the file is used instead. That is not
+// precise enough for filtering by time, so requests with since or until are rejected for these sources.
+func readExecutionOutputRecords(files *FilePathProvider, taskId int, stream types.TaskLogStream, isExecutionSelected func(int) bool) ([]types.TaskLogRecord, error) {
+	executionFiles, err := files.GetExecutionLogFiles(taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	executionIds := make([]int, 0, len(executionFiles))
+	for executionId := range executionFiles {
+		if isExecutionSelected(executionId) {
+			executionIds = append(executionIds, executionId)
+		}
+	}
+	slices.Sort(executionIds)
+
+	fileInfix := "_stdout_"
+	if stream == types.TaskLogStreamStderr {
+		fileInfix = "_stderr_"
+	}
+
+	var records []types.TaskLogRecord
+	for _, executionId := range executionIds {
+		for _, path := range executionFiles[executionId] {
+			if !strings.Contains(path, fileInfix) {
+				continue
+			}
+
+			fileInfo, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = readLines(path, false, func(line string) {
+				records = append(records, types.TaskLogRecord{
+					Time:      fileInfo.ModTime(),
+					Stream:    stream,
+					Execution: executionId,
+					Line:      strings.ToValidUTF8(line, "\uFFFD"),
+				})
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return records, nil
+}
+
+// filterTaskLogRecords applies all filters from the request to the records. The grep expression must be already
+// compiled and the execution resolved.
+func filterTaskLogRecords(records []types.TaskLogRecord, request *packet.TaskLogsRequestBody, grep *regexp.Regexp) []types.TaskLogRecord {
+	result := make([]types.TaskLogRecord, 0, len(records))
+	for index := range records {
+		if request.MatchesRecord(&records[index], grep) {
+			result = append(result, records[index])
+		}
+	}
+
+	if request.Tail > 0 && len(result) > request.Tail {
+		result = result[len(result)-request.Tail:]
+	}
+	return result
+}
+
+// Maximum amount of task log data sent in a single packet while following a task.
+const followTaskLogChunkSize = 32 * 1024
+
+// taskLogFollower reads a task log as it is being written. The file may not exist yet, when the task was just started,
+// and it may be rotated by the per-task logger at any point.
+type taskLogFollower struct {
+	path   string
+	file   *os.File
+	offset int64 // applied when the file is first opened
+}
+
+func createTaskLogFollower(path string, offset int64) *taskLogFollower {
+	return &taskLogFollower{
+		path:   path,
+		offset: offset,
+	}
+}
+
+// read returns the number of bytes read into buffer. Zero means there is no new content at the moment.
+func (follower *taskLogFollower) read(buffer []byte) (int, error) {
+	if follower.file == nil {
+		file, err := os.Open(follower.path)
+		if os.IsNotExist(err) {
+			return 0, nil
+		} else if err != nil {
+			return 0, err
+		}
+		if _, err := file.Seek(follower.offset, io.SeekStart); err != nil {
+			file.Close()
+			return 0, err
+		}
+		follower.file = file
+	}
+
+	bytesRead, err := follower.file.Read(buffer)
+	if bytesRead > 0 || (err != nil && err != io.EOF) {
+		return bytesRead, err
+	}
+
+	// We read everything. If the log was rotated, continue with the new file. The old one could have been written
+	// to right before rotation, so read it again before switching.
+	openedInfo, err := follower.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	currentInfo, err := os.Stat(follower.path)
+	if err != nil || os.SameFile(openedInfo, currentInfo) {
+		return 0, nil
+	}
+	bytesRead, err = follower.file.Read(buffer)
+	if bytesRead > 0 || (err != nil && err != io.EOF) {
+		return bytesRead, err
+	}
+	follower.file.Close()
+	follower.file = nil
+	follower.offset = 0
+	return follower.read(buffer)
+}
+
+func (follower *taskLogFollower) close() {
+	if follower.file != nil {
+		follower.file.Close()
+	}
+}
+
+// followedTaskLog holds everything needed to stream the log of a single task to the frontend.
+type followedTaskLog struct {
+	taskId      int
+	taskName    string
+	notifier    *scheduler.LogNotifier
+	wakeChannel chan struct{}
+	doneChannel chan struct{}
+	reader      *taskLogFollower
+}
+
+// createFollowedTaskLog subscribes wakeChannel for changes in the task log and for the task ending. Must be called
+// with the scheduler locked. The goroutine waiting for the task to end exits after stopChannel is closed.
+func createFollowedTaskLog(task *scheduler.Task, offset int64, wakeChannel chan struct{}, stopChannel chan struct{}, goroutines *BackendSync) *followedTaskLog {
+	followed := &followedTaskLog{
+		taskId:      task.Computed.Id,
+		taskName:    task.FriendlyName,
+		notifier:    task.Channels.LogNotifier,
+		wakeChannel: wakeChannel,
+		doneChannel: task.Channels.DoneChannel,
+		reader:      createTaskLogFollower(task.Computed.OutFilePath, offset),
+	}
+	followed.notifier.Subscribe(wakeChannel)
+
+	goroutines.StartGoroutine(func() {
+		select {
+		case <-followed.doneChannel:
+			select {
+			case wakeChannel <- struct{}{}:
+			default:
+			}
+		case <-stopChannel:
+		}
+	})
+
+	return followed
+}
+
+// createFinishedTaskLog is like createFollowedTaskLog, but for a task trimmed out of memory. Such task is not executed
+// anymore, so its log is only read to the end.
+func createFinishedTaskLog(task *scheduler.Task, offset int64) *followedTaskLog {
+	doneChannel := make(chan struct{})
+	close(doneChannel)
+	return &followedTaskLog{
+		taskId:      task.Computed.Id,
+		taskName:    task.FriendlyName,
+		doneChannel: doneChannel,
+		reader:      createTaskLogFollower(task.Computed.OutFilePath, offset),
+	}
+}
+
+func (followed *followedTaskLog) isDone() bool {
+	select {
+	case <-followed.doneChannel:
+		return true
+	default:
+		return false
+	}
+}
+
+func (followed *followedTaskLog) close() {
+	if followed.notifier != nil {
+		followed.notifier.Unsubscribe(followed.wakeChannel)
+	}
+	followed.reader.close()
+}
@@ -0,0 +1,111 @@
+package backend
+
+import (
+	"slices"
+	i "spieven/backend/interfaces"
+	"spieven/backend/scheduler"
+	"spieven/common/types"
+	"strconv"
+)
+
+// resolveTaskSelector returns the task selected by a canonical types.TaskSelector, looking both in memory and among
+// trimmed tasks. It must be called with the scheduler locked.
+//
+// Tasks are collected with collectLatestTasks, so tasks on different displays are distinct and "picom" running on two
+// displays is ambiguous. In that case no task is returned, only candidates the user can choose from. If nothing
+// matches, both results are nil.
+func resolveTaskSelector(backendState *BackendState, selectorStr string) (*scheduler.Task, []types.TaskCandidate) {
+	sched := &backendState.scheduler
+
+	selector, err := types.ParseTaskSelector(selectorStr)
+	if err != nil {
+		backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Invalid task selector %q: %v", selectorStr, err)
+		return nil, nil
+	}
+
+	matches := func(task *scheduler.Task) bool {
+		return selector.Matches(task.Computed.Id, task.FriendlyName, task.Display, task.Tags)
+	}
+	includeTrimmed := !selector.HasId || sched.FindTask(selector.Id) == nil
+	tasks := collectLatestTasks(backendState, matches, includeTrimmed)
+
+	switch len(tasks) {
+	case 0:
+		return nil, nil
+	case 1:
+		return tasks[0], nil
+	default:
+		candidates := make([]types.TaskCandidate, 0, len(tasks))
+		for _, task := range tasks {
+			candidates = append(candidates, types.TaskCandidate{
+				Id:            task.Computed.Id,
+				FriendlyName:  task.FriendlyName,
+				Display:       task.Display,
+				IsDeactivated: task.Dynamic.IsDeactivated,
+			})
+		}
+		return nil, candidates
+	}
+}
+
+// collectLatestTasks returns tasks matching a function, ordered by id. It must be called with the scheduler locked.
+//
+// Like with list --unique-names, tasks which were run again only count once. The active one is returned, or the one
+// with the highest id if none is active. Contrary to --unique-names, tasks with the same name on different displays
+// are distinct.
+func collectLatestTasks(backendState *BackendState, matches func(*scheduler.Task) bool, includeTrimmed bool) []*scheduler.Task {
+	sched := &backendState.scheduler
+
+	tasks := sched.GetTasks()
+	if includeTrimmed {
+		tasks = append(slices.Clone(tasks), sched.ReadTrimmedTasks(backendState.messages, backendState.files)...)
+	}
+	return selectLatestTasks(tasks, matches)
+}
+
+// selectLatestTasks picks the latest of matching tasks for each name and display, see collectLatestTasks.
+func selectLatestTasks(tasks []*scheduler.Task, matches func(*scheduler.Task) bool) []*scheduler.Task {
+	type nameDisplay struct {
+		name    string
+		display types.DisplaySelection
+	}
+	latestTasks := make(map[nameDisplay]*scheduler.Task)
+	for _, task := range tasks {
+		if !matches(task) {
+			continue
+		}
+		key := nameDisplay{task.FriendlyName, task.Display}
+		if latest, found := latestTasks[key]; !found || isPreferredTask(task, latest) {
+			latestTasks[key] = task
+		}
+	}
+
+	result := make([]*scheduler.Task, 0, len(latestTasks))
+	for _, task := range latestTasks {
+		result = append(result, task)
+	}
+	slices.SortFunc(result, func(a, b *scheduler.Task) int { return a.Computed.Id - b.Computed.Id })
+	return result
+}
+
+// isPreferredTask tells whether a task should be selected instead of another one with the same name and display. There
+// can only be one active task with a name on a display.
+func isPreferredTask(task *scheduler.Task, other *scheduler.Task) bool {
+	if task.Dynamic.IsDeactivated != other.Dynamic.IsDeactivated {
+		return !task.Dynamic.IsDeactivated
+	}
+	return task.Computed.Id > other.Computed.Id
+}
+
+// requestedTaskSelector returns the selector of a request which can select a task either with a selector or, for
+// older frontends, with an id.
+func requestedTaskSelector(selector string, taskId int) string {
+	if selector != "" {
+		return selector
+	}
+	return strconv.Itoa(taskId)
+}
+
+func logAmbiguousTaskSelector(backendState *BackendState, selector string, candidates []types.TaskCandidate) {
+	backendState.messages.AddF(i.BackendMessageError, i.MessageKindRequest, nil, "Task selector %v is ambiguous, it matches %v tasks", selector, len(candidates))
+}
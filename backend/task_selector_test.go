@@ -0,0 +1,77 @@
+package backend
+
+import (
+	"slices"
+	"spieven/backend/scheduler"
+	"spieven/common/types"
+	"testing"
+)
+
+func TestSelectLatestTasks(t *testing.T) {
+	xorg0 := types.DisplaySelection{Type: types.DisplaySelectionTypeXorg, Name: ":0"}
+	xorg1 := types.DisplaySelection{Type: types.DisplaySelectionTypeXorg, Name: ":1"}
+	headless := types.DisplaySelection{Type: types.DisplaySelectionTypeHeadless}
+
+	newTask := func(id int, friendlyName string, display types.DisplaySelection, isDeactivated bool, tags ...string) *scheduler.Task {
+		task := &scheduler.Task{FriendlyName: friendlyName, Display: display, Tags: tags}
+		task.Computed.Id = id
+		task.Dynamic.IsDeactivated = isDeactivated
+		return task
+	}
+	tasks := []*scheduler.Task{
+		newTask(0, "picom", xorg0, true),
+		newTask(1, "picom", xorg0, false),
+		newTask(2, "picom", xorg0, true),
+		newTask(3, "picom", xorg1, true),
+		newTask(4, "picom", xorg1, true),
+		newTask(5, "bar", xorg0, true, "session"),
+		newTask(6, "bar", xorg0, true, "session"),
+		newTask(7, "dunst", xorg0, false, "session"),
+		newTask(8, "123", headless, true),
+		newTask(9, "user@host", headless, false),
+		newTask(10, "tag:session", headless, false),
+	}
+
+	tests := []struct {
+		selector    string
+		expectedIds []int
+	}{
+		// Active task is preferred over later deactivated runs, otherwise the latest run is taken
+		{selector: "picom@x:0", expectedIds: []int{1}},
+		{selector: "picom@x:1", expectedIds: []int{4}},
+		// Same name on different displays is ambiguous
+		{selector: "picom", expectedIds: []int{1, 4}},
+		{selector: "bar", expectedIds: []int{6}},
+		// Tags select the latest run of each task having the tag, so they are ambiguous with multiple tasks
+		{selector: "tag:session", expectedIds: []int{6, 7}},
+		// Ids select older runs as well
+		{selector: "0", expectedIds: []int{0}},
+		{selector: "8", expectedIds: []int{8}},
+		{selector: "123", expectedIds: nil},
+		{selector: "name:123", expectedIds: []int{8}},
+		{selector: "name:user@host", expectedIds: []int{9}},
+		{selector: "name:tag:session", expectedIds: []int{10}},
+		{selector: "picom@x:2", expectedIds: nil},
+		{selector: "missing", expectedIds: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			selector, err := types.ParseTaskSelector(test.selector)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			matches := func(task *scheduler.Task) bool {
+				return selector.Matches(task.Computed.Id, task.FriendlyName, task.Display, task.Tags)
+			}
+
+			var ids []int
+			for _, task := range selectLatestTasks(tasks, matches) {
+				ids = append(ids, task.Computed.Id)
+			}
+			if !slices.Equal(ids, test.expectedIds) {
+				t.Fatalf("expected tasks %v, got %v", test.expectedIds, ids)
+			}
+		})
+	}
+}
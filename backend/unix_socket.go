@@ -0,0 +1,119 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"syscall"
+)
+
+// listenUnixSocket creates a unix socket for frontend connections. Only the owner can connect to it, unless
+// allowedGroupId is non-negative. In that case members of the group can connect as well. The directory of the socket
+// is created for the group, if it does not exist. An existing directory is not changed, so it must already be
+// accessible to the group.
+func listenUnixSocket(socketPath string, allowedGroupId int) (net.Listener, error) {
+	directoryMode := os.FileMode(0700)
+	socketMode := os.FileMode(0600)
+	if allowedGroupId >= 0 {
+		directoryMode = 0710
+		socketMode = 0660
+	}
+
+	directory := filepath.Dir(socketPath)
+	_, err := os.Stat(directory)
+	isNewDirectory := errors.Is(err, os.ErrNotExist)
+	if err := os.MkdirAll(directory, directoryMode); err != nil {
+		return nil, err
+	}
+
+	// A socket file can be left over after a crash. Remove it, but only if no backend is listening on it.
+	if _, err := os.Stat(socketPath); err == nil {
+		if connection, err := net.Dial("unix", socketPath); err == nil {
+			connection.Close()
+			return nil, fmt.Errorf("another backend is already listening on %v", socketPath)
+		}
+		if err := os.Remove(socketPath); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chmod(socketPath, socketMode)
+	if err == nil && allowedGroupId >= 0 {
+		if isNewDirectory {
+			err = os.Chown(directory, -1, allowedGroupId)
+			if err == nil {
+				err = os.Chmod(directory, directoryMode)
+			}
+		}
+		if err == nil {
+			err = os.Chown(socketPath, -1, allowedGroupId)
+		}
+		if err != nil {
+			err = fmt.Errorf("cannot share the socket with the group, the owner must be its member: %w", err)
+		}
+	}
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// checkPeerCredentials verifies the process on the other side of a unix socket belongs to the same user as the backend
+// or to the allowed group. Group membership is checked both for the primary and supplementary groups.
+func checkPeerCredentials(connection *net.UnixConn, allowedGroupId int) error {
+	rawConnection, err := connection.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var credentials *syscall.Ucred
+	var credentialsErr error
+	err = rawConnection.Control(func(fd uintptr) {
+		credentials, credentialsErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil {
+		return err
+	}
+	if credentialsErr != nil {
+		return credentialsErr
+	}
+
+	if int(credentials.Uid) == os.Getuid() {
+		return nil
+	}
+	if allowedGroupId >= 0 {
+		if int(credentials.Gid) == allowedGroupId {
+			return nil
+		}
+		if peerUser, err := user.LookupId(strconv.Itoa(int(credentials.Uid))); err == nil {
+			if groupIds, err := peerUser.GroupIds(); err == nil && slices.Contains(groupIds, strconv.Itoa(allowedGroupId)) {
+				return nil
+			}
+		}
+	}
+	return errors.New("peer is not allowed to connect")
+}
+
+// lookupGroupId converts a group name or a numeric id to a group id.
+func lookupGroupId(nameOrId string) (int, error) {
+	group, err := user.LookupGroup(nameOrId)
+	if err != nil {
+		group, err = user.LookupGroupId(nameOrId)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid group %v", nameOrId)
+	}
+	return strconv.Atoi(group.Gid)
+}
@@ -0,0 +1,159 @@
+// Package client controls a Spieven backend from Go programs. It speaks the same packet protocol as the spieven
+// command line, which is built on top of it.
+//
+// Requests honor deadlines and cancellation of their context. Streaming requests, such as following logs or events,
+// run on a dedicated connection until the context is done or the handler returns an error, so the Client stays usable
+// in the meantime.
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"spieven/common/packet"
+	"sync"
+	"time"
+)
+
+type Client struct {
+	options dialOptions
+
+	lock      sync.Mutex
+	conn      net.Conn // nil after an interrupted request, the next one reconnects
+	handshake packet.HandshakeResponseBody
+	closed    bool
+}
+
+// Dial connects to the backend. The context only limits connecting, not the lifetime of the Client.
+func Dial(ctx context.Context, opts ...Option) (*Client, error) {
+	client := &Client{}
+	for _, opt := range opts {
+		opt(&client.options)
+	}
+
+	conn, handshake, err := connect(ctx, &client.options)
+	if err != nil {
+		return nil, err
+	}
+	client.conn = conn
+	client.handshake = *handshake
+	return client, nil
+}
+
+func (client *Client) Close() error {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	client.closed = true
+	if client.conn == nil {
+		return nil
+	}
+	err := client.conn.Close()
+	client.conn = nil
+	return err
+}
+
+// Backend returns the handshake response of the backend, including its version and capabilities.
+func (client *Client) Backend() packet.HandshakeResponseBody {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	return client.handshake
+}
+
+// roundTrip sends a request and receives exactly one response on the shared connection.
+func (client *Client) roundTrip(ctx context.Context, request packet.Packet) (packet.Packet, error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if client.closed {
+		return packet.Packet{}, ErrClosed
+	}
+	if client.conn == nil {
+		conn, handshake, err := connect(ctx, &client.options)
+		if err != nil {
+			return packet.Packet{}, err
+		}
+		client.conn = conn
+		client.handshake = *handshake
+	}
+
+	var response packet.Packet
+	err := runWithContext(ctx, client.conn, client.options.timeout, func() (err error) {
+		if err = packet.SendPacket(client.conn, request); err != nil {
+			return
+		}
+		response, err = packet.ReceivePacket(client.conn)
+		return
+	})
+	if err != nil {
+		// The connection may be left in the middle of a packet
+		client.conn.Close()
+		client.conn = nil
+	}
+	return response, err
+}
+
+// stream sends a request on a new connection and passes all responses to handleResponse until it returns false or an
+// error, or the context is done.
+func (client *Client) stream(ctx context.Context, request packet.Packet, handleResponse func(response packet.Packet) (bool, error)) error {
+	client.lock.Lock()
+	closed := client.closed
+	client.lock.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	conn, _, err := connect(ctx, &client.options)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return runWithContext(ctx, conn, 0, func() error {
+		if err := packet.SendPacket(conn, request); err != nil {
+			return err
+		}
+		for {
+			response, err := packet.ReceivePacket(conn)
+			if err != nil {
+				return err
+			}
+			more, err := handleResponse(response)
+			if err != nil || !more {
+				return err
+			}
+		}
+	})
+}
+
+// runWithContext applies the deadline of the context to the connection and interrupts pending reads and writes when
+// the context is cancelled. If the context has no deadline, the timeout is used instead, unless it's 0.
+func runWithContext(ctx context.Context, conn net.Conn, timeout time.Duration, call func() error) error {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	defer conn.SetDeadline(time.Time{})
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	err := call()
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		// The connection deadline may pass slightly before the context notices
+		return context.DeadlineExceeded
+	}
+	return err
+}
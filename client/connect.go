@@ -0,0 +1,236 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"spieven/common"
+	"spieven/common/packet"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const tlsDialTimeout = time.Second * 10
+
+// connect connects to a backend running on the same machine through its unix socket, or through another unix socket
+// given as unix://PATH. TCP is only used if an address is specified, e.g. localhost for a backend started with --tcp. It is secured with TLS, if a client config is given or
+// one exists in the default auth directory. There is no fallback to TCP without an address, because a local TCP port
+// can be taken by any process, which would then receive requests including the environment of the caller.
+func connect(ctx context.Context, options *dialOptions) (net.Conn, *packet.HandshakeResponseBody, error) {
+	serverPort, err := options.resolvePort()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid default port: %w", err)
+	}
+
+	serverAddress := options.address
+	unixSocketPath, useUnixSocket := strings.CutPrefix(serverAddress, "unix://")
+	if serverAddress == "" {
+		useUnixSocket = true
+		unixSocketPath, err = common.GetUnixSocketPath(strconv.Itoa(serverPort))
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot use the unix socket: %w", err)
+		}
+	}
+
+	var hostWithPort string
+	if !useUnixSocket {
+		hostWithPort = net.JoinHostPort(serverAddress, strconv.Itoa(serverPort))
+	}
+
+	// Load TLS credentials for remote connections
+	var tlsConfig *tls.Config
+	var token string
+	if !useUnixSocket {
+		tlsConfig, token, err = loadClientTlsConfig(options.clientConfigPath, serverAddress)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	dial := func() (net.Conn, error) {
+		if tlsConfig != nil {
+			dialer := &tls.Dialer{
+				NetDialer: &net.Dialer{Timeout: tlsDialTimeout},
+				Config:    tlsConfig,
+			}
+			return dialer.DialContext(ctx, "tcp4", hostWithPort)
+		}
+		var dialer net.Dialer
+		if useUnixSocket {
+			return dialer.DialContext(ctx, "unix", unixSocketPath)
+		}
+		return dialer.DialContext(ctx, "tcp4", hostWithPort)
+	}
+
+	connection, err := dial()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if options.autorunBinary == "" || options.isCustomAddress() {
+			return nil, nil, fmt.Errorf("%w (didn't try to start it)", ErrBackendUnreachable)
+		}
+
+		cmd := exec.Command(options.autorunBinary, "serve")
+		cmd.Stdin = nil
+		cmd.Stdout = nil
+		cmd.Stderr = nil
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Setsid: true,
+		}
+		err := cmd.Start()
+		if err != nil {
+			return nil, nil, errors.New("cannot start Spieven backend")
+		}
+
+		connection, err = common.TryCallWithTimeouts(dial, time.Millisecond*1300, 13)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w even after starting it in background", ErrBackendUnreachable)
+		}
+	}
+
+	// Authentication and handshake should be instant, don't let a stuck backend block forever
+	var handshake *packet.HandshakeResponseBody
+	err = runWithContext(ctx, connection, options.timeout, func() (err error) {
+		if tlsConfig != nil {
+			if err := authenticateToBackend(connection, token); err != nil {
+				return err
+			}
+		}
+		handshake, err = handshakeWithBackend(connection)
+		return
+	})
+	if err != nil {
+		connection.Close()
+		return nil, nil, err
+	}
+
+	return connection, handshake, nil
+}
+
+// loadClientTlsConfig reads the client config file. It returns nil config without an error, if the file does not
+// exist, meaning plain TCP should be used.
+func loadClientTlsConfig(clientConfigPath string, serverAddress string) (*tls.Config, string, error) {
+	if clientConfigPath == "" {
+		authDir, err := common.GetDefaultAuthDir()
+		if err != nil {
+			return nil, "", nil
+		}
+		clientConfigPath = filepath.Join(authDir, common.AuthClientConfig)
+		if !common.FileExists(clientConfigPath) {
+			return nil, "", nil
+		}
+	}
+
+	content, err := os.ReadFile(clientConfigPath)
+	if err != nil {
+		return nil, "", err
+	}
+	var clientConfig common.ClientConfig
+	if err := json.Unmarshal(content, &clientConfig); err != nil {
+		return nil, "", fmt.Errorf("invalid client config %v: %w", clientConfigPath, err)
+	}
+
+	configDir := filepath.Dir(clientConfigPath)
+	resolvePath := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(configDir, path)
+	}
+
+	caCert, err := os.ReadFile(resolvePath(clientConfig.CaCertFile))
+	if err != nil {
+		return nil, "", err
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caCert) {
+		return nil, "", errors.New("invalid CA certificate in client config")
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:    caPool,
+		ServerName: serverAddress,
+		MinVersion: tls.VersionTLS13,
+	}
+	if clientConfig.ClientCertFile != "" {
+		clientCert, err := tls.LoadX509KeyPair(resolvePath(clientConfig.ClientCertFile), resolvePath(clientConfig.ClientKeyFile))
+		if err != nil {
+			return nil, "", err
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	return tlsConfig, clientConfig.Token, nil
+}
+
+func authenticateToBackend(connection net.Conn, token string) error {
+	requestPacket, err := packet.EncodeAuthenticatePacket(packet.AuthenticateRequestBody{Token: token})
+	if err != nil {
+		return err
+	}
+
+	err = packet.SendPacket(connection, requestPacket)
+	if err != nil {
+		return err
+	}
+
+	responsePacket, err := packet.ReceivePacket(connection)
+	if err != nil {
+		return err
+	}
+
+	response, err := packet.DecodeAuthenticateResponsePacket(responsePacket)
+	if err != nil {
+		return err
+	}
+
+	if !response.Success {
+		return ErrAuthenticationFailed
+	}
+	return nil
+}
+
+// handshakeWithBackend exchanges protocol versions with the backend. Backends with a different major version cannot
+// be used, typically after upgrading Spieven while the old backend is still running.
+func handshakeWithBackend(connection net.Conn) (*packet.HandshakeResponseBody, error) {
+	request := packet.HandshakeRequestBody{
+		ProtocolMajor: packet.ProtocolVersionMajor,
+		ProtocolMinor: packet.ProtocolVersionMinor,
+		Capabilities:  packet.SupportedCapabilities,
+		ClientVersion: common.Version,
+	}
+	requestPacket, err := packet.EncodeHandshakePacket(request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = packet.SendPacket(connection, requestPacket)
+	if err != nil {
+		return nil, err
+	}
+
+	responsePacket, err := packet.ReceivePacket(connection)
+	if err != nil {
+		return nil, fmt.Errorf("backend did not respond to handshake, it may be too old. Restart it to use the new version: %w", err)
+	}
+
+	response, err := packet.DecodeHandshakeResponsePacket(responsePacket)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Status != packet.HandshakeResponseStatusAccepted {
+		return nil, &IncompatibleBackendError{Handshake: response}
+	}
+	return &response, nil
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"spieven/common/packet"
+	"spieven/common/types"
+)
+
+var (
+	ErrBackendUnreachable   = errors.New("cannot connect to Spieven backend")
+	ErrAuthenticationFailed = errors.New("backend rejected the credentials")
+	ErrClosed               = errors.New("client is closed")
+
+	// Returned for the RunResponseStatus values, wrapped in a RunError
+	ErrAlreadyRunning            = errors.New("an identical task is already running")
+	ErrNameDisplayAlreadyRunning = errors.New("a task with this name is already running on the display")
+	ErrInvalidDisplay            = errors.New("invalid display")
+	ErrTaskNotFound              = errors.New("task not found")
+	ErrTaskNotDeactivated        = errors.New("task is already active")
+	ErrUnknown                   = errors.New("unknown backend error")
+
+	ErrAlreadyStopped = errors.New("task is already stopped")
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrReadFailed     = errors.New("failed reading logs")
+	ErrNotSupported   = errors.New("backend does not support the request, restart it to use the new version")
+	ErrEmptyFilter    = errors.New("filter selects all tasks, use StopAll or ResumeAll to select all of them")
+
+	// Returned for task selectors, see types.TaskSelector
+	ErrInvalidSelector = errors.New("invalid task selector")
+	ErrAmbiguousTask   = errors.New("task selector matches multiple tasks")
+	ErrNoTaskSelectors = errors.New("backend only supports selecting tasks by ID, restart it to use names and tags")
+)
+
+// RunError is returned by Run, Resume and Move, when the backend did not start the task. It matches one of the ErrX
+// values above with errors.Is.
+type RunError struct {
+	Status types.RunResponseStatus
+	Task   string // requested task selector, empty for Run
+}
+
+func (err *RunError) Error() string {
+	if err.Task != "" {
+		return fmt.Sprintf("task %v: %v", err.Task, err.Unwrap())
+	}
+	return err.Unwrap().Error()
+}
+
+func (err *RunError) Unwrap() error {
+	switch err.Status {
+	case types.RunResponseStatusAlreadyRunning:
+		return ErrAlreadyRunning
+	case types.RunResponseStatusNameDisplayAlreadyRunning:
+		return ErrNameDisplayAlreadyRunning
+	case types.RunResponseStatusInvalidDisplay:
+		return ErrInvalidDisplay
+	case types.RunResponseStatusTaskNotFound:
+		return ErrTaskNotFound
+	case types.RunResponseStatusTaskNotDeactivated:
+		return ErrTaskNotDeactivated
+	case types.RunResponseStatusEmptyFilter:
+		return ErrEmptyFilter
+	default:
+		return ErrUnknown
+	}
+}
+
+func checkRunStatus(response *packet.RunResponseBody, task string) error {
+	switch response.Status {
+	case types.RunResponseStatusSuccess:
+		return nil
+	case types.RunResponseStatusAmbiguousTask:
+		return &AmbiguousTaskError{Selector: task, Candidates: response.Candidates}
+	default:
+		return &RunError{Status: response.Status, Task: task}
+	}
+}
+
+// AmbiguousTaskError is returned, when a task selector matches multiple tasks. It matches ErrAmbiguousTask with
+// errors.Is.
+type AmbiguousTaskError struct {
+	Selector   string
+	Candidates []types.TaskCandidate
+}
+
+func (err *AmbiguousTaskError) Error() string {
+	return types.FormatTaskCandidates(err.Selector, err.Candidates)
+}
+
+func (err *AmbiguousTaskError) Unwrap() error {
+	return ErrAmbiguousTask
+}
+
+// IncompatibleBackendError is returned by Dial, when the backend speaks a different major version of the protocol.
+type IncompatibleBackendError struct {
+	Handshake packet.HandshakeResponseBody
+}
+
+func (err *IncompatibleBackendError) Error() string {
+	return fmt.Sprintf("backend %v uses protocol version %v.%v, which is incompatible with version %v.%v of this client. Restart the backend to use the new version",
+		err.Handshake.BackendVersion, err.Handshake.ProtocolMajor, err.Handshake.ProtocolMinor, packet.ProtocolVersionMajor, packet.ProtocolVersionMinor)
+}
@@ -0,0 +1,233 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"spieven/common/packet"
+	"spieven/common/types"
+	"strconv"
+	"strings"
+)
+
+// Messages returns messages of the backend log. Follow in the request is ignored, use FollowMessages instead.
+func (client *Client) Messages(ctx context.Context, request packet.LogRequestBody) (packet.LogResponseBody, error) {
+	request.Follow = false
+	requestPacket, err := packet.EncodeLogPacket(request)
+	if err != nil {
+		return nil, err
+	}
+	responsePacket, err := client.roundTrip(ctx, requestPacket)
+	if err != nil {
+		return nil, err
+	}
+	return packet.DecodeLogResponsePacket(responsePacket)
+}
+
+// FollowMessages passes existing and new messages of the backend log to handleMessage until the context is done.
+func (client *Client) FollowMessages(ctx context.Context, request packet.LogRequestBody, handleMessage func(message types.BackendMessage) error) error {
+	request.Follow = true
+	requestPacket, err := packet.EncodeLogPacket(request)
+	if err != nil {
+		return err
+	}
+	return client.stream(ctx, requestPacket, func(responsePacket packet.Packet) (bool, error) {
+		response, err := packet.DecodeLogResponsePacket(responsePacket)
+		if err != nil {
+			return false, err
+		}
+		for _, message := range response {
+			if err := handleMessage(message); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+// Events passes events matching the filter to handleEvent until the context is done.
+func (client *Client) Events(ctx context.Context, filter types.EventFilter, handleEvent func(event types.Event) error) error {
+	requestPacket, err := packet.EncodeEventsPacket(packet.EventsRequestBody{Filter: filter})
+	if err != nil {
+		return err
+	}
+	return client.stream(ctx, requestPacket, func(responsePacket packet.Packet) (bool, error) {
+		response, err := packet.DecodeEventsResponsePacket(responsePacket)
+		if err != nil {
+			return false, err
+		}
+		for _, event := range response {
+			if err := handleEvent(event); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+// Logs reads the task log or captured stdout/stderr of a task. Task in the request is a selector, see
+// types.TaskSelector.
+func (client *Client) Logs(ctx context.Context, request packet.TaskLogsRequestBody) (*packet.TaskLogsResponseBody, error) {
+	selector, _, err := client.selectTask(request.Task)
+	if err != nil {
+		return nil, err
+	}
+	request.Task = selector
+	requestPacket, err := packet.EncodeTaskLogsPacket(request)
+	if err != nil {
+		return nil, err
+	}
+	responsePacket, err := client.roundTrip(ctx, requestPacket)
+	if err != nil {
+		return nil, err
+	}
+	response, err := packet.DecodeTaskLogsResponsePacket(responsePacket)
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.Status {
+	case types.TaskLogsResponseStatusSuccess:
+		return &response, nil
+	case types.TaskLogsResponseStatusTaskNotFound:
+		return nil, ErrTaskNotFound
+	case types.TaskLogsResponseStatusInvalidRequest:
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, response.Error)
+	case types.TaskLogsResponseStatusReadError:
+		return nil, fmt.Errorf("%w: %v", ErrReadFailed, response.Error)
+	case types.TaskLogsResponseStatusAmbiguousTask:
+		return nil, &AmbiguousTaskError{Selector: selector, Candidates: response.Candidates}
+	default:
+		return nil, ErrUnknown
+	}
+}
+
+// FollowLogs passes records selected by the request to handleRecord, like Logs. If the task is active, it then keeps
+// passing new matching records until the task is deactivated or the context is done.
+func (client *Client) FollowLogs(ctx context.Context, request packet.TaskLogsRequestBody, handleRecord func(record *types.TaskLogRecord) error) error {
+	response, err := client.Logs(ctx, request)
+	if err != nil {
+		return err
+	}
+	for i := range response.Records {
+		if err := handleRecord(&response.Records[i]); err != nil {
+			return err
+		}
+	}
+	if !response.IsActive {
+		return nil
+	}
+
+	// New lines are read from the task log. It also contains output of the task, so we can follow stdout/stderr with it
+	// as well. The filters have to be applied on our side, with the same execution the backend selected.
+	var grep *regexp.Regexp
+	if request.Grep != "" {
+		grep = regexp.MustCompile(request.Grep) // already validated by the backend
+	}
+	request.Execution = response.Execution
+	handleLine := func(line string) error {
+		record, err := types.ParseTaskLogRecord(strings.TrimSuffix(line, "\n"))
+		if err != nil || !request.MatchesRecord(&record, grep) {
+			return nil
+		}
+		return handleRecord(&record)
+	}
+	return client.Follow(ctx, strconv.Itoa(response.TaskId), response.LogFileOffset, handleLine)
+}
+
+// Follow passes lines of the task log of a task given by a selector, starting at a given offset, to handleLine until
+// the task is deactivated or the context is done. Lines are passed along with their trailing newline.
+func (client *Client) Follow(ctx context.Context, task string, offset int64, handleLine func(line string) error) error {
+	selector, taskId, err := client.selectTask(task)
+	if err != nil {
+		return err
+	}
+	request := packet.FollowTaskLogRequestBody{
+		Task:   selector,
+		TaskId: taskId,
+		Offset: offset,
+	}
+	handleTaskLine := func(line TaskLine) error { return handleLine(line.Line) }
+	return client.followTaskLog(ctx, request, handleTaskLine)
+}
+
+// TaskLine is a line of a task log passed by FollowTasks.
+type TaskLine struct {
+	TaskId   int
+	TaskName string
+	Line     string
+}
+
+// FollowTasks passes lines of logs of all active tasks matching the filter to handleLine, including tasks started
+// later. It runs until the context is done.
+func (client *Client) FollowTasks(ctx context.Context, filter types.TaskFilter, handleLine func(line TaskLine) error) error {
+	if err := client.checkFilter(&filter); err != nil {
+		return err
+	}
+	request := packet.FollowTaskLogRequestBody{Filter: &filter}
+	return client.followTaskLog(ctx, request, handleLine)
+}
+
+func (client *Client) followTaskLog(ctx context.Context, request packet.FollowTaskLogRequestBody, handleLine func(line TaskLine) error) error {
+	requestPacket, err := packet.EncodeFollowTaskLogPacket(request)
+	if err != nil {
+		return err
+	}
+
+	// Chunks are not aligned to lines, so keep the unterminated remainder of each task until the rest of it arrives.
+	partialLines := make(map[int][]byte)
+	taskNames := make(map[int]string)
+	return client.stream(ctx, requestPacket, func(responsePacket packet.Packet) (bool, error) {
+		response, err := packet.DecodeFollowTaskLogResponsePacket(responsePacket)
+		if err != nil {
+			return false, err
+		}
+
+		passLine := func(line []byte) error {
+			return handleLine(TaskLine{
+				TaskId:   response.TaskId,
+				TaskName: taskNames[response.TaskId],
+				Line:     string(line),
+			})
+		}
+
+		switch response.Status {
+		case packet.FollowTaskLogResponseStatusStarted:
+			taskNames[response.TaskId] = response.TaskName
+		case packet.FollowTaskLogResponseStatusChunk:
+			partialLine := append(partialLines[response.TaskId], response.Data...)
+			for {
+				lineLength := bytes.IndexByte(partialLine, '\n')
+				if lineLength < 0 {
+					break
+				}
+				if err := passLine(partialLine[:lineLength+1]); err != nil {
+					return false, err
+				}
+				partialLine = partialLine[lineLength+1:]
+			}
+			partialLines[response.TaskId] = partialLine
+		case packet.FollowTaskLogResponseStatusDeactivated:
+			if partialLine := partialLines[response.TaskId]; len(partialLine) > 0 {
+				if err := passLine(partialLine); err != nil {
+					return false, err
+				}
+			}
+			delete(partialLines, response.TaskId)
+			delete(taskNames, response.TaskId)
+			if request.Filter == nil {
+				return false, nil
+			}
+		case packet.FollowTaskLogResponseStatusInvalidTask:
+			return false, ErrTaskNotFound
+		case packet.FollowTaskLogResponseStatusAmbiguousTask:
+			return false, &AmbiguousTaskError{Selector: request.Task, Candidates: response.Candidates}
+		case packet.FollowTaskLogResponseStatusReadError:
+			return false, fmt.Errorf("%w: %v", ErrReadFailed, response.Error)
+		default:
+			return false, ErrUnknown
+		}
+		return true, nil
+	})
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"spieven/common/buildopts"
+	"strconv"
+	"time"
+)
+
+type dialOptions struct {
+	address          string
+	port             int
+	clientConfigPath string
+	autorunBinary    string
+	timeout          time.Duration
+}
+
+// Option customizes how Dial connects to the backend.
+type Option func(options *dialOptions)
+
+// WithAddress connects to a backend at a given address over TCP, or through a unix socket given as unix://PATH, e.g.
+// one created with spieven serve --socket. By default a backend on the same machine is used through its unix socket.
+func WithAddress(address string) Option {
+	return func(options *dialOptions) {
+		options.address = address
+	}
+}
+
+// WithPort connects to a backend listening on a given port. 0 means the build-specific default.
+func WithPort(port int) Option {
+	return func(options *dialOptions) {
+		options.port = port
+	}
+}
+
+// WithClientConfig authenticates to a remote backend with a given client config. By default client.json in the auth
+// directory is used, if it exists.
+func WithClientConfig(path string) Option {
+	return func(options *dialOptions) {
+		options.clientConfigPath = path
+	}
+}
+
+// WithAutorun starts the backend by running "BINARY serve" in the background, if it cannot be reached. It only applies
+// to the default address and port.
+func WithAutorun(spievenBinary string) Option {
+	return func(options *dialOptions) {
+		options.autorunBinary = spievenBinary
+	}
+}
+
+// WithTimeout limits the time of each request, which is not streaming and whose context has no deadline of its own.
+func WithTimeout(timeout time.Duration) Option {
+	return func(options *dialOptions) {
+		options.timeout = timeout
+	}
+}
+
+func (options *dialOptions) isCustomAddress() bool {
+	return options.address != "" || options.port != 0
+}
+
+func (options *dialOptions) resolvePort() (int, error) {
+	if options.port != 0 {
+		return options.port, nil
+	}
+	return strconv.Atoi(buildopts.DefaultPort)
+}
@@ -0,0 +1,363 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"spieven/common/packet"
+	"spieven/common/types"
+)
+
+// Run schedules a new task. Empty Cwd defaults to the current working directory and empty FriendlyName to the
+// executable name. Env is passed as is, fill it with os.Environ() to inherit the environment.
+func (client *Client) Run(ctx context.Context, request packet.RunRequestBody) (*packet.RunResponseBody, error) {
+	if len(request.Cmdline) == 0 {
+		return nil, fmt.Errorf("%w: empty command line", ErrInvalidRequest)
+	}
+	if request.Cwd == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			var found bool
+			cwd, found = os.LookupEnv("HOME")
+			if !found {
+				return nil, fmt.Errorf("could not determine working directory for the task")
+			}
+		}
+		request.Cwd = cwd
+	}
+	if request.FriendlyName == "" {
+		request.FriendlyName = request.Cmdline[0]
+	}
+	if err := ValidateRunRequestBody(&request); err != nil {
+		return nil, err
+	}
+
+	requestPacket, err := packet.EncodeRunPacket(request)
+	if err != nil {
+		return nil, err
+	}
+	responsePacket, err := client.roundTrip(ctx, requestPacket)
+	if err != nil {
+		return nil, err
+	}
+	response, err := packet.DecodeRunResponsePacket(responsePacket)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRunStatus((*packet.RunResponseBody)(&response), ""); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) List(ctx context.Context, request packet.ListRequestBody) (packet.ListResponseBody, error) {
+	if err := client.checkFilter(&request.Filter); err != nil {
+		return nil, err
+	}
+	requestPacket, err := packet.EncodeListPacket(request)
+	if err != nil {
+		return nil, err
+	}
+	responsePacket, err := client.roundTrip(ctx, requestPacket)
+	if err != nil {
+		return nil, err
+	}
+	return packet.DecodeListResponsePacket(responsePacket)
+}
+
+// Stop deactivates a task given by a selector, see types.TaskSelector. It returns ErrTaskNotFound or ErrAlreadyStopped,
+// if the task cannot be stopped, or an AmbiguousTaskError.
+func (client *Client) Stop(ctx context.Context, task string) error {
+	selector, taskId, err := client.selectTask(task)
+	if err != nil {
+		return err
+	}
+	requestPacket, err := packet.EncodeStopPacket(packet.StopRequestBody{Task: selector, TaskId: taskId})
+	if err != nil {
+		return err
+	}
+	responsePacket, err := client.roundTrip(ctx, requestPacket)
+	if err != nil {
+		return err
+	}
+	response, err := packet.DecodeStopResponsePacket(responsePacket)
+	if err != nil {
+		return err
+	}
+
+	switch response.Status {
+	case types.StopResponseStatusSuccess:
+		return nil
+	case types.StopResponseStatusTaskNotFound:
+		return ErrTaskNotFound
+	case types.StopResponseStatusAlreadyStopped:
+		return ErrAlreadyStopped
+	case types.StopResponseStatusAmbiguousTask:
+		return &AmbiguousTaskError{Selector: selector, Candidates: response.Candidates}
+	default:
+		return ErrUnknown
+	}
+}
+
+// Resume runs a deactivated task given by a selector again, keeping its ID.
+func (client *Client) Resume(ctx context.Context, task string) (*packet.ResumeResponseBody, error) {
+	selector, taskId, err := client.selectTask(task)
+	if err != nil {
+		return nil, err
+	}
+	requestPacket, err := packet.EncodeResumePacket(packet.ResumeRequestBody{Task: selector, TaskId: taskId})
+	if err != nil {
+		return nil, err
+	}
+	responsePacket, err := client.roundTrip(ctx, requestPacket)
+	if err != nil {
+		return nil, err
+	}
+	response, err := packet.DecodeResumeResponsePacket(responsePacket)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRunStatus(&response.RunResponseBody, selector); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// StopMany deactivates all active tasks matching the filter. Status selection of the filter decides, whether
+// deactivated tasks are reported as well. Only the most recent task of each name on each display is considered. A filter
+// selecting tasks only by status is rejected with ErrEmptyFilter, use StopAll for that.
+func (client *Client) StopMany(ctx context.Context, filter types.TaskFilter) ([]types.BulkTaskResult, error) {
+	return client.stopMany(ctx, filter, false)
+}
+
+// StopAll is like StopMany, but it accepts a filter selecting tasks only by status, e.g. all active tasks.
+func (client *Client) StopAll(ctx context.Context, filter types.TaskFilter) ([]types.BulkTaskResult, error) {
+	return client.stopMany(ctx, filter, true)
+}
+
+func (client *Client) stopMany(ctx context.Context, filter types.TaskFilter, all bool) ([]types.BulkTaskResult, error) {
+	if err := client.checkBulkFilter(&filter, all); err != nil {
+		return nil, err
+	}
+	requestPacket, err := packet.EncodeStopPacket(packet.StopRequestBody{Filter: &filter, All: all})
+	if err != nil {
+		return nil, err
+	}
+	responsePacket, err := client.roundTrip(ctx, requestPacket)
+	if err != nil {
+		return nil, err
+	}
+	response, err := packet.DecodeStopResponsePacket(responsePacket)
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.Status {
+	case types.StopResponseStatusSuccess:
+		return response.Results, nil
+	case types.StopResponseStatusEmptyFilter:
+		return nil, ErrEmptyFilter
+	default:
+		return nil, ErrUnknown
+	}
+}
+
+// ResumeMany runs all deactivated tasks matching the filter again. Status selection of the filter decides, whether
+// active tasks are reported as well. Only the most recent task of each name on each display is considered, so older
+// runs of tasks which were run again are not resumed. A filter selecting tasks only by status is rejected with
+// ErrEmptyFilter, use ResumeAll for that.
+func (client *Client) ResumeMany(ctx context.Context, filter types.TaskFilter) ([]types.BulkTaskResult, error) {
+	return client.resumeMany(ctx, filter, false)
+}
+
+// ResumeAll is like ResumeMany, but it accepts a filter selecting tasks only by status, e.g. all deactivated tasks.
+func (client *Client) ResumeAll(ctx context.Context, filter types.TaskFilter) ([]types.BulkTaskResult, error) {
+	return client.resumeMany(ctx, filter, true)
+}
+
+func (client *Client) resumeMany(ctx context.Context, filter types.TaskFilter, all bool) ([]types.BulkTaskResult, error) {
+	if err := client.checkBulkFilter(&filter, all); err != nil {
+		return nil, err
+	}
+	requestPacket, err := packet.EncodeResumePacket(packet.ResumeRequestBody{Filter: &filter, All: all})
+	if err != nil {
+		return nil, err
+	}
+	responsePacket, err := client.roundTrip(ctx, requestPacket)
+	if err != nil {
+		return nil, err
+	}
+	response, err := packet.DecodeResumeResponsePacket(responsePacket)
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.Status {
+	case types.RunResponseStatusSuccess:
+		return response.Results, nil
+	case types.RunResponseStatusEmptyFilter:
+		return nil, ErrEmptyFilter
+	default:
+		return nil, ErrUnknown
+	}
+}
+
+// Move restarts a task given by a selector on a different display, keeping its ID and counters.
+func (client *Client) Move(ctx context.Context, task string, display types.DisplaySelection) (*packet.MoveResponseBody, error) {
+	selector, taskId, err := client.selectTask(task)
+	if err != nil {
+		return nil, err
+	}
+	requestPacket, err := packet.EncodeMovePacket(packet.MoveRequestBody{Task: selector, TaskId: taskId, Display: display})
+	if err != nil {
+		return nil, err
+	}
+	responsePacket, err := client.roundTrip(ctx, requestPacket)
+	if err != nil {
+		return nil, err
+	}
+	response, err := packet.DecodeMoveResponsePacket(responsePacket)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRunStatus((*packet.RunResponseBody)(&response), selector); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Refresh cancels waits between executions of all active tasks matching the filter.
+func (client *Client) Refresh(ctx context.Context, filter types.TaskFilter) (*packet.RefreshResponseBody, error) {
+	if err := client.checkFilter(&filter); err != nil {
+		return nil, err
+	}
+	requestPacket, err := packet.EncodeRefreshPacket(packet.RefreshRequestBody{Filter: filter})
+	if err != nil {
+		return nil, err
+	}
+	responsePacket, err := client.roundTrip(ctx, requestPacket)
+	if err != nil {
+		return nil, err
+	}
+	response, err := packet.DecodeRefreshResponsePacket(responsePacket)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// History returns the most recent executions of a task given by a selector.
+func (client *Client) History(ctx context.Context, task string) (*packet.HistoryResponseBody, error) {
+	selector, _, err := client.selectTask(task)
+	if err != nil {
+		return nil, err
+	}
+	requestPacket, err := packet.EncodeHistoryPacket(packet.HistoryRequestBody{Task: selector})
+	if err != nil {
+		return nil, err
+	}
+	responsePacket, err := client.roundTrip(ctx, requestPacket)
+	if err != nil {
+		return nil, err
+	}
+	response, err := packet.DecodeHistoryResponsePacket(responsePacket)
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.Status {
+	case types.HistoryResponseStatusSuccess:
+		return &response, nil
+	case types.HistoryResponseStatusTaskNotFound:
+		return nil, ErrTaskNotFound
+	case types.HistoryResponseStatusAmbiguousTask:
+		return nil, &AmbiguousTaskError{Selector: selector, Candidates: response.Candidates}
+	default:
+		return nil, ErrUnknown
+	}
+}
+
+// Inspect returns the definition and the current state of a task given by a selector. Values of environment variables
+// which look like secrets are masked by the backend.
+func (client *Client) Inspect(ctx context.Context, task string) (*packet.InspectResponseBody, error) {
+	selector, _, err := client.selectTask(task)
+	if err != nil {
+		return nil, err
+	}
+	requestPacket, err := packet.EncodeInspectPacket(packet.InspectRequestBody{Task: selector})
+	if err != nil {
+		return nil, err
+	}
+	responsePacket, err := client.roundTrip(ctx, requestPacket)
+	if err != nil {
+		return nil, err
+	}
+	response, err := packet.DecodeInspectResponsePacket(responsePacket)
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.Status {
+	case types.InspectResponseStatusSuccess:
+		return &response, nil
+	case types.InspectResponseStatusTaskNotFound:
+		return nil, ErrTaskNotFound
+	case types.InspectResponseStatusAmbiguousTask:
+		return nil, &AmbiguousTaskError{Selector: selector, Candidates: response.Candidates}
+	default:
+		return nil, ErrUnknown
+	}
+}
+
+// selectTask converts a task selector given by the user to the canonical form sent to the backend, see
+// types.TaskSelector. Requests which older backends only accept with an id also get the id, so selectors other than
+// ids fail with ErrNoTaskSelectors on such backends.
+func (client *Client) selectTask(task string) (selector string, taskId int, err error) {
+	parsed, err := types.ParseTaskSelector(task)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w %q: %v", ErrInvalidSelector, task, err)
+	}
+	backend := client.Backend()
+	if !parsed.HasId && !backend.HasCapability(packet.CapabilityTaskSelectors) {
+		return "", 0, ErrNoTaskSelectors
+	}
+	return parsed.String(), parsed.Id, nil
+}
+
+// checkCapability returns ErrNotSupported, if the backend is too old for a request. Requests which older backends
+// would misinterpret must be checked with it.
+func (client *Client) checkCapability(capability packet.Capability) error {
+	backend := client.Backend()
+	if !backend.HasCapability(capability) {
+		return fmt.Errorf("%w: %v", ErrNotSupported, capability)
+	}
+	return nil
+}
+
+// checkBulkFilter validates a filter of StopMany and ResumeMany. Filters selecting tasks only by status are rejected
+// before sending them, because older backends would select all tasks.
+func (client *Client) checkBulkFilter(filter *types.TaskFilter, all bool) error {
+	if err := client.checkCapability(packet.CapabilityBulkActions); err != nil {
+		return err
+	}
+	filter.Derive()
+	if !all && !filter.HasAnyFilter {
+		return ErrEmptyFilter
+	}
+	return client.checkFilter(filter)
+}
+
+// checkFilter validates the where expression of a filter, see types.TaskExpression. Older backends would ignore it and
+// select all tasks, so it's rejected with ErrNotSupported instead.
+func (client *Client) checkFilter(filter *types.TaskFilter) error {
+	if filter.WhereFilter == "" {
+		return nil
+	}
+	if _, err := types.ParseTaskExpression(filter.WhereFilter); err != nil {
+		return fmt.Errorf("%w: invalid where expression: %v", ErrInvalidRequest, err)
+	}
+	return client.checkCapability(packet.CapabilityWhereFilter)
+}
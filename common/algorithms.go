@@ -1,5 +1,7 @@
 package common
 
+import "unicode/utf8"
+
 func ContainsAll(requiredStrings []string, actualStrings []string) bool {
 	for _, req := range requiredStrings {
 		found := false
@@ -25,3 +27,14 @@ func Contains(requiredStrings []string, actualString string) bool {
 	}
 	return false
 }
+
+// Returns the longest prefix of the value, which has at most maxLength bytes and doesn't split a UTF-8 rune.
+func TruncateUtf8(value string, maxLength int) string {
+	if len(value) <= maxLength {
+		return value
+	}
+	for maxLength > 0 && !utf8.RuneStart(value[maxLength]) {
+		maxLength--
+	}
+	return value[:maxLength]
+}
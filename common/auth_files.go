@@ -0,0 +1,36 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Files created by "spieven auth init". Server files are used by a backend accepting remote connections. Client files
+// can be copied to other machines to connect to it.
+const (
+	AuthCaCertFile     = "ca.crt"
+	AuthCaKeyFile      = "ca.key"
+	AuthServerCertFile = "server.crt"
+	AuthServerKeyFile  = "server.key"
+	AuthClientCertFile = "client.crt"
+	AuthClientKeyFile  = "client.key"
+	AuthTokenFile      = "token"
+	AuthClientConfig   = "client.json"
+)
+
+func GetDefaultAuthDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "Spieven", "auth"), nil
+}
+
+// ClientConfig describes how the frontend authenticates to a remote backend. Relative paths are resolved against the
+// directory of the config file, so the whole directory can be copied between machines.
+type ClientConfig struct {
+	CaCertFile     string // CA used to verify the backend
+	ClientCertFile string `json:",omitempty"` // client certificate, can be used instead of the token
+	ClientKeyFile  string `json:",omitempty"`
+	Token          string `json:",omitempty"`
+}
@@ -10,7 +10,6 @@ import (
 const VersionSuffix = "-dev"
 const DefaultPort = "13128"
 const AutorunBackend = false
-const HandshakeValidationEnabled = false
 
 func PrintBuildFlavourNotice() {
 	fmt.Fprintln(os.Stderr, "!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!! WARNING !!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!")
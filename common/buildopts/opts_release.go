@@ -5,6 +5,5 @@ package buildopts
 const VersionSuffix = ""
 const DefaultPort = "13129"
 const AutorunBackend = true
-const HandshakeValidationEnabled = true
 
 func PrintBuildFlavourNotice() {}
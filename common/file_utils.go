@@ -1,41 +1,13 @@
 package common
 
 import (
-	"hash/fnv"
+	"compress/gzip"
 	"io"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 )
 
-func CalculateSpievenFileHash() (uint64, error) {
-	exePath, err := os.Executable()
-	if err != nil {
-		return 0, err
-	}
-	resolvedPath, err := filepath.EvalSymlinks(exePath)
-	if err != nil {
-		return 0, err
-	}
-	return CalculateFileHash(resolvedPath)
-}
-
-func CalculateFileHash(path string) (uint64, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return 0, err
-	}
-	defer f.Close()
-
-	h := fnv.New64a()
-	if _, err := io.Copy(h, f); err != nil {
-		return 0, err
-	}
-
-	return h.Sum64(), nil
-}
-
 func WriteBytesToWriter(writer io.Writer, value []byte) error {
 	written := 0
 	for written < len(value) {
@@ -108,3 +80,31 @@ func CopyFile(src string, dst string) error {
 	}
 	return nil
 }
+
+// CompressFile replaces a file with its gzipped version with a ".gz" suffix appended to the name.
+func CompressFile(src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	dst := src + ".gz"
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	writer := gzip.NewWriter(out)
+	if _, err = io.Copy(writer, in); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	return os.Remove(src)
+}
@@ -7,27 +7,43 @@ import (
 	"io"
 )
 
+// PacketId values are a part of the protocol and must never be changed or reused. New packets get new numbers.
+// Responses have the highest bit set.
 type PacketId byte
 
 const (
 	// Frontend->Backend commands
-	PacketIdHandshake PacketId = iota
-	PacketIdRun
-	PacketIdList
-	PacketIdLog
-	PacketIdQueryTaskActive
-	PacketIdRefresh
-	PacketIdResume
-	PacketIdStop
+	PacketIdHandshake     PacketId = 0
+	PacketIdRun           PacketId = 1
+	PacketIdList          PacketId = 2
+	PacketIdLog           PacketId = 3
+	PacketIdFollowTaskLog PacketId = 4
+	PacketIdRefresh       PacketId = 5
+	PacketIdResume        PacketId = 6
+	PacketIdStop          PacketId = 7
+	PacketIdMove          PacketId = 8
+	PacketIdTaskLogs      PacketId = 9
+	PacketIdAuthenticate  PacketId = 10
+	PacketIdEvents        PacketId = 11
+	PacketIdHistory       PacketId = 12
+	PacketIdInspect       PacketId = 13
 
 	// Backend->Frontend commands
-	PacketIdRunResponse
-	PacketIdListResponse
-	PacketIdLogResponse
-	PacketIdQueryTaskActiveResponse
-	PacketIdRefreshResponse
-	PacketIdResumeResponse
-	PacketIdStopResponse
+	PacketIdHandshakeResponse     PacketId = 128
+	PacketIdRunResponse           PacketId = 129
+	PacketIdListResponse          PacketId = 130
+	PacketIdLogResponse           PacketId = 131
+	PacketIdFollowTaskLogResponse PacketId = 132
+	PacketIdRefreshResponse       PacketId = 133
+	PacketIdResumeResponse        PacketId = 134
+	PacketIdStopResponse          PacketId = 135
+	PacketIdMoveResponse          PacketId = 136
+	PacketIdTaskLogsResponse      PacketId = 137
+	PacketIdAuthenticateResponse  PacketId = 138
+	PacketIdEventsResponse        PacketId = 139
+	PacketIdHistoryResponse       PacketId = 140
+	PacketIdInspectResponse       PacketId = 141
+	PacketIdError                 PacketId = 255
 )
 
 type Packet struct {
@@ -55,7 +71,15 @@ func EncodePacket(PacketId PacketId, data any) (Packet, error) {
 	return result, err
 }
 
+// DecodePacket deserializes the packet body. Fields unknown to this side of the connection are ignored.
 func DecodePacket(packet Packet, expectedPacketId PacketId, data any) error {
+	if packet.Id == PacketIdError && expectedPacketId != PacketIdError {
+		var body ErrorResponseBody
+		if err := json.Unmarshal(packet.Data, &body); err != nil {
+			return err
+		}
+		return &BackendError{Response: body}
+	}
 	if expectedPacketId != packet.Id {
 		return fmt.Errorf("invalid PacketId")
 	}
@@ -0,0 +1,29 @@
+package packet
+
+// AuthenticateRequestBody is sent by the frontend right after establishing a TLS connection. Token can be empty, if
+// the frontend authenticates with a client certificate.
+type AuthenticateRequestBody struct {
+	Token string
+}
+
+func EncodeAuthenticatePacket(body AuthenticateRequestBody) (Packet, error) {
+	return EncodePacket(PacketIdAuthenticate, body)
+}
+
+func DecodeAuthenticatePacket(packet Packet) (result AuthenticateRequestBody, err error) {
+	err = DecodePacket(packet, PacketIdAuthenticate, &result)
+	return
+}
+
+type AuthenticateResponseBody struct {
+	Success bool
+}
+
+func EncodeAuthenticateResponsePacket(body AuthenticateResponseBody) (Packet, error) {
+	return EncodePacket(PacketIdAuthenticateResponse, body)
+}
+
+func DecodeAuthenticateResponsePacket(packet Packet) (result AuthenticateResponseBody, err error) {
+	err = DecodePacket(packet, PacketIdAuthenticateResponse, &result)
+	return
+}
@@ -0,0 +1,28 @@
+package packet
+
+import "fmt"
+
+// ErrorResponseBody is sent by the backend instead of a regular response, when it cannot handle a request, e.g. because
+// it's older than the frontend and does not know the packet.
+type ErrorResponseBody struct {
+	RequestId PacketId
+	Error     string
+}
+
+func EncodeErrorResponsePacket(body ErrorResponseBody) (Packet, error) {
+	return EncodePacket(PacketIdError, body)
+}
+
+func DecodeErrorResponsePacket(packet Packet) (result ErrorResponseBody, err error) {
+	err = DecodePacket(packet, PacketIdError, &result)
+	return
+}
+
+// BackendError is returned when decoding any packet, if the backend responded with an error instead.
+type BackendError struct {
+	Response ErrorResponseBody
+}
+
+func (err *BackendError) Error() string {
+	return fmt.Sprintf("backend cannot handle the request: %v. It may be older than the frontend, restart it to use the new version", err.Response.Error)
+}
@@ -0,0 +1,29 @@
+package packet
+
+import "spieven/common/types"
+
+// EventsRequestBody subscribes to events. Backend keeps sending them until the connection is closed.
+type EventsRequestBody struct {
+	Filter types.EventFilter
+}
+
+func EncodeEventsPacket(body EventsRequestBody) (Packet, error) {
+	return EncodePacket(PacketIdEvents, body)
+}
+
+func DecodeEventsPacket(packet Packet) (result EventsRequestBody, err error) {
+	err = DecodePacket(packet, PacketIdEvents, &result)
+	return
+}
+
+// EventsResponseBody is a batch of events. The first one is empty and confirms the subscription.
+type EventsResponseBody []types.Event
+
+func EncodeEventsResponsePacket(body EventsResponseBody) (Packet, error) {
+	return EncodePacket(PacketIdEventsResponse, body)
+}
+
+func DecodeEventsResponsePacket(packet Packet) (result EventsResponseBody, err error) {
+	err = DecodePacket(packet, PacketIdEventsResponse, &result)
+	return
+}
@@ -0,0 +1,54 @@
+package packet
+
+import "spieven/common/types"
+
+type FollowTaskLogRequestBody struct {
+	Task   string // canonical types.TaskSelector, takes precedence over TaskId
+	TaskId int    // used by frontends not supporting task selectors
+	Offset int64  // position in the task log to start streaming from
+
+	// When set, logs of all active tasks matching the filter are followed instead of a single task. Tasks are picked
+	// up and dropped as they are activated and deactivated, so the stream does not end until the connection is closed.
+	Filter *types.TaskFilter
+}
+
+func EncodeFollowTaskLogPacket(body FollowTaskLogRequestBody) (Packet, error) {
+	return EncodePacket(PacketIdFollowTaskLog, body)
+}
+
+func DecodeFollowTaskLogPacket(packet Packet) (result FollowTaskLogRequestBody, err error) {
+	err = DecodePacket(packet, PacketIdFollowTaskLog, &result)
+	return
+}
+
+// FollowTaskLogResponseStatus describes a single response packet. Backend announces each followed task, keeps sending
+// chunks of its log as it grows and sends a final packet once it's deactivated. When following a single task, the
+// connection is closed after that.
+type FollowTaskLogResponseStatus byte
+
+const (
+	FollowTaskLogResponseStatusStarted FollowTaskLogResponseStatus = iota
+	FollowTaskLogResponseStatusChunk
+	FollowTaskLogResponseStatusDeactivated
+	FollowTaskLogResponseStatusInvalidTask
+	FollowTaskLogResponseStatusReadError
+	FollowTaskLogResponseStatusAmbiguousTask
+)
+
+type FollowTaskLogResponseBody struct {
+	Status     FollowTaskLogResponseStatus
+	TaskId     int
+	TaskName   string // only sent with FollowTaskLogResponseStatusStarted
+	Data       []byte
+	Error      string
+	Candidates []types.TaskCandidate `json:",omitempty"` // only sent with FollowTaskLogResponseStatusAmbiguousTask
+}
+
+func EncodeFollowTaskLogResponsePacket(body FollowTaskLogResponseBody) (Packet, error) {
+	return EncodePacket(PacketIdFollowTaskLogResponse, body)
+}
+
+func DecodeFollowTaskLogResponsePacket(packet Packet) (result FollowTaskLogResponseBody, err error) {
+	err = DecodePacket(packet, PacketIdFollowTaskLogResponse, &result)
+	return
+}
@@ -1,12 +1,94 @@
 package packet
 
-type HandshakeRequestBody uint64
+// Version of the protocol spoken between the frontend and the backend. Major version is changed on incompatible
+// changes, such as removed packets or fields with changed meaning. Minor version is changed when packets or fields are
+// added. Unknown fields are ignored when decoding and unknown packets are answered with PacketIdError, so frontends
+// and backends with the same major version can talk to each other.
+const (
+	ProtocolVersionMajor = 1
+	ProtocolVersionMinor = 6
+)
 
-func EncodeHandshakePacket(value HandshakeRequestBody) (Packet, error) {
-	return EncodePacket(PacketIdHandshake, &value)
+// Capability names an optional feature, so that clients can check for it without comparing versions.
+type Capability string
+
+const (
+	CapabilityFollowTaskLog Capability = "follow-task-log" // streaming task logs with PacketIdFollowTaskLog
+	CapabilityFollowFilter  Capability = "follow-filter"   // following logs of many tasks with a filter
+	CapabilityTaskLogs      Capability = "task-logs"       // reading task logs with PacketIdTaskLogs
+	CapabilityLogFilters    Capability = "log-filters"     // structured backend messages and filtering them
+	CapabilityLogSinks      Capability = "log-sinks"       // forwarding task logs to syslog or a pipe
+	CapabilityMoveTask      Capability = "move-task"       // moving tasks to other displays
+	CapabilityEvents        Capability = "events"          // subscribing to events with PacketIdEvents
+	CapabilityHistory       Capability = "history"         // execution history of tasks with PacketIdHistory
+	CapabilityInspect       Capability = "inspect"         // all details of a single task with PacketIdInspect
+	CapabilityTaskSelectors Capability = "task-selectors"  // selecting tasks by name, display or tag in requests
+	CapabilityBulkActions   Capability = "bulk-actions"    // stopping and resuming all tasks matching a filter
+	CapabilityWhereFilter   Capability = "where-filter"    // filtering tasks with TaskFilter.WhereFilter expressions
+)
+
+var SupportedCapabilities = []Capability{
+	CapabilityFollowTaskLog,
+	CapabilityFollowFilter,
+	CapabilityTaskLogs,
+	CapabilityLogFilters,
+	CapabilityLogSinks,
+	CapabilityMoveTask,
+	CapabilityEvents,
+	CapabilityHistory,
+	CapabilityInspect,
+	CapabilityTaskSelectors,
+	CapabilityBulkActions,
+	CapabilityWhereFilter,
+}
+
+// HandshakeRequestBody is the first packet sent by the frontend on every connection.
+type HandshakeRequestBody struct {
+	ProtocolMajor int
+	ProtocolMinor int
+	Capabilities  []Capability
+	ClientVersion string // informational, e.g. version of the frontend binary
+}
+
+func EncodeHandshakePacket(body HandshakeRequestBody) (Packet, error) {
+	return EncodePacket(PacketIdHandshake, body)
 }
 
 func DecodeHandshakePacket(packet Packet) (result HandshakeRequestBody, err error) {
 	err = DecodePacket(packet, PacketIdHandshake, &result)
 	return
 }
+
+type HandshakeResponseStatus byte
+
+const (
+	HandshakeResponseStatusAccepted HandshakeResponseStatus = iota
+	HandshakeResponseStatusIncompatibleVersion
+)
+
+// HandshakeResponseBody is sent by the backend in both cases. The connection is closed after an incompatible version.
+type HandshakeResponseBody struct {
+	Status         HandshakeResponseStatus
+	ProtocolMajor  int
+	ProtocolMinor  int
+	Capabilities   []Capability
+	BackendVersion string
+}
+
+func (body *HandshakeResponseBody) HasCapability(capability Capability) bool {
+	for _, c := range body.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+func EncodeHandshakeResponsePacket(body HandshakeResponseBody) (Packet, error) {
+	return EncodePacket(PacketIdHandshakeResponse, body)
+}
+
+func DecodeHandshakeResponsePacket(packet Packet) (result HandshakeResponseBody, err error) {
+	err = DecodePacket(packet, PacketIdHandshakeResponse, &result)
+	return
+}
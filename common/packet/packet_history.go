@@ -0,0 +1,36 @@
+package packet
+
+import "spieven/common/types"
+
+type HistoryRequestBody struct {
+	Task string // canonical types.TaskSelector
+}
+
+func EncodeHistoryPacket(body HistoryRequestBody) (Packet, error) {
+	return EncodePacket(PacketIdHistory, body)
+}
+
+func DecodeHistoryPacket(packet Packet) (body HistoryRequestBody, err error) {
+	err = DecodePacket(packet, PacketIdHistory, &body)
+	return
+}
+
+type HistoryResponseBody struct {
+	Status       types.HistoryResponseStatus
+	TaskId       int
+	FriendlyName string
+	IsActive     bool
+	RunCount     int                     // total number of executions, the history only keeps the most recent ones
+	TotalUsage   types.ResourceUsage     // resources used by all executions, including ones dropped from the history
+	Executions   []types.ExecutionRecord // oldest first
+	Candidates   []types.TaskCandidate   `json:",omitempty"` // only for HistoryResponseStatusAmbiguousTask
+}
+
+func EncodeHistoryResponsePacket(body HistoryResponseBody) (Packet, error) {
+	return EncodePacket(PacketIdHistoryResponse, body)
+}
+
+func DecodeHistoryResponsePacket(packet Packet) (result HistoryResponseBody, err error) {
+	err = DecodePacket(packet, PacketIdHistoryResponse, &result)
+	return
+}
@@ -0,0 +1,78 @@
+package packet
+
+import (
+	"spieven/common/types"
+	"time"
+)
+
+type InspectRequestBody struct {
+	Task string // canonical types.TaskSelector
+}
+
+func EncodeInspectPacket(body InspectRequestBody) (Packet, error) {
+	return EncodePacket(PacketIdInspect, body)
+}
+
+func DecodeInspectPacket(packet Packet) (body InspectRequestBody, err error) {
+	err = DecodePacket(packet, PacketIdInspect, &body)
+	return
+}
+
+// InspectResponseBody contains everything the backend knows about a task. Durations are computed by the backend, so
+// they are correct even if clocks of the frontend and the backend differ.
+type InspectResponseBody struct {
+	Status types.InspectResponseStatus
+
+	// Definition of the task, as passed by the frontend
+	Id                    int
+	FriendlyName          string
+	Cmdline               []string
+	Cwd                   string
+	Env                   []string // values of variables which look like secrets are masked
+	Display               types.DisplaySelection
+	Tags                  []string
+	CaptureStdout         bool
+	CaptureStderr         bool
+	DelayAfterSuccessMs   int
+	DelayAfterFailureMs   int
+	MaxSubsequentFailures int
+	LogRetention          types.LogRetention
+	LogFormat             types.TaskLogFormat
+	LogSinks              types.LogSinks
+
+	// Values computed by the backend
+	Hash            int
+	NameDisplayHash int
+	DisplayState    types.DisplayState
+
+	// State of the task
+	IsDeactivated          bool
+	DeactivationReason     string
+	RunCount               int
+	FailureCount           int
+	SubsequentFailureCount int
+	LastExitValue          int
+	Pids                   []int         // the running command followed by its descendants, empty if not running
+	CurrentStartTime       time.Time     // zero if the command is not running
+	CurrentUptime          time.Duration // 0 if the command is not running
+	NextRunTime            time.Time     // zero if the task is not waiting for the next execution
+	TimeUntilNextRun       time.Duration // 0 if the task is not waiting for the next execution
+	RecentExecutions       []types.ExecutionRecord
+	TotalUsage             types.ResourceUsage
+
+	// Files of the task
+	LogFile            string
+	LastStdoutFilePath string
+	LastStderrFilePath string
+
+	Candidates []types.TaskCandidate `json:",omitempty"` // only for InspectResponseStatusAmbiguousTask
+}
+
+func EncodeInspectResponsePacket(body InspectResponseBody) (Packet, error) {
+	return EncodePacket(PacketIdInspectResponse, body)
+}
+
+func DecodeInspectResponsePacket(packet Packet) (result InspectResponseBody, err error) {
+	err = DecodePacket(packet, PacketIdInspectResponse, &result)
+	return
+}
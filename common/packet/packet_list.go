@@ -1,6 +1,9 @@
 package packet
 
-import "spieven/common/types"
+import (
+	"spieven/common/types"
+	"time"
+)
 
 type ListRequestBody struct {
 	Filter      types.TaskFilter
@@ -31,8 +34,12 @@ type ListResponseBodyItem struct {
 	FailureCount           int
 	SubsequentFailureCount int
 	LastExitValue          int
+	TotalUsage             types.ResourceUsage
 	LastStdout             string
 	HasLastStdout          bool
+	CurrentPid             int       // 0 if the command is not running
+	CurrentStartTime       time.Time // zero if the command is not running
+	NextRunTime            time.Time // zero if the task is not waiting for the next execution
 }
 type ListResponseBody []ListResponseBodyItem
 
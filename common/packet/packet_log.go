@@ -1,14 +1,31 @@
 package packet
 
-func EncodeLogPacket() (Packet, error) {
-	return EncodePacket(PacketIdLog, nil)
+import (
+	"spieven/common/types"
+	"time"
+)
+
+type LogRequestBody struct {
+	MinSeverity types.BackendMessageSeverity
+	Kind        types.BackendMessageKind // empty means any kind
+	TaskId      *int                     // nil means messages of any task or no task at all
+	Since       time.Time                // zero means no limit
+
+	// When set, backend keeps sending new messages matching the filters as they are added. It does not end until
+	// the connection is closed.
+	Follow bool
 }
 
-func DecodeLogPacket(packet Packet) error {
-	return DecodePacket(packet, PacketIdLog, nil)
+func EncodeLogPacket(body LogRequestBody) (Packet, error) {
+	return EncodePacket(PacketIdLog, body)
+}
+
+func DecodeLogPacket(packet Packet) (result LogRequestBody, err error) {
+	err = DecodePacket(packet, PacketIdLog, &result)
+	return
 }
 
-type LogResponseBody []string
+type LogResponseBody []types.BackendMessage
 
 func EncodeLogResponsePacket(body LogResponseBody) (Packet, error) {
 	return EncodePacket(PacketIdLogResponse, body)
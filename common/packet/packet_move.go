@@ -0,0 +1,29 @@
+package packet
+
+import "spieven/common/types"
+
+type MoveRequestBody struct {
+	Task    string // canonical types.TaskSelector, takes precedence over TaskId
+	TaskId  int    // used by frontends not supporting task selectors
+	Display types.DisplaySelection
+}
+
+func EncodeMovePacket(body MoveRequestBody) (Packet, error) {
+	return EncodePacket(PacketIdMove, body)
+}
+
+func DecodeMovePacket(packet Packet) (body MoveRequestBody, err error) {
+	err = DecodePacket(packet, PacketIdMove, &body)
+	return
+}
+
+type MoveResponseBody RunResponseBody
+
+func EncodeMoveResponsePacket(body MoveResponseBody) (Packet, error) {
+	return EncodePacket(PacketIdMoveResponse, body)
+}
+
+func DecodeMoveResponsePacket(packet Packet) (result MoveResponseBody, err error) {
+	err = DecodePacket(packet, PacketIdMoveResponse, &result)
+	return
+}
@@ -1,7 +1,18 @@
 package packet
 
+import "spieven/common/types"
+
 type ResumeRequestBody struct {
-	TaskId int
+	Task   string // canonical types.TaskSelector, takes precedence over TaskId
+	TaskId int    // used by frontends not supporting task selectors
+
+	// When set, all deactivated tasks matching the filter are resumed instead of a single task. Only the most recent
+	// task of each name on each display is considered, like with task selectors.
+	Filter *types.TaskFilter
+
+	// Filters selecting tasks only by status are rejected, unless All is set, so a forgotten filter does not resume
+	// all tasks.
+	All bool
 }
 
 func EncodeResumePacket(body ResumeRequestBody) (Packet, error) {
@@ -13,7 +24,10 @@ func DecodeResumePacket(packet Packet) (body ResumeRequestBody, err error) {
 	return
 }
 
-type ResumeResponseBody RunResponseBody
+type ResumeResponseBody struct {
+	RunResponseBody
+	Results []types.BulkTaskResult `json:",omitempty"` // only for requests with a filter, ordered by task id
+}
 
 func EncodeResumeResponsePacket(body ResumeResponseBody) (Packet, error) {
 	return EncodePacket(PacketIdResumeResponse, body)
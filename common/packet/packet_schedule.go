@@ -14,6 +14,9 @@ type RunRequestBody struct {
 	DelayAfterFailureMs   int
 	MaxSubsequentFailures int
 	Tags                  []string
+	LogRetention          types.LogRetention
+	LogFormat             types.TaskLogFormat
+	LogSinks              types.LogSinks
 }
 
 func EncodeRunPacket(data RunRequestBody) (Packet, error) {
@@ -26,9 +29,10 @@ func DecodeRunPacket(packet Packet) (result RunRequestBody, err error) {
 }
 
 type RunResponseBody struct {
-	Status  types.RunResponseStatus
-	Id      int
-	LogFile string
+	Status     types.RunResponseStatus
+	Id         int
+	LogFile    string
+	Candidates []types.TaskCandidate `json:",omitempty"` // only for RunResponseStatusAmbiguousTask
 }
 
 func EncodeRunResponsePacket(value RunResponseBody) (Packet, error) {
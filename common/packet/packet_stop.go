@@ -3,7 +3,16 @@ package packet
 import "spieven/common/types"
 
 type StopRequestBody struct {
-	TaskId int
+	Task   string // canonical types.TaskSelector, takes precedence over TaskId
+	TaskId int    // used by frontends not supporting task selectors
+
+	// When set, all tasks matching the filter are stopped instead of a single task. Only the most recent task of each
+	// name on each display is considered, like with task selectors.
+	Filter *types.TaskFilter
+
+	// Filters selecting tasks only by status are rejected, unless All is set, so a forgotten filter does not stop
+	// all tasks.
+	All bool
 }
 
 func EncodeStopPacket(body StopRequestBody) (Packet, error) {
@@ -16,7 +25,9 @@ func DecodeStopPacket(packet Packet) (body StopRequestBody, err error) {
 }
 
 type StopResponseBody struct {
-	Status types.StopResponseStatus
+	Status     types.StopResponseStatus
+	Candidates []types.TaskCandidate  `json:",omitempty"` // only for StopResponseStatusAmbiguousTask
+	Results    []types.BulkTaskResult `json:",omitempty"` // only for requests with a filter, ordered by task id
 }
 
 func EncodeStopResponsePacket(body StopResponseBody) (Packet, error) {
@@ -0,0 +1,86 @@
+package packet
+
+import (
+	"regexp"
+	"spieven/common/types"
+	"time"
+)
+
+type TaskLogsSource byte
+
+const (
+	TaskLogsSourceTaskLog TaskLogsSource = iota
+	TaskLogsSourceStdout
+	TaskLogsSourceStderr
+)
+
+type TaskLogsRequestBody struct {
+	Task      string // canonical types.TaskSelector
+	Source    TaskLogsSource
+	Execution int // execution id or one of types.ExecutionSelection* constants
+	Tail      int // 0 means all lines
+	Since     time.Time // only for TaskLogsSourceTaskLog, stdout and stderr have no timestamps
+	Until     time.Time // only for TaskLogsSourceTaskLog, stdout and stderr have no timestamps
+	Grep      string // regular expression lines have to match
+}
+
+// MatchesExecution checks whether an execution is selected by the request. ExecutionSelectionLast has to be resolved
+// to an execution id first, see TaskLogsResponseBody.Execution.
+func (request *TaskLogsRequestBody) MatchesExecution(executionId int) bool {
+	return request.Execution == types.ExecutionSelectionAll || request.Execution == executionId
+}
+
+// MatchesRecord checks a record against all filters of the request except for Tail. The grep expression must be
+// already compiled from the request, or nil if there is none.
+func (request *TaskLogsRequestBody) MatchesRecord(record *types.TaskLogRecord, grep *regexp.Regexp) bool {
+	switch request.Source {
+	case TaskLogsSourceStdout:
+		if record.Stream != types.TaskLogStreamStdout {
+			return false
+		}
+	case TaskLogsSourceStderr:
+		if record.Stream != types.TaskLogStreamStderr {
+			return false
+		}
+	}
+	if !request.MatchesExecution(record.Execution) {
+		return false
+	}
+	if !request.Since.IsZero() && record.Time.Before(request.Since) {
+		return false
+	}
+	if !request.Until.IsZero() && record.Time.After(request.Until) {
+		return false
+	}
+	return grep == nil || grep.MatchString(record.Line)
+}
+
+func EncodeTaskLogsPacket(body TaskLogsRequestBody) (Packet, error) {
+	return EncodePacket(PacketIdTaskLogs, body)
+}
+
+func DecodeTaskLogsPacket(packet Packet) (body TaskLogsRequestBody, err error) {
+	err = DecodePacket(packet, PacketIdTaskLogs, &body)
+	return
+}
+
+type TaskLogsResponseBody struct {
+	Status        types.TaskLogsResponseStatus
+	Error         string
+	TaskId        int
+	LogFile       string
+	LogFileOffset int64 // position in the log file up to which the records were read
+	Execution     int   // execution of the request with ExecutionSelectionLast resolved to an execution id
+	IsActive      bool
+	Records       []types.TaskLogRecord
+	Candidates    []types.TaskCandidate `json:",omitempty"` // only for TaskLogsResponseStatusAmbiguousTask
+}
+
+func EncodeTaskLogsResponsePacket(body TaskLogsResponseBody) (Packet, error) {
+	return EncodePacket(PacketIdTaskLogsResponse, body)
+}
+
+func DecodeTaskLogsResponsePacket(packet Packet) (result TaskLogsResponseBody, err error) {
+	err = DecodePacket(packet, PacketIdTaskLogsResponse, &result)
+	return
+}
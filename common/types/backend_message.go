@@ -0,0 +1,109 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type BackendMessageSeverity byte
+
+const (
+	BackendMessageSeverityInfo BackendMessageSeverity = iota
+	BackendMessageSeverityError
+)
+
+const BackendMessageSeverityStrValues = "info, error"
+
+func ParseBackendMessageSeverity(value string) (BackendMessageSeverity, error) {
+	switch value {
+	case "info":
+		return BackendMessageSeverityInfo, nil
+	case "error":
+		return BackendMessageSeverityError, nil
+	default:
+		return BackendMessageSeverityInfo, fmt.Errorf("invalid severity %q, expected one of: %v", value, BackendMessageSeverityStrValues)
+	}
+}
+
+func (severity BackendMessageSeverity) String() string {
+	switch severity {
+	case BackendMessageSeverityInfo:
+		return "info"
+	case BackendMessageSeverityError:
+		return "error"
+	default:
+		return "invalid"
+	}
+}
+
+func (severity BackendMessageSeverity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(severity.String())
+}
+
+func (severity *BackendMessageSeverity) UnmarshalJSON(data []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*severity, err = ParseBackendMessageSeverity(s)
+	return err
+}
+
+// BackendMessageKind describes what area of the backend a message concerns.
+type BackendMessageKind string
+
+const (
+	BackendMessageKindTask       BackendMessageKind = "task"       // lifecycle of tasks: running, stopping, deactivation
+	BackendMessageKindRequest    BackendMessageKind = "request"    // rejected frontend requests
+	BackendMessageKindDisplay    BackendMessageKind = "display"    // displays being opened or closed
+	BackendMessageKindLogs       BackendMessageKind = "logs"       // task log files and log sinks
+	BackendMessageKindStorage    BackendMessageKind = "storage"    // deactivated tasks saved to disk
+	BackendMessageKindConnection BackendMessageKind = "connection" // frontend connections
+	BackendMessageKindBackend    BackendMessageKind = "backend"    // state of the backend itself
+)
+
+const BackendMessageKindStrValues = "task, request, display, logs, storage, connection, backend"
+
+func ParseBackendMessageKind(value string) (BackendMessageKind, error) {
+	kind := BackendMessageKind(value)
+	switch kind {
+	case BackendMessageKindTask, BackendMessageKindRequest, BackendMessageKindDisplay, BackendMessageKindLogs,
+		BackendMessageKindStorage, BackendMessageKindConnection, BackendMessageKindBackend:
+		return kind, nil
+	default:
+		return "", fmt.Errorf("invalid message kind %q, expected one of: %v", value, BackendMessageKindStrValues)
+	}
+}
+
+// BackendMessage is a description of an event or a failure encountered by the backend. Messages are stored for later
+// retrieval by the log command.
+type BackendMessage struct {
+	Sequence uint64 // increasing number, allowing to tell which messages were already seen
+	Time     time.Time
+	Severity BackendMessageSeverity
+	Kind     BackendMessageKind
+	TaskId   *int   `json:",omitempty"`
+	TaskName string `json:",omitempty"`
+	Display  string `json:",omitempty"`
+	Content  string
+}
+
+func (msg *BackendMessage) String() string {
+	var severity string
+	switch msg.Severity {
+	case BackendMessageSeverityInfo:
+		severity = " INFO"
+	case BackendMessageSeverityError:
+		severity = "ERROR"
+	default:
+		severity = "     " // Should not happen, but let's handle it gracefully
+	}
+
+	date := msg.Time.Format("2006-01-02 15-04-05")
+	taskLabel := ""
+	if msg.TaskId != nil {
+		taskLabel = fmt.Sprintf(" (task id=%v, %v)", *msg.TaskId, msg.TaskName)
+	}
+	return fmt.Sprintf("[%v][%v] %v%v", severity, date, msg.Content, taskLabel)
+}
@@ -0,0 +1,21 @@
+package types
+
+// BulkTaskOutcome tells what happened to one of the tasks selected by a filter in a bulk stop or resume.
+type BulkTaskOutcome string
+
+const (
+	BulkTaskOutcomeStopped        BulkTaskOutcome = "stopped"
+	BulkTaskOutcomeAlreadyStopped BulkTaskOutcome = "already-stopped"
+	BulkTaskOutcomeResumed        BulkTaskOutcome = "resumed"
+	BulkTaskOutcomeAlreadyActive  BulkTaskOutcome = "already-active"
+	BulkTaskOutcomeFailed         BulkTaskOutcome = "failed"
+)
+
+// BulkTaskResult describes the outcome of a bulk request for a single task.
+type BulkTaskResult struct {
+	TaskId       int
+	FriendlyName string
+	Display      DisplaySelection
+	Outcome      BulkTaskOutcome
+	Error        string `json:",omitempty"` // only for BulkTaskOutcomeFailed
+}
@@ -155,3 +155,13 @@ func readCurrentDisplayNameFromEnv(displayType DisplaySelectionType) (string, er
 	}
 	return displayName, nil
 }
+
+// DisplayState tells whether tasks can currently run on a display.
+type DisplayState string
+
+const (
+	DisplayStateHeadless    DisplayState = "headless"    // task does not need a display
+	DisplayStateActive      DisplayState = "active"      // display is watched by the backend
+	DisplayStateInactive    DisplayState = "inactive"    // display vanished or was never used by any task
+	DisplayStateUnsupported DisplayState = "unsupported" // libraries for the display type are not available
+)
@@ -0,0 +1,116 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventType describes what happened. Types are grouped into categories by the prefix before the first hyphen, e.g.
+// all task-* events belong to the task category.
+type EventType string
+
+const (
+	EventTypeTaskScheduled   EventType = "task-scheduled"   // task was run by a frontend
+	EventTypeTaskResumed     EventType = "task-resumed"     // deactivated task was resumed by a frontend
+	EventTypeTaskMoved       EventType = "task-moved"       // task was restarted on a different display
+	EventTypeTaskStarted     EventType = "task-started"     // a new execution of the command has started
+	EventTypeTaskExited      EventType = "task-exited"      // the command ended with exit code 0
+	EventTypeTaskFailed      EventType = "task-failed"      // the command ended with a non-zero exit code or could not be started
+	EventTypeTaskDeactivated EventType = "task-deactivated" // task will not be executed anymore
+	EventTypeDisplayAppeared EventType = "display-appeared" // backend connected to a display used by a task
+	EventTypeDisplayVanished EventType = "display-vanished" // display server has stopped working
+)
+
+var AllEventTypes = []EventType{
+	EventTypeTaskScheduled,
+	EventTypeTaskResumed,
+	EventTypeTaskMoved,
+	EventTypeTaskStarted,
+	EventTypeTaskExited,
+	EventTypeTaskFailed,
+	EventTypeTaskDeactivated,
+	EventTypeDisplayAppeared,
+	EventTypeDisplayVanished,
+}
+
+func (eventType EventType) Category() string {
+	category, _, _ := strings.Cut(string(eventType), "-")
+	return category
+}
+
+// ParseEventTypes parses a single event type or a category of event types, e.g. "task".
+func ParseEventTypes(value string) ([]EventType, error) {
+	var result []EventType
+	for _, eventType := range AllEventTypes {
+		if string(eventType) == value || eventType.Category() == value {
+			result = append(result, eventType)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("invalid event type %q, expected a category (task, display) or one of: %v", value, AllEventTypes)
+	}
+	return result, nil
+}
+
+// Event is pushed by the backend to subscribed frontends as soon as something changes. Unlike BackendMessage, events
+// are not stored.
+type Event struct {
+	Time      time.Time
+	Type      EventType
+	TaskId    *int   `json:",omitempty"`
+	TaskName  string `json:",omitempty"`
+	Display   string `json:",omitempty"` // display of the task or the display the event is about
+	Execution *int   `json:",omitempty"` // index of the execution of the task
+	ExitCode  *int   `json:",omitempty"`
+	Reason    string `json:",omitempty"`
+}
+
+func (event *Event) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "[%v] %v", event.Time.Format("2006-01-02 15-04-05"), event.Type)
+	if event.TaskId != nil {
+		fmt.Fprintf(&builder, " task=%v", *event.TaskId)
+	}
+	if event.TaskName != "" {
+		fmt.Fprintf(&builder, " name=%v", event.TaskName)
+	}
+	if event.Display != "" {
+		fmt.Fprintf(&builder, " display=%v", event.Display)
+	}
+	if event.Execution != nil {
+		fmt.Fprintf(&builder, " execution=%v", *event.Execution)
+	}
+	if event.ExitCode != nil {
+		fmt.Fprintf(&builder, " exitCode=%v", *event.ExitCode)
+	}
+	if event.Reason != "" {
+		fmt.Fprintf(&builder, " reason=%q", event.Reason)
+	}
+	return builder.String()
+}
+
+// EventFilter selects events sent to a subscriber. Empty fields match all events.
+type EventFilter struct {
+	Types  []EventType
+	TaskId *int
+}
+
+func (filter *EventFilter) Matches(event *Event) bool {
+	if len(filter.Types) > 0 {
+		matched := false
+		for _, eventType := range filter.Types {
+			if eventType == event.Type {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if filter.TaskId != nil && (event.TaskId == nil || *event.TaskId != *filter.TaskId) {
+		return false
+	}
+	return true
+}
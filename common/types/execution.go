@@ -0,0 +1,45 @@
+package types
+
+import "time"
+
+// ExecutionTrigger tells why an execution of a task was started.
+type ExecutionTrigger string
+
+const (
+	ExecutionTriggerScheduled ExecutionTrigger = "scheduled" // first execution of a newly run task
+	ExecutionTriggerTimer     ExecutionTrigger = "timer"     // delay after the previous execution passed
+	ExecutionTriggerRefresh   ExecutionTrigger = "refresh"   // refresh cut the delay after the previous execution short
+	ExecutionTriggerResume    ExecutionTrigger = "resume"    // first execution after resuming a deactivated task
+	ExecutionTriggerMove      ExecutionTrigger = "move"      // first execution after moving a task to another display
+)
+
+// ExecutionResult tells how an execution of a task ended.
+type ExecutionResult string
+
+const (
+	ExecutionResultSuccess       ExecutionResult = "success"
+	ExecutionResultFailure       ExecutionResult = "failure" // non-zero exit code
+	ExecutionResultStartFailed   ExecutionResult = "start-failed"
+	ExecutionResultStopped       ExecutionResult = "stopped" // killed by a stop, move or vanished display
+	ExecutionResultBackendKilled ExecutionResult = "backend-killed"
+	ExecutionResultLogFailed     ExecutionResult = "log-failed" // killed, because its output could not be logged
+)
+
+// ExecutionRecord describes a single finished execution of a task.
+type ExecutionRecord struct {
+	Execution      int
+	Trigger        ExecutionTrigger
+	StartTime      time.Time
+	EndTime        time.Time
+	Duration       time.Duration
+	Result         ExecutionResult
+	ExitCode       int            // only valid for ExecutionResultSuccess and ExecutionResultFailure
+	StdoutFilePath string         // empty if stdout was not captured
+	StderrFilePath string         // empty if stderr was not captured
+	Usage          *ResourceUsage // nil if the command did not end by itself, e.g. it was stopped
+}
+
+// HasExitCode returns true if the command ended by itself, so its exit code is known.
+func (record *ExecutionRecord) HasExitCode() bool {
+	return record.Result == ExecutionResultSuccess || record.Result == ExecutionResultFailure
+}
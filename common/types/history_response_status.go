@@ -0,0 +1,10 @@
+package types
+
+type HistoryResponseStatus byte
+
+const (
+	HistoryResponseStatusSuccess HistoryResponseStatus = iota
+	HistoryResponseStatusTaskNotFound
+	HistoryResponseStatusUnknown
+	HistoryResponseStatusAmbiguousTask
+)
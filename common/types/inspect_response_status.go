@@ -0,0 +1,10 @@
+package types
+
+type InspectResponseStatus byte
+
+const (
+	InspectResponseStatusSuccess InspectResponseStatus = iota
+	InspectResponseStatusTaskNotFound
+	InspectResponseStatusUnknown
+	InspectResponseStatusAmbiguousTask
+)
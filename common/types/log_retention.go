@@ -0,0 +1,30 @@
+package types
+
+import "time"
+
+// LogRetention describes how long logs of a task are kept on disk. For each field zero means the backend default
+// should be used and a negative value means there is no limit.
+type LogRetention struct {
+	MaxExecutions   int           // number of most recent executions to keep stdout/stderr files for
+	MaxTaskLogBytes int64         // size of the task log after which it is rotated
+	MaxAge          time.Duration // age after which stdout/stderr files and rotated task logs are removed
+	Compress        bool          // gzip stdout/stderr files of older executions and rotated task logs
+}
+
+func (retention LogRetention) WithDefaults(defaults LogRetention) LogRetention {
+	if retention.MaxExecutions == 0 {
+		retention.MaxExecutions = defaults.MaxExecutions
+	}
+	if retention.MaxTaskLogBytes == 0 {
+		retention.MaxTaskLogBytes = defaults.MaxTaskLogBytes
+	}
+	if retention.MaxAge == 0 {
+		retention.MaxAge = defaults.MaxAge
+	}
+	retention.Compress = retention.Compress || defaults.Compress
+	return retention
+}
+
+func (retention LogRetention) HasMaxExecutions() bool   { return retention.MaxExecutions > 0 }
+func (retention LogRetention) HasMaxTaskLogBytes() bool { return retention.MaxTaskLogBytes > 0 }
+func (retention LogRetention) HasMaxAge() bool          { return retention.MaxAge > 0 }
@@ -0,0 +1,55 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// LogSinks describes where lines of task logs are forwarded, in addition to the task log file. Empty fields mean
+// the sink is not used.
+type LogSinks struct {
+	Syslog          string // address of a syslog daemon accepting RFC 5424 messages
+	SyslogMaxLength int    // longer syslog messages are truncated, 0 means DefaultSyslogMaxLength
+	LogPipe         string // shell command getting each line as a json object on its stdin
+}
+
+// DefaultSyslogMaxLength is the size of messages, which RFC 5426 recommends all syslog receivers to accept over UDP.
+// Unix datagram sockets also limit the size of messages.
+const DefaultSyslogMaxLength = 2048
+
+const SyslogMaxLengthHelpString = "Maximum length of a syslog message in bytes, longer messages are truncated (default: 2048)"
+
+// Validate checks the configured sinks before sending them to the backend.
+func (sinks *LogSinks) Validate() error {
+	if sinks.Syslog != "" {
+		if _, _, err := ParseSyslogAddress(sinks.Syslog); err != nil {
+			return err
+		}
+	}
+	if sinks.SyslogMaxLength < 0 {
+		return errors.New("syslog max length must not be negative")
+	}
+	return nil
+}
+
+// GetSyslogMaxLength returns the maximum length of a syslog message, applying the default.
+func (sinks *LogSinks) GetSyslogMaxLength() int {
+	if sinks.SyslogMaxLength == 0 {
+		return DefaultSyslogMaxLength
+	}
+	return sinks.SyslogMaxLength
+}
+
+const SyslogAddressHelpString = "Use unix://PATH for a unix datagram socket, e.g. unix:///dev/log, or udp://HOST:PORT."
+
+// ParseSyslogAddress converts a syslog address to arguments for net.Dial.
+func ParseSyslogAddress(value string) (network string, address string, err error) {
+	if path, found := strings.CutPrefix(value, "unix://"); found && path != "" {
+		return "unixgram", path, nil
+	}
+	if hostWithPort, found := strings.CutPrefix(value, "udp://"); found && hostWithPort != "" {
+		return "udp", hostWithPort, nil
+	}
+	return "", "", fmt.Errorf("invalid syslog address %q. %v", value, SyslogAddressHelpString)
+}
@@ -0,0 +1,37 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// ResourceUsage describes resources used by a command, as reported by the kernel when it exits. It includes resources
+// of its children, which were waited for.
+type ResourceUsage struct {
+	UserTime                   time.Duration
+	SystemTime                 time.Duration
+	MaxRssKb                   int64 // peak resident set size; in totals, the highest peak of all executions
+	MajorFaults                int64
+	VoluntaryContextSwitches   int64
+	InvoluntaryContextSwitches int64
+}
+
+// Add accumulates usage of another execution into running totals.
+func (usage *ResourceUsage) Add(other ResourceUsage) {
+	usage.UserTime += other.UserTime
+	usage.SystemTime += other.SystemTime
+	usage.MaxRssKb = max(usage.MaxRssKb, other.MaxRssKb)
+	usage.MajorFaults += other.MajorFaults
+	usage.VoluntaryContextSwitches += other.VoluntaryContextSwitches
+	usage.InvoluntaryContextSwitches += other.InvoluntaryContextSwitches
+}
+
+func (usage *ResourceUsage) CpuTime() time.Duration {
+	return usage.UserTime + usage.SystemTime
+}
+
+func (usage *ResourceUsage) String() string {
+	return fmt.Sprintf("cpu %v (user %v, sys %v), max rss %v KiB, major faults %v, context switches %v voluntary %v involuntary",
+		usage.CpuTime().Round(time.Millisecond), usage.UserTime.Round(time.Millisecond), usage.SystemTime.Round(time.Millisecond),
+		usage.MaxRssKb, usage.MajorFaults, usage.VoluntaryContextSwitches, usage.InvoluntaryContextSwitches)
+}
@@ -10,4 +10,6 @@ const (
 	RunResponseStatusTaskNotFound       // only for reEncodeRunResponsePacket
 	RunResponseStatusTaskNotDeactivated // only for reEncodeRunResponsePacket
 	RunResponseStatusUnknown
+	RunResponseStatusAmbiguousTask // only for resume and move, sent with candidates
+	RunResponseStatusEmptyFilter   // only for resume with a filter selecting all tasks without All
 )
@@ -7,5 +7,7 @@ const (
 	StopResponseStatusTaskNotFound
 	StopResponseStatusAlreadyStopped
 	StopResponseStatusUnknown
+	StopResponseStatusAmbiguousTask
+	StopResponseStatusEmptyFilter // only for requests with a filter selecting all tasks without All
 )
 
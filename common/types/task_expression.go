@@ -0,0 +1,335 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TaskExpression is a condition on fields of a task, given by the user in filters of list and bulk commands, e.g.
+// `name=pic* && (failures>0 || exit!=0)`. String fields are compared with globs (=, !=) or regular expressions
+// (~, !~), numeric fields with =, !=, <, <=, > and >=. The tag field is a list, it's equal to a glob if any of the tags
+// matches it and not equal if none does. Values containing spaces, parentheses, & or | must be quoted, either with
+// double quotes allowing Go escapes or with single quotes taken literally.
+type TaskExpression struct {
+	root taskExpressionNode
+}
+
+const TaskExpressionHelpString = "Compare fields with =, != (globs or numbers), ~, !~ (regular expressions) and <, <=, >, >= " +
+	"(numbers), combine comparisons with &&, ||, ! and parentheses, e.g. \"name=pic* && failures>0\". " +
+	"Fields: " + taskExpressionFieldNames + "."
+
+// TaskExpressionFields are the values of a task a TaskExpression is evaluated against.
+type TaskExpressionFields struct {
+	Id       int
+	Name     string
+	Display  string // label of the display, e.g. x:0 or h for headless
+	Tags     []string
+	Cmdline  string // arguments joined with spaces
+	Cwd      string
+	Status   string // active or deactivated
+	Reason   string // reason of deactivation, empty for active tasks
+	Runs     int
+	Failures int
+	Exit     int // exit code of the last execution
+}
+
+type taskExpressionField struct {
+	number  func(fields *TaskExpressionFields) int      // nil for string fields
+	strings func(fields *TaskExpressionFields) []string // nil for numeric fields
+}
+
+const taskExpressionFieldNames = "id, name, display, tag, cmdline, cwd, status, reason, runs, failures, exit"
+
+var taskExpressionFields = map[string]taskExpressionField{
+	"id":       {number: func(fields *TaskExpressionFields) int { return fields.Id }},
+	"name":     {strings: func(fields *TaskExpressionFields) []string { return []string{fields.Name} }},
+	"display":  {strings: func(fields *TaskExpressionFields) []string { return []string{fields.Display} }},
+	"tag":      {strings: func(fields *TaskExpressionFields) []string { return fields.Tags }},
+	"cmdline":  {strings: func(fields *TaskExpressionFields) []string { return []string{fields.Cmdline} }},
+	"cwd":      {strings: func(fields *TaskExpressionFields) []string { return []string{fields.Cwd} }},
+	"status":   {strings: func(fields *TaskExpressionFields) []string { return []string{fields.Status} }},
+	"reason":   {strings: func(fields *TaskExpressionFields) []string { return []string{fields.Reason} }},
+	"runs":     {number: func(fields *TaskExpressionFields) int { return fields.Runs }},
+	"failures": {number: func(fields *TaskExpressionFields) int { return fields.Failures }},
+	"exit":     {number: func(fields *TaskExpressionFields) int { return fields.Exit }},
+}
+
+// Longer operators must come first, so "<=" is not parsed as "<" followed by a value starting with "=".
+var taskExpressionOperators = []string{"==", "!=", "<=", ">=", "!~", "=", "<", ">", "~"}
+
+func ParseTaskExpression(value string) (*TaskExpression, error) {
+	parser := taskExpressionParser{input: value}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	parser.skipSpaces()
+	if !parser.atEnd() {
+		return nil, parser.errorf("unexpected %q", parser.rest())
+	}
+	return &TaskExpression{root: root}, nil
+}
+
+func (expression *TaskExpression) Matches(fields *TaskExpressionFields) bool {
+	return expression.root.matches(fields)
+}
+
+type taskExpressionNode interface {
+	matches(fields *TaskExpressionFields) bool
+}
+
+type taskExpressionAnd struct{ left, right taskExpressionNode }
+type taskExpressionOr struct{ left, right taskExpressionNode }
+type taskExpressionNot struct{ operand taskExpressionNode }
+
+func (node *taskExpressionAnd) matches(fields *TaskExpressionFields) bool {
+	return node.left.matches(fields) && node.right.matches(fields)
+}
+
+func (node *taskExpressionOr) matches(fields *TaskExpressionFields) bool {
+	return node.left.matches(fields) || node.right.matches(fields)
+}
+
+func (node *taskExpressionNot) matches(fields *TaskExpressionFields) bool {
+	return !node.operand.matches(fields)
+}
+
+type taskExpressionNumberComparison struct {
+	field    func(fields *TaskExpressionFields) int
+	operator string
+	value    int
+}
+
+func (node *taskExpressionNumberComparison) matches(fields *TaskExpressionFields) bool {
+	value := node.field(fields)
+	switch node.operator {
+	case "=", "==":
+		return value == node.value
+	case "!=":
+		return value != node.value
+	case "<":
+		return value < node.value
+	case "<=":
+		return value <= node.value
+	case ">":
+		return value > node.value
+	case ">=":
+		return value >= node.value
+	default:
+		return false
+	}
+}
+
+// taskExpressionStringComparison is true if any of the values of the field matches the pattern, or if none does when
+// negated. Globs are converted to anchored regular expressions.
+type taskExpressionStringComparison struct {
+	field   func(fields *TaskExpressionFields) []string
+	pattern *regexp.Regexp
+	negate  bool
+}
+
+func (node *taskExpressionStringComparison) matches(fields *TaskExpressionFields) bool {
+	matched := slices.ContainsFunc(node.field(fields), node.pattern.MatchString)
+	return matched != node.negate
+}
+
+type taskExpressionParser struct {
+	input    string
+	position int
+}
+
+func (parser *taskExpressionParser) parseOr() (taskExpressionNode, error) {
+	left, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for parser.consume("||") {
+		right, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &taskExpressionOr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (parser *taskExpressionParser) parseAnd() (taskExpressionNode, error) {
+	left, err := parser.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for parser.consume("&&") {
+		right, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &taskExpressionAnd{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (parser *taskExpressionParser) parseUnary() (taskExpressionNode, error) {
+	switch {
+	case parser.consume("!"):
+		operand, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &taskExpressionNot{operand: operand}, nil
+	case parser.consume("("):
+		node, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.consume(")") {
+			return nil, parser.errorf("expected )")
+		}
+		return node, nil
+	default:
+		return parser.parseComparison()
+	}
+}
+
+func (parser *taskExpressionParser) parseComparison() (taskExpressionNode, error) {
+	parser.skipSpaces()
+	start := parser.position
+	for !parser.atEnd() && (unicode.IsLetter(rune(parser.input[parser.position])) || parser.input[parser.position] == '_') {
+		parser.position++
+	}
+	name := parser.input[start:parser.position]
+	if name == "" {
+		if parser.atEnd() {
+			return nil, parser.errorf("expected a comparison")
+		}
+		return nil, parser.errorf("expected a field name, got %q", parser.rest())
+	}
+	field, found := taskExpressionFields[strings.ToLower(name)]
+	if !found {
+		return nil, fmt.Errorf("unknown field %q, expected one of: %v", name, taskExpressionFieldNames)
+	}
+
+	operator := ""
+	for _, candidate := range taskExpressionOperators {
+		if parser.consume(candidate) {
+			operator = candidate
+			break
+		}
+	}
+	if operator == "" {
+		return nil, parser.errorf("expected an operator after %q", name)
+	}
+
+	value, err := parser.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if field.number != nil {
+		if operator == "~" || operator == "!~" {
+			return nil, fmt.Errorf("field %q is a number, it cannot be matched with %v", name, operator)
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("field %q is a number, but it's compared with %q", name, value)
+		}
+		return &taskExpressionNumberComparison{field: field.number, operator: operator, value: number}, nil
+	}
+
+	var pattern *regexp.Regexp
+	switch operator {
+	case "=", "==", "!=":
+		pattern = compileGlob(value)
+	case "~", "!~":
+		pattern, err = regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %v", value, err)
+		}
+	default:
+		return nil, fmt.Errorf("field %q is a string, it cannot be compared with %v", name, operator)
+	}
+	return &taskExpressionStringComparison{field: field.strings, pattern: pattern, negate: strings.HasPrefix(operator, "!")}, nil
+}
+
+func (parser *taskExpressionParser) parseValue() (string, error) {
+	parser.skipSpaces()
+	if parser.atEnd() {
+		return "", parser.errorf("expected a value")
+	}
+
+	switch parser.input[parser.position] {
+	case '"':
+		quoted, err := strconv.QuotedPrefix(parser.rest())
+		if err != nil {
+			return "", parser.errorf("unterminated string or invalid escape sequence")
+		}
+		parser.position += len(quoted)
+		return strconv.Unquote(quoted)
+	case '\'':
+		end := strings.IndexByte(parser.input[parser.position+1:], '\'')
+		if end < 0 {
+			return "", parser.errorf("unterminated string")
+		}
+		value := parser.input[parser.position+1 : parser.position+1+end]
+		parser.position += end + 2
+		return value, nil
+	default:
+		start := parser.position
+		for !parser.atEnd() && !strings.ContainsRune(" \t\n()&|", rune(parser.input[parser.position])) {
+			parser.position++
+		}
+		if start == parser.position {
+			return "", parser.errorf("expected a value, got %q", parser.rest())
+		}
+		return parser.input[start:parser.position], nil
+	}
+}
+
+// consume skips the token if it's next in the input.
+func (parser *taskExpressionParser) consume(token string) bool {
+	parser.skipSpaces()
+	if strings.HasPrefix(parser.rest(), token) {
+		parser.position += len(token)
+		return true
+	}
+	return false
+}
+
+func (parser *taskExpressionParser) skipSpaces() {
+	for !parser.atEnd() && unicode.IsSpace(rune(parser.input[parser.position])) {
+		parser.position++
+	}
+}
+
+func (parser *taskExpressionParser) atEnd() bool {
+	return parser.position >= len(parser.input)
+}
+
+func (parser *taskExpressionParser) rest() string {
+	return parser.input[parser.position:]
+}
+
+func (parser *taskExpressionParser) errorf(format string, args ...any) error {
+	return fmt.Errorf(format+" at position %v", append(args, parser.position+1)...)
+}
+
+// compileGlob converts a glob, where * matches any characters and ? a single one, to an anchored regular expression.
+func compileGlob(glob string) *regexp.Regexp {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for _, char := range glob {
+		switch char {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	builder.WriteString("$")
+	return regexp.MustCompile(builder.String())
+}
@@ -0,0 +1,211 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTaskExpressionMatches(t *testing.T) {
+	fields := TaskExpressionFields{
+		Id:       7,
+		Name:     "picom",
+		Display:  "x:0",
+		Tags:     []string{"session", "compositor"},
+		Cmdline:  "picom --config /home/user/my config.conf",
+		Cwd:      "/home/user",
+		Status:   "deactivated",
+		Reason:   "stopped by user",
+		Runs:     3,
+		Failures: 2,
+		Exit:     -1,
+	}
+
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		// Numbers
+		{"id=7", true},
+		{"id==7", true},
+		{"id=8", false},
+		{"id!=7", false},
+		{"id!=8", true},
+		{"runs<3", false},
+		{"runs<4", true},
+		{"runs<=3", true},
+		{"runs<=2", false},
+		{"runs>2", true},
+		{"runs>3", false},
+		{"runs>=3", true},
+		{"runs>=4", false},
+		{"failures=2", true},
+		{"exit=-1", true},
+		{"exit<0", true},
+
+		// Strings are compared with anchored globs
+		{"name=picom", true},
+		{"name==picom", true},
+		{"name=pic", false},
+		{"name=icom", false},
+		{"name=pic*", true},
+		{"name=*com", true},
+		{"name=*ico*", true},
+		{"name=pic?m", true},
+		{"name=pic?", false},
+		{"name=*", true},
+		{"name!=picom", false},
+		{"name!=pic", true},
+		{"name!=pic*", false},
+		{"display=x:0", true},
+		{"display=x:*", true},
+		{"display=h", false},
+		{"cwd=/home/*", true},
+		{"cwd=/home", false},
+		{"status=deactivated", true},
+		{"status=active", false},
+		{"NAME=picom", true},
+
+		// Glob characters other than * and ? are taken literally
+		{"name=p.com", false},
+		{"cwd=/home/use[r]", false},
+
+		// Strings are compared with unanchored regular expressions
+		{"name~ico", true},
+		{"name~^ico", false},
+		{"name~^pic.m$", true},
+		{"name!~ico", false},
+		{"name!~^x", true},
+		{"reason~user", true},
+
+		// Tags are equal if any of them matches and not equal if none does
+		{"tag=session", true},
+		{"tag=compositor", true},
+		{"tag=sess", false},
+		{"tag=sess*", true},
+		{"tag!=session", false},
+		{"tag!=other", true},
+		{"tag~^comp", true},
+		{"tag!~^comp", false},
+		{"tag!~^other", true},
+
+		// Quoting
+		{`reason="stopped by user"`, true},
+		{`reason='stopped by user'`, true},
+		{`reason=stopped`, false},
+		{`cmdline="*my config.conf"`, true},
+		{`cmdline='*my config.conf'`, true},
+		{`cmdline="*(x)*"`, false},
+		{`reason="stopped\x20by user"`, true},
+		{`reason='stopped\x20by user'`, false},
+		{`name="pic*"`, true},
+		{`name~'^p.c'`, true},
+		{`name=""`, false},
+		{`reason!=''`, true},
+
+		// Operators and precedence
+		{"name=picom && runs=3", true},
+		{"name=picom && runs=4", false},
+		{"name=other || runs=3", true},
+		{"name=other || runs=4", false},
+		{"name=picom || runs=4 && id=8", true},
+		{"(name=picom || runs=4) && id=8", false},
+		{"runs=4 && id=8 || name=picom", true},
+		{"runs=4 && (id=8 || name=picom)", false},
+		{"!name=picom", false},
+		{"!name=other", true},
+		{"!!name=picom", true},
+		{"!name=other && runs=3", true},
+		{"!(name=other || runs=3)", false},
+		{"!name=picom || runs=3", true},
+		{"!(name=picom && runs=4)", true},
+
+		// Spacing
+		{"  name = picom  ", true},
+		{"name=picom&&runs=3", true},
+		{"(name=picom)||(runs=4)", true},
+		{"! ( name=picom )", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expression, err := ParseTaskExpression(test.expression)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if matches := expression.Matches(&fields); matches != test.expected {
+				t.Fatalf("expected %v, got %v", test.expected, matches)
+			}
+		})
+	}
+}
+
+func TestTaskExpressionMatchesNoTags(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{"tag=*", false},
+		{"tag!=*", true},
+		{"tag~.", false},
+		{"tag!~.", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expression, err := ParseTaskExpression(test.expression)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if matches := expression.Matches(&TaskExpressionFields{}); matches != test.expected {
+				t.Fatalf("expected %v, got %v", test.expected, matches)
+			}
+		})
+	}
+}
+
+func TestParseTaskExpressionErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"", "expected a comparison at position 1"},
+		{"   ", "expected a comparison at position 4"},
+		{"name=picom &&", "expected a comparison at position 14"},
+		{"name=picom ||", "expected a comparison at position 14"},
+		{"!", "expected a comparison at position 2"},
+		{"(name=picom", "expected ) at position 12"},
+		{"((name=picom) && runs=3", "expected ) at position 24"},
+		{"name=picom)", `unexpected ")" at position 11`},
+		{"name=picom runs=3", `unexpected "runs=3" at position 12`},
+		{"name=picom & runs=3", `unexpected "& runs=3" at position 12`},
+		{"=picom", `expected a field name, got "=picom" at position 1`},
+		{"name=a && 3=b", `expected a field name, got "3=b" at position 11`},
+		{"name", `expected an operator after "name" at position 5`},
+		{"name picom", `expected an operator after "name" at position 6`},
+		{"name=", "expected a value at position 6"},
+		{"name=)", `expected a value, got ")" at position 6`},
+		{`name="picom`, "unterminated string or invalid escape sequence at position 6"},
+		{`name='picom`, "unterminated string at position 6"},
+		{`name="\q"`, "unterminated string or invalid escape sequence at position 6"},
+		{"nme=picom", `unknown field "nme"`},
+		{"runs=three", `field "runs" is a number, but it's compared with "three"`},
+		{"runs~3", `field "runs" is a number, it cannot be matched with ~`},
+		{"exit!~0", `field "exit" is a number, it cannot be matched with !~`},
+		{"name<picom", `field "name" is a string, it cannot be compared with <`},
+		{"tag>=a", `field "tag" is a string, it cannot be compared with >=`},
+		{"name~(", `expected a value, got "(" at position 6`},
+		{"name~'('", `invalid regular expression "("`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expression, err := ParseTaskExpression(test.expression)
+			if err == nil {
+				t.Fatalf("expected an error, got %+v", expression)
+			}
+			if !strings.Contains(err.Error(), test.expected) {
+				t.Fatalf("expected error containing %q, got %q", test.expected, err.Error())
+			}
+		})
+	}
+}
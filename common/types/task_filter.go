@@ -7,6 +7,7 @@ type TaskFilter struct {
 	AnyNameFilter      []string
 	DisplayFilter      DisplaySelection
 	AllTagsFilter      []string
+	WhereFilter        string // TaskExpression, validated by frontends
 	IncludeActive      bool
 	IncludeDeactivated bool
 
@@ -14,6 +15,7 @@ type TaskFilter struct {
 	HasAnyNameFilter bool `json:"-"`
 	HasDisplayFilter bool `json:"-"`
 	HasAllTagsFilter bool `json:"-"`
+	HasWhereFilter   bool `json:"-"`
 	HasAnyFilter     bool `json:"-"`
 }
 
@@ -22,5 +24,6 @@ func (filter *TaskFilter) Derive() {
 	filter.HasAnyNameFilter = len(filter.AnyNameFilter) > 0
 	filter.HasDisplayFilter = filter.DisplayFilter.Type != DisplaySelectionTypeNone
 	filter.HasAllTagsFilter = len(filter.AllTagsFilter) > 0
-	filter.HasAnyFilter = filter.HasIdFilter || filter.HasAnyNameFilter || filter.HasDisplayFilter || filter.HasAllTagsFilter
+	filter.HasWhereFilter = filter.WhereFilter != ""
+	filter.HasAnyFilter = filter.HasIdFilter || filter.HasAnyNameFilter || filter.HasDisplayFilter || filter.HasAllTagsFilter || filter.HasWhereFilter
 }
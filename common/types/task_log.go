@@ -0,0 +1,146 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TaskLogFormat byte
+
+const (
+	TaskLogFormatText TaskLogFormat = iota
+	TaskLogFormatJson
+)
+
+const TaskLogFormatStrValues = "text, json"
+
+func ParseTaskLogFormat(value string) (TaskLogFormat, error) {
+	switch value {
+	case "", "text":
+		return TaskLogFormatText, nil
+	case "json":
+		return TaskLogFormatJson, nil
+	default:
+		return TaskLogFormatText, fmt.Errorf("invalid task log format %q, expected one of: %v", value, TaskLogFormatStrValues)
+	}
+}
+
+func (format TaskLogFormat) String() string {
+	switch format {
+	case TaskLogFormatText:
+		return "text"
+	case TaskLogFormatJson:
+		return "json"
+	default:
+		return "invalid"
+	}
+}
+
+func (format TaskLogFormat) MarshalJSON() ([]byte, error) {
+	return json.Marshal(format.String())
+}
+
+func (format *TaskLogFormat) UnmarshalJSON(data []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*format, err = ParseTaskLogFormat(s)
+	return err
+}
+
+// TaskLogStream denotes the origin of a line in the task log.
+type TaskLogStream string
+
+const (
+	TaskLogStreamStdout     TaskLogStream = "out"
+	TaskLogStreamStderr     TaskLogStream = "err"
+	TaskLogStreamDiagnostic TaskLogStream = "sys" // messages generated by Spieven itself
+)
+
+// TaskLogRecord is a single line of the task log. Depending on the TaskLogFormat of the task, it's saved either as
+// a human-readable line or as a json object.
+type TaskLogRecord struct {
+	Time      time.Time
+	Stream    TaskLogStream
+	Execution int
+	Line      string
+}
+
+const taskLogTimeFormat = "2006-01-02 15:04:05.000"
+
+// Format serializes the record to a line of the task log, including a trailing newline.
+func (record *TaskLogRecord) Format(format TaskLogFormat) string {
+	if format == TaskLogFormatJson {
+		serialized, err := json.Marshal(record)
+		if err == nil {
+			return string(serialized) + "\n"
+		}
+	}
+
+	return fmt.Sprintf("%v [%v] #%v %v\n", record.Time.Format(taskLogTimeFormat), record.Stream, record.Execution, record.Line)
+}
+
+// ParseTaskLogRecord is the reverse of Format. It accepts a line in any of the formats, without a trailing newline.
+func ParseTaskLogRecord(line string) (record TaskLogRecord, err error) {
+	if strings.HasPrefix(line, "{") {
+		err = json.Unmarshal([]byte(line), &record)
+		return
+	}
+
+	invalidLineErr := errors.New("invalid task log line")
+
+	// Timestamp contains a space, so it has to be cut separately.
+	if len(line) < len(taskLogTimeFormat)+1 {
+		return record, invalidLineErr
+	}
+	record.Time, err = time.ParseInLocation(taskLogTimeFormat, line[:len(taskLogTimeFormat)], time.Local)
+	if err != nil {
+		return record, invalidLineErr
+	}
+	line = line[len(taskLogTimeFormat)+1:]
+
+	stream, line, found := strings.Cut(line, " ")
+	if !found || !strings.HasPrefix(stream, "[") || !strings.HasSuffix(stream, "]") {
+		return record, invalidLineErr
+	}
+	record.Stream = TaskLogStream(stream[1 : len(stream)-1])
+
+	execution, line, found := strings.Cut(line, " ")
+	if !found || !strings.HasPrefix(execution, "#") {
+		return record, invalidLineErr
+	}
+	record.Execution, err = strconv.Atoi(execution[1:])
+	if err != nil {
+		return record, invalidLineErr
+	}
+
+	record.Line = line
+	return record, nil
+}
+
+const (
+	ExecutionSelectionAll  = -1
+	ExecutionSelectionLast = -2
+)
+
+const ExecutionSelectionHelpString = "Use \"all\", \"last\" or an execution number."
+
+func ParseExecutionSelection(value string) (int, error) {
+	switch value {
+	case "", "all":
+		return ExecutionSelectionAll, nil
+	case "last":
+		return ExecutionSelectionLast, nil
+	default:
+		execution, err := strconv.Atoi(value)
+		if err != nil || execution < 0 {
+			return 0, fmt.Errorf("invalid execution %q. %v", value, ExecutionSelectionHelpString)
+		}
+		return execution, nil
+	}
+}
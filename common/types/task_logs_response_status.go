@@ -0,0 +1,12 @@
+package types
+
+type TaskLogsResponseStatus byte
+
+const (
+	TaskLogsResponseStatusSuccess TaskLogsResponseStatus = iota
+	TaskLogsResponseStatusTaskNotFound
+	TaskLogsResponseStatusInvalidRequest
+	TaskLogsResponseStatusReadError
+	TaskLogsResponseStatusUnknown
+	TaskLogsResponseStatusAmbiguousTask
+)
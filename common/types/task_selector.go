@@ -0,0 +1,147 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TaskSelector selects a single task in commands targeting tasks. It's given as a task id, a friendly name, a friendly
+// name with a display, e.g. picom@x:0, or a tag, e.g. tag:foo. Names which would be taken for one of the other forms,
+// such as 123, tag:foo or user@host, are given with a prefix, e.g. name:user@host.
+type TaskSelector struct {
+	Id         int // only valid if HasId
+	HasId      bool
+	Name       string
+	Display    DisplaySelection // only valid if HasDisplay
+	HasDisplay bool
+	Tag        string
+}
+
+const TaskSelectorHelpString = "Use a task ID, a friendly name, a name with a display, e.g. \"picom@x:0\", or a tag, e.g. \"tag:foo\". " +
+	"Names looking like one of the other forms can be given with a prefix, e.g. \"name:user@host\"."
+
+const (
+	taskSelectorTagPrefix  = "tag:"
+	taskSelectorNamePrefix = "name:"
+)
+
+// ParseTaskSelector parses a selector given by the user. Errors only tell what is wrong, not that the selector is
+// invalid, so callers should add that. Displays without names, such as picom@x, are completed from the environment,
+// so selectors should be parsed by the frontend and sent to the backend in the canonical form returned by String.
+func ParseTaskSelector(value string) (TaskSelector, error) {
+	var selector TaskSelector
+	if value == "" {
+		return selector, errors.New("selector must not be empty")
+	}
+
+	if id, err := strconv.Atoi(value); err == nil {
+		selector.Id = id
+		selector.HasId = true
+		return selector, nil
+	}
+
+	// The whole rest is the name, so a display cannot be given along with the prefix
+	if name, found := strings.CutPrefix(value, taskSelectorNamePrefix); found {
+		if name == "" {
+			return selector, errors.New("name must not be empty")
+		}
+		selector.Name = name
+		return selector, nil
+	}
+
+	if tag, found := strings.CutPrefix(value, taskSelectorTagPrefix); found {
+		if tag == "" {
+			return selector, errors.New("tag must not be empty")
+		}
+		selector.Tag = tag
+		return selector, nil
+	}
+
+	name, display, hasDisplay := cutLast(value, "@")
+	if hasDisplay {
+		if name == "" {
+			return selector, errors.New("name must not be empty")
+		}
+		if err := selector.Display.ParseDisplaySelection(display, false); err != nil {
+			return selector, err
+		}
+		selector.HasDisplay = true
+	}
+	selector.Name = name
+	return selector, nil
+}
+
+func (selector TaskSelector) String() string {
+	switch {
+	case selector.HasId:
+		return strconv.Itoa(selector.Id)
+	case selector.Tag != "":
+		return taskSelectorTagPrefix + selector.Tag
+	case selector.HasDisplay:
+		return fmt.Sprintf("%v@%v", selector.Name, selector.Display.ComputeDisplayLabel())
+	case needsNamePrefix(selector.Name):
+		return taskSelectorNamePrefix + selector.Name
+	default:
+		return selector.Name
+	}
+}
+
+// needsNamePrefix tells whether a friendly name alone would be parsed as something else than the name.
+func needsNamePrefix(name string) bool {
+	if _, err := strconv.Atoi(name); err == nil {
+		return true
+	}
+	return strings.HasPrefix(name, taskSelectorTagPrefix) || strings.HasPrefix(name, taskSelectorNamePrefix) || strings.Contains(name, "@")
+}
+
+func (selector TaskSelector) Matches(id int, friendlyName string, display DisplaySelection, tags []string) bool {
+	switch {
+	case selector.HasId:
+		return id == selector.Id
+	case selector.Tag != "":
+		for _, tag := range tags {
+			if tag == selector.Tag {
+				return true
+			}
+		}
+		return false
+	default:
+		return friendlyName == selector.Name && (!selector.HasDisplay || display == selector.Display)
+	}
+}
+
+func cutLast(value string, separator string) (before string, after string, found bool) {
+	index := strings.LastIndex(value, separator)
+	if index < 0 {
+		return value, "", false
+	}
+	return value[:index], value[index+len(separator):], true
+}
+
+// TaskCandidate describes one of the tasks matched by an ambiguous TaskSelector.
+type TaskCandidate struct {
+	Id            int
+	FriendlyName  string
+	Display       DisplaySelection
+	IsDeactivated bool
+}
+
+func (candidate TaskCandidate) String() string {
+	state := "active"
+	if candidate.IsDeactivated {
+		state = "deactivated"
+	}
+	return fmt.Sprintf("%v (%v@%v, %v)", candidate.Id, candidate.FriendlyName, candidate.Display.ComputeDisplayLabel(), state)
+}
+
+// FormatTaskCandidates describes an ambiguous selector, so the user can pick one of the candidates.
+func FormatTaskCandidates(selector string, candidates []TaskCandidate) string {
+	descriptions := make([]string, len(candidates))
+	for index, candidate := range candidates {
+		descriptions[index] = candidate.String()
+	}
+	return fmt.Sprintf("task selector %q is ambiguous, it matches tasks %v. Select one of them by ID or by name@display",
+		selector, strings.Join(descriptions, ", "))
+}
@@ -0,0 +1,154 @@
+package types
+
+import (
+	"testing"
+)
+
+func TestParseTaskSelector(t *testing.T) {
+	t.Setenv("DISPLAY", ":5")
+
+	xorg := func(name string) DisplaySelection {
+		return DisplaySelection{Type: DisplaySelectionTypeXorg, Name: name}
+	}
+	headless := DisplaySelection{Type: DisplaySelectionTypeHeadless}
+
+	tests := []struct {
+		value     string
+		expected  TaskSelector
+		canonical string // result of String, if it differs from the value
+	}{
+		{value: "12", expected: TaskSelector{Id: 12, HasId: true}},
+		{value: "0", expected: TaskSelector{Id: 0, HasId: true}},
+		{value: "picom", expected: TaskSelector{Name: "picom"}},
+		{value: "picom@x:0", expected: TaskSelector{Name: "picom", Display: xorg(":0"), HasDisplay: true}},
+		{value: "picom@x", expected: TaskSelector{Name: "picom", Display: xorg(":5"), HasDisplay: true}, canonical: "picom@x:5"},
+		{value: "picom@h", expected: TaskSelector{Name: "picom", Display: headless, HasDisplay: true}},
+		{value: "a@b@x:0", expected: TaskSelector{Name: "a@b", Display: xorg(":0"), HasDisplay: true}},
+		{value: "123@x:0", expected: TaskSelector{Name: "123", Display: xorg(":0"), HasDisplay: true}},
+		{value: "tag:foo", expected: TaskSelector{Tag: "foo"}},
+		{value: "tag:foo@x:0", expected: TaskSelector{Tag: "foo@x:0"}},
+		{value: "name:picom", expected: TaskSelector{Name: "picom"}, canonical: "picom"},
+		{value: "name:123", expected: TaskSelector{Name: "123"}},
+		{value: "name:tag:foo", expected: TaskSelector{Name: "tag:foo"}},
+		{value: "name:name:foo", expected: TaskSelector{Name: "name:foo"}},
+		{value: "name:user@host", expected: TaskSelector{Name: "user@host"}},
+		{value: "name:picom@x:0", expected: TaskSelector{Name: "picom@x:0"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			selector, err := ParseTaskSelector(test.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if selector != test.expected {
+				t.Fatalf("expected %+v, got %+v", test.expected, selector)
+			}
+
+			canonical := test.canonical
+			if canonical == "" {
+				canonical = test.value
+			}
+			if selector.String() != canonical {
+				t.Fatalf("expected canonical form %q, got %q", canonical, selector.String())
+			}
+
+			reparsed, err := ParseTaskSelector(selector.String())
+			if err != nil {
+				t.Fatalf("unexpected error parsing canonical form: %v", err)
+			}
+			if reparsed != selector {
+				t.Fatalf("canonical form %q parsed to %+v instead of %+v", selector.String(), reparsed, selector)
+			}
+		})
+	}
+}
+
+func TestParseTaskSelectorErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"tag:",
+		"name:",
+		"@x:0",
+		"user@example.com",
+		"picom@q:0",
+	}
+
+	for _, value := range tests {
+		t.Run(value, func(t *testing.T) {
+			if selector, err := ParseTaskSelector(value); err == nil {
+				t.Fatalf("expected an error, got %+v", selector)
+			}
+		})
+	}
+}
+
+func TestTaskSelectorStringEscapesNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "picom", expected: "picom"},
+		{name: "picom2", expected: "picom2"},
+		{name: "123", expected: "name:123"},
+		{name: "-1", expected: "name:-1"},
+		{name: "tag:foo", expected: "name:tag:foo"},
+		{name: "name:foo", expected: "name:name:foo"},
+		{name: "user@host", expected: "name:user@host"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selector := TaskSelector{Name: test.name}
+			if selector.String() != test.expected {
+				t.Fatalf("expected %q, got %q", test.expected, selector.String())
+			}
+
+			reparsed, err := ParseTaskSelector(selector.String())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if reparsed != selector {
+				t.Fatalf("expected %+v, got %+v", selector, reparsed)
+			}
+		})
+	}
+}
+
+func TestTaskSelectorMatches(t *testing.T) {
+	xorg0 := DisplaySelection{Type: DisplaySelectionTypeXorg, Name: ":0"}
+	xorg1 := DisplaySelection{Type: DisplaySelectionTypeXorg, Name: ":1"}
+
+	tests := []struct {
+		selector     string
+		id           int
+		friendlyName string
+		display      DisplaySelection
+		tags         []string
+		expected     bool
+	}{
+		{selector: "3", id: 3, friendlyName: "picom", display: xorg0, expected: true},
+		{selector: "3", id: 4, friendlyName: "3", display: xorg0, expected: false},
+		{selector: "name:3", id: 4, friendlyName: "3", display: xorg0, expected: true},
+		{selector: "picom", id: 1, friendlyName: "picom", display: xorg1, expected: true},
+		{selector: "picom", id: 1, friendlyName: "picom2", display: xorg0, expected: false},
+		{selector: "picom@x:0", id: 1, friendlyName: "picom", display: xorg0, expected: true},
+		{selector: "picom@x:0", id: 1, friendlyName: "picom", display: xorg1, expected: false},
+		{selector: "tag:foo", id: 1, friendlyName: "picom", tags: []string{"bar", "foo"}, expected: true},
+		{selector: "tag:foo", id: 1, friendlyName: "tag:foo", tags: []string{"bar"}, expected: false},
+		{selector: "name:tag:foo", id: 1, friendlyName: "tag:foo", tags: []string{"foo"}, expected: true},
+		{selector: "name:user@host", id: 1, friendlyName: "user@host", display: xorg0, expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			selector, err := ParseTaskSelector(test.selector)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if matches := selector.Matches(test.id, test.friendlyName, test.display, test.tags); matches != test.expected {
+				t.Fatalf("expected %v, got %v", test.expected, matches)
+			}
+		})
+	}
+}
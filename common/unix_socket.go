@@ -0,0 +1,45 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// GetUnixSocketPath returns a path of the unix socket used by a backend. Port is a part of the name, so backends of
+// different build flavours or started with a custom port do not collide, even though no TCP port is involved.
+func GetUnixSocketPath(port string) (string, error) {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		// The fallback has a predictable name in a world-writable directory, so another user could create it first
+		// to capture the socket. Only use it, if it's really ours and nobody else can access it.
+		runtimeDir = filepath.Join(os.TempDir(), fmt.Sprintf("spieven-runtime-%d", os.Getuid()))
+		if err := os.MkdirAll(runtimeDir, 0700); err != nil {
+			return "", err
+		}
+		if err := checkPrivateDirectory(runtimeDir); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(runtimeDir, "spieven", port+".sock"), nil
+}
+
+// checkPrivateDirectory verifies a directory is not a symlink, is owned by the current user and has mode 0700.
+func checkPrivateDirectory(directory string) error {
+	info, err := os.Lstat(directory)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%v is not a directory", directory)
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%v is not owned by the current user", directory)
+	}
+	if info.Mode().Perm() != 0700 {
+		return fmt.Errorf("%v must have mode 0700, but it has %04o", directory, info.Mode().Perm())
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package frontend
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"spieven/common"
+	"time"
+)
+
+const authCertValidity = time.Hour * 24 * 365 * 10
+
+type authCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPem []byte
+	keyPem  []byte
+}
+
+// createAuthCertificate creates a certificate signed by the parent. If parent is nil, a self-signed CA is created.
+func createAuthCertificate(template *x509.Certificate, parent *authCertificate) (*authCertificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	template.SerialNumber = serialNumber
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(authCertValidity)
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(certDer)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authCertificate{
+		cert:    cert,
+		key:     key,
+		certPem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDer}),
+		keyPem:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	}, nil
+}
+
+// CmdAuthInit creates a CA, certificates for the backend and for a client, a token and a client config file. The
+// backend certificate is valid for this machine's hostname, localhost and given additional hosts.
+func CmdAuthInit(authDir string, hosts []string, force bool) error {
+	if common.FileExists(filepath.Join(authDir, common.AuthCaCertFile)) && !force {
+		return fmt.Errorf("credentials already exist in %v. Use --force to overwrite them", authDir)
+	}
+	if err := os.MkdirAll(authDir, 0700); err != nil {
+		return err
+	}
+
+	ca, err := createAuthCertificate(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Spieven CA"},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	serverTemplate := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "Spieven backend"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		hosts = append(hosts, hostname)
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			serverTemplate.IPAddresses = append(serverTemplate.IPAddresses, ip)
+		} else {
+			serverTemplate.DNSNames = append(serverTemplate.DNSNames, host)
+		}
+	}
+	server, err := createAuthCertificate(serverTemplate, ca)
+	if err != nil {
+		return err
+	}
+
+	client, err := createAuthCertificate(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: "Spieven frontend"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+	if err != nil {
+		return err
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	clientConfig, err := json.MarshalIndent(common.ClientConfig{
+		CaCertFile:     common.AuthCaCertFile,
+		ClientCertFile: common.AuthClientCertFile,
+		ClientKeyFile:  common.AuthClientKeyFile,
+		Token:          token,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{common.AuthCaCertFile, ca.certPem},
+		{common.AuthCaKeyFile, ca.keyPem},
+		{common.AuthServerCertFile, server.certPem},
+		{common.AuthServerKeyFile, server.keyPem},
+		{common.AuthClientCertFile, client.certPem},
+		{common.AuthClientKeyFile, client.keyPem},
+		{common.AuthTokenFile, []byte(token + "\n")},
+		{common.AuthClientConfig, append(clientConfig, '\n')},
+	}
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(authDir, file.name), file.content, 0600); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Credentials written to %v\n", authDir)
+	fmt.Printf("Run \"spieven serve --remote\" on this machine. To connect from another machine, copy %v, %v, %v and %v\n",
+		common.AuthClientConfig, common.AuthCaCertFile, common.AuthClientCertFile, common.AuthClientKeyFile)
+	fmt.Printf("to its %v directory or pass the config with --client-config.\n", authDir)
+	return nil
+}
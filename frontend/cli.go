@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"spieven/common"
+	"spieven/common/packet"
 	"spieven/common/types"
 	ftypes "spieven/frontend/types"
 	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -14,29 +17,183 @@ import (
 type CommonFlags struct {
 	serverAddress string
 	serverPort    int
+	clientConfig  string
 }
 
+// taskArgHelpString describes the TASK argument of commands targeting a single task.
+const taskArgHelpString = "TASK can be a task ID, a friendly name, a name with a display, e.g. picom@x:0, or a tag, e.g. tag:foo. " +
+	"Names looking like one of the other forms, e.g. containing @, can be given with a prefix, e.g. name:user@host. " +
+	"Names and tags must select a single task, ignoring older runs of the same task."
+
 func AddCommonFlags(cmd *cobra.Command, flags *CommonFlags) {
-	cmd.Flags().StringVar(&flags.serverAddress, "server-address", "", "Server address to connect to (default: localhost)")
+	cmd.Flags().StringVar(&flags.serverAddress, "server-address", "", "Server address to connect to over TCP, e.g. localhost for a backend started with --tcp, or unix://PATH for a socket created with serve --socket (default: the unix socket of a local backend)")
 	cmd.Flags().IntVar(&flags.serverPort, "server-port", 0, "Server port to connect to (default: build-specific, 0 means default)")
+	cmd.Flags().StringVar(&flags.clientConfig, "client-config", "", "Client config used to authenticate to a remote backend (default: client.json in the auth directory, if it exists)")
+}
+
+// bulkTaskFilterFlags select tasks of commands, which accept either a single TASK or a filter.
+type bulkTaskFilterFlags struct {
+	idFilter      int
+	anyNameFilter []string
+	display       string
+	tags          []string
+	where         string
+	status        string
+	all           bool
+}
+
+func addBulkTaskFilterFlags(cmd *cobra.Command, flags *bulkTaskFilterFlags) {
+	cmd.Flags().IntVarP(&flags.idFilter, "id", "i", math.MaxInt, "Select tasks by id")
+	cmd.Flags().StringSliceVarP(&flags.anyNameFilter, "names", "n", []string{}, "Select tasks with any of given friendly names (comma separated)")
+	cmd.Flags().StringVarP(&flags.display, "display", "p", "", "Select tasks running on a display. "+types.DisplaySelectionHelpString)
+	cmd.Flags().StringSliceVarP(&flags.tags, "tags", "t", []string{}, "Select tasks having all of given tags (comma separated)")
+	cmd.Flags().StringVar(&flags.where, "where", "", "Select tasks matching an expression. "+types.TaskExpressionHelpString)
+	cmd.Flags().StringVarP(&flags.status, "status", "s", "", "Select tasks by status. One of "+ftypes.TaskStatusFilterStrValues+" (default: all)")
+	cmd.Flags().BoolVar(&flags.all, "all", false, "Select all tasks. Required when no other filter than --status is given")
+}
+
+// parseBulkTaskFilter returns a filter created from the flags, or nil if the command targets a single TASK given in
+// args instead. A status alone would select nearly all tasks, so it must be combined with another filter or --all.
+func parseBulkTaskFilter(args []string, flags *bulkTaskFilterFlags) (*types.TaskFilter, error) {
+	taskStatusFilter, err := ftypes.ParseTaskStatusFilter(flags.status)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := types.TaskFilter{
+		IdFilter:           flags.idFilter,
+		AnyNameFilter:      flags.anyNameFilter,
+		AllTagsFilter:      flags.tags,
+		WhereFilter:        flags.where,
+		IncludeActive:      taskStatusFilter == ftypes.TaskStatusFilterAll || taskStatusFilter == ftypes.TaskStatusFilterActive,
+		IncludeDeactivated: taskStatusFilter == ftypes.TaskStatusFilterAll || taskStatusFilter == ftypes.TaskStatusFilterInactive,
+	}
+	if err := filter.DisplayFilter.ParseDisplaySelection(flags.display, true); err != nil {
+		return nil, err
+	}
+	if err := validateWhereFilter(flags.where); err != nil {
+		return nil, err
+	}
+	filter.Derive()
+	hasFilter := filter.HasAnyFilter || flags.status != "" || flags.all
+
+	switch {
+	case len(args) == 1 && hasFilter:
+		return nil, errors.New("TASK cannot be used together with filters")
+	case flags.all && filter.HasAnyFilter:
+		return nil, errors.New("--all cannot be used together with other filters than --status")
+	case len(args) == 0 && !filter.HasAnyFilter && !flags.all:
+		if flags.status != "" {
+			return nil, errors.New("--status must be used together with another filter or --all")
+		}
+		return nil, errors.New("either TASK, a filter or --all must be specified")
+	case len(args) == 1:
+		return nil, nil
+	default:
+		return &filter, nil
+	}
+}
+
+// validateWhereFilter checks the expression before connecting, so errors are reported with the flag they come from.
+func validateWhereFilter(where string) error {
+	if where == "" {
+		return nil
+	}
+	if _, err := types.ParseTaskExpression(where); err != nil {
+		return fmt.Errorf("invalid --where expression: %v", err)
+	}
+	return nil
 }
 
 func CreateCliCommands() (commands []*cobra.Command) {
 	{
-		var commonFlags CommonFlags
+		var (
+			severity    string
+			kind        string
+			taskId      int
+			since       string
+			jsonOutput  bool
+			follow      bool
+			commonFlags CommonFlags
+		)
 		cmd := &cobra.Command{
-			Use:   "log",
+			Use:   "log [OPTIONS...]",
 			Short: "Display a backend log",
 			Args:  cobra.ExactArgs(0),
 			RunE: func(cmd *cobra.Command, args []string) error {
-				connection, err := ConnectToBackend(false, commonFlags.serverAddress, commonFlags.serverPort)
+				request := packet.LogRequestBody{Follow: follow}
+
+				var err error
+				if request.MinSeverity, err = types.ParseBackendMessageSeverity(severity); err != nil {
+					return err
+				}
+				if kind != "" {
+					if request.Kind, err = types.ParseBackendMessageKind(kind); err != nil {
+						return err
+					}
+				}
+				if cmd.Flags().Changed("task") {
+					request.TaskId = &taskId
+				}
+				if request.Since, err = ftypes.ParseTimeSelection(since); err != nil {
+					return err
+				}
+
+				backendClient, err := connectToBackend(cmd.Context(), false, &commonFlags)
 				if err == nil {
-					defer connection.Close()
-					err = CmdLog(connection)
+					defer backendClient.Close()
+					err = CmdLog(cmd.Context(), backendClient, request, jsonOutput)
 				}
 				return err
 			},
 		}
+		cmd.Flags().StringVarP(&severity, "severity", "s", "info", "Display only messages of given severity or higher. One of "+types.BackendMessageSeverityStrValues)
+		cmd.Flags().StringVarP(&kind, "kind", "k", "", "Display only messages of given kind. One of "+types.BackendMessageKindStrValues)
+		cmd.Flags().IntVarP(&taskId, "task", "i", 0, "Display only messages concerning a task with given id")
+		cmd.Flags().StringVar(&since, "since", "", "Display only messages added after given time. "+ftypes.TimeSelectionHelpString)
+		cmd.Flags().BoolVar(&jsonOutput, "json", false, "Display messages as json objects, one per line")
+		cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Keep displaying new messages as they are added")
+		AddCommonFlags(cmd, &commonFlags)
+		commands = append(commands, cmd)
+	}
+
+	{
+		var (
+			filters     []string
+			taskId      int
+			jsonOutput  bool
+			commonFlags CommonFlags
+		)
+		cmd := &cobra.Command{
+			Use:   "events [OPTIONS...]",
+			Short: "Display events, such as tasks starting and exiting, as they happen",
+			Long: "Display events, such as tasks starting and exiting or displays appearing and vanishing, as they happen. " +
+				"The command runs until interrupted.",
+			Args: cobra.ExactArgs(0),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var eventFilter types.EventFilter
+				for _, filter := range filters {
+					eventTypes, err := types.ParseEventTypes(filter)
+					if err != nil {
+						return err
+					}
+					eventFilter.Types = append(eventFilter.Types, eventTypes...)
+				}
+				if cmd.Flags().Changed("task") {
+					eventFilter.TaskId = &taskId
+				}
+
+				backendClient, err := connectToBackend(cmd.Context(), false, &commonFlags)
+				if err == nil {
+					defer backendClient.Close()
+					err = CmdEvents(cmd.Context(), backendClient, eventFilter, jsonOutput)
+				}
+				return err
+			},
+		}
+		cmd.Flags().StringSliceVarP(&filters, "filter", "f", []string{}, "Display only events of given types. Can be a category (task, display) or a type, e.g. task-failed")
+		cmd.Flags().IntVarP(&taskId, "task", "i", 0, "Display only events concerning a task with given id")
+		cmd.Flags().BoolVar(&jsonOutput, "json", false, "Display events as json objects, one per line")
 		AddCommonFlags(cmd, &commonFlags)
 		commands = append(commands, cmd)
 	}
@@ -50,6 +207,9 @@ func CreateCliCommands() (commands []*cobra.Command) {
 			status        string
 			uniqueNames   bool
 			tags          []string
+			where         string
+			columns       []string
+			sortBy        string
 			commonFlags   CommonFlags
 		)
 		cmd := &cobra.Command{
@@ -57,10 +217,13 @@ func CreateCliCommands() (commands []*cobra.Command) {
 			Short: "Display a list of running tasks",
 			Args:  cobra.ExactArgs(0),
 			RunE: func(cmd *cobra.Command, args []string) error {
-				listFormat, err := ftypes.ParseListFormat(format)
+				listFormat, template, err := ftypes.ParseListFormat(format)
 				if err != nil {
 					return err
 				}
+				if cmd.Flags().Changed("columns") && !listFormat.HasColumns() {
+					return errors.New("columns can only be selected in the default and csv formats")
+				}
 
 				var taskStatusFilter ftypes.TaskStatusFilter
 				taskStatusFilter, err = ftypes.ParseTaskStatusFilter(status)
@@ -72,17 +235,21 @@ func CreateCliCommands() (commands []*cobra.Command) {
 					IdFilter:           idFilter,
 					AnyNameFilter:      anyNameFilter,
 					AllTagsFilter:      tags,
+					WhereFilter:        where,
 					IncludeActive:      taskStatusFilter == ftypes.TaskStatusFilterAll || taskStatusFilter == ftypes.TaskStatusFilterActive,
 					IncludeDeactivated: taskStatusFilter == ftypes.TaskStatusFilterAll || taskStatusFilter == ftypes.TaskStatusFilterInactive,
 				}
 				if err := filter.DisplayFilter.ParseDisplaySelection(display, true); err != nil {
 					return err
 				}
+				if err := validateWhereFilter(where); err != nil {
+					return err
+				}
 
-				connection, err := ConnectToBackend(false, commonFlags.serverAddress, commonFlags.serverPort)
+				backendClient, err := connectToBackend(cmd.Context(), false, &commonFlags)
 				if err == nil {
-					defer connection.Close()
-					err = CmdList(connection, filter, listFormat, uniqueNames)
+					defer backendClient.Close()
+					err = CmdList(cmd.Context(), backendClient, filter, listFormat, template, uniqueNames, columns, sortBy)
 				}
 				return err
 			},
@@ -91,9 +258,73 @@ func CreateCliCommands() (commands []*cobra.Command) {
 		cmd.Flags().StringSliceVarP(&anyNameFilter, "names", "n", []string{}, "Filter tasks by friendly names. Multiple names can be specified (comma separated) to allow multiple results")
 		cmd.Flags().StringVarP(&display, "display", "p", "", "Filter tasks by display. "+types.DisplaySelectionHelpString)
 		cmd.Flags().StringSliceVarP(&tags, "tags", "t", []string{}, "Filter tasks by tags. Multiple tags can be specified (comma separated) to require multiple tags to be present")
+		cmd.Flags().StringVar(&where, "where", "", "Filter tasks by an expression. "+types.TaskExpressionHelpString)
 		cmd.Flags().StringVarP(&status, "status", "s", "all", "Task status filter. One of "+ftypes.TaskStatusFilterStrValues)
 		cmd.Flags().BoolVarP(&uniqueNames, "unique-names", "u", false, "If multiple tasks with the same name are found, select the one with most recent id")
-		cmd.Flags().StringVarP(&format, "format", "f", "default", "Output format: default, detailed or json.")
+		cmd.Flags().StringVarP(&format, "format", "f", "default", "Output format: "+ftypes.ListFormatStrValues+". "+
+			"Templates use Go text/template syntax with fields of json output, e.g. template='{{.Id}} {{.FriendlyName}} {{join .Tags \",\"}}'")
+		cmd.Flags().StringSliceVarP(&columns, "columns", "c", defaultListColumns, "Columns of the default and csv formats (comma separated). Any of "+listColumnNames)
+		cmd.Flags().StringVar(&sortBy, "sort-by", "", "Sort tasks by a column, optionally followed by :asc or :desc, e.g. uptime:desc")
+		cmd.RegisterFlagCompletionFunc("format", completeListFormat)
+		AddCommonFlags(cmd, &commonFlags)
+		commands = append(commands, cmd)
+	}
+
+	{
+		var (
+			anyNameFilter []string
+			display       string
+			tags          []string
+			where         string
+			status        string
+			interval      time.Duration
+			commonFlags   CommonFlags
+		)
+		cmd := &cobra.Command{
+			Use:   "top [OPTIONS...]",
+			Short: "Display a live view of tasks with the log of the selected one",
+			Long: "Display a live view of tasks with the log of the selected one. Tasks can be selected with arrow keys or " +
+				"j/k and stopped (s), resumed (r), refreshed (f) or peeked (p). Filters can be changed with t (tag), " +
+				"d (display) and a (show deactivated tasks). Press q to quit.",
+			Args: cobra.ExactArgs(0),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				taskStatusFilter, err := ftypes.ParseTaskStatusFilter(status)
+				if err != nil {
+					return err
+				}
+				if interval <= 0 {
+					return errors.New("interval must be positive")
+				}
+
+				filter := types.TaskFilter{
+					IdFilter:           math.MaxInt,
+					AnyNameFilter:      anyNameFilter,
+					AllTagsFilter:      tags,
+					WhereFilter:        where,
+					IncludeActive:      taskStatusFilter == ftypes.TaskStatusFilterAll || taskStatusFilter == ftypes.TaskStatusFilterActive,
+					IncludeDeactivated: taskStatusFilter == ftypes.TaskStatusFilterAll || taskStatusFilter == ftypes.TaskStatusFilterInactive,
+				}
+				if err := filter.DisplayFilter.ParseDisplaySelection(display, true); err != nil {
+					return err
+				}
+				if err := validateWhereFilter(where); err != nil {
+					return err
+				}
+
+				backendClient, err := connectToBackend(cmd.Context(), false, &commonFlags)
+				if err == nil {
+					defer backendClient.Close()
+					err = CmdTop(cmd.Context(), backendClient, filter, interval)
+				}
+				return err
+			},
+		}
+		cmd.Flags().StringSliceVarP(&anyNameFilter, "names", "n", []string{}, "Show tasks with any of given friendly names (comma separated)")
+		cmd.Flags().StringVarP(&display, "display", "p", "", "Show tasks running on a display. "+types.DisplaySelectionHelpString)
+		cmd.Flags().StringSliceVarP(&tags, "tags", "t", []string{}, "Show tasks having all of given tags (comma separated)")
+		cmd.Flags().StringVar(&where, "where", "", "Show tasks matching an expression. "+types.TaskExpressionHelpString)
+		cmd.Flags().StringVarP(&status, "status", "s", "all", "Task status filter. One of "+ftypes.TaskStatusFilterStrValues)
+		cmd.Flags().DurationVar(&interval, "interval", time.Second, "How often tasks are listed again, e.g. 500ms")
 		AddCommonFlags(cmd, &commonFlags)
 		commands = append(commands, cmd)
 	}
@@ -109,6 +340,9 @@ func CreateCliCommands() (commands []*cobra.Command) {
 			rerunDelayAfterFailure int
 			maxSubsequentFailures  int
 			tags                   []string
+			logRetention           types.LogRetention
+			logFormat              string
+			logSinks               types.LogSinks
 			noAutoRun              bool
 			commonFlags            CommonFlags
 		)
@@ -137,17 +371,26 @@ func CreateCliCommands() (commands []*cobra.Command) {
 					return err
 				}
 
-				connection, err := ConnectToBackend(!noAutoRun, commonFlags.serverAddress, commonFlags.serverPort)
+				taskLogFormat, err := types.ParseTaskLogFormat(logFormat)
+				if err != nil {
+					return err
+				}
+
+				if err := logSinks.Validate(); err != nil {
+					return err
+				}
+
+				backendClient, err := connectToBackend(cmd.Context(), !noAutoRun, &commonFlags)
 				if err == nil {
-					defer connection.Close()
-					response, err := CmdRun(connection, args, friendlyName, captureStdout, captureStderr,
-						displaySelection, rerunDelayAfterSuccess, rerunDelayAfterFailure, maxSubsequentFailures, tags)
+					defer backendClient.Close()
+					response, err := CmdRun(cmd.Context(), backendClient, args, friendlyName, captureStdout, captureStderr,
+						displaySelection, rerunDelayAfterSuccess, rerunDelayAfterFailure, maxSubsequentFailures, tags, logRetention, taskLogFormat, logSinks)
 					if err != nil {
 						return err
 					}
 
 					if peek {
-						err := CmdPeek(connection, response.Id, &response.LogFile)
+						err := CmdPeek(cmd.Context(), backendClient, strconv.Itoa(response.Id))
 						if err != nil {
 							return err
 						}
@@ -165,6 +408,14 @@ func CreateCliCommands() (commands []*cobra.Command) {
 		cmd.Flags().IntVarP(&rerunDelayAfterFailure, "delay-after-failure", "f", 0, "Delay in milliseconds before rerunning EncodeRunResponsePacketd command after a failed execution")
 		cmd.Flags().IntVarP(&maxSubsequentFailures, "max-subsequent-failures", "m", 3, "Specify a number of command failures in a row after which the task will become deactivated. Specify -1 for no limit.")
 		cmd.Flags().StringSliceVarP(&tags, "tags", "t", []string{}, "Specify comma-separated list of tags for the task. Task do not have any effect, but they can be used to filter tasks.")
+		cmd.Flags().IntVar(&logRetention.MaxExecutions, "keep-executions", 0, "Number of most recent executions to keep stdout/stderr files for. Specify 0 to use backend default or -1 for no limit.")
+		cmd.Flags().Int64Var(&logRetention.MaxTaskLogBytes, "max-log-size", 0, "Size in bytes after which the task log is rotated. Specify 0 to use backend default or -1 for no limit.")
+		cmd.Flags().DurationVar(&logRetention.MaxAge, "max-log-age", 0, "Age after which stdout/stderr files and rotated task logs are removed, e.g. 24h. Specify 0 to use backend default or -1s for no limit.")
+		cmd.Flags().BoolVar(&logRetention.Compress, "compress-logs", false, "Compress stdout/stderr files of older executions and rotated task logs with gzip")
+		cmd.Flags().StringVar(&logFormat, "log-format", "text", "Format of the task log. Use json to write each line as an ndjson record. One of "+types.TaskLogFormatStrValues)
+		cmd.Flags().StringVar(&logSinks.Syslog, "syslog", "", "Forward the task log to a syslog daemon as RFC 5424 messages. "+types.SyslogAddressHelpString)
+		cmd.Flags().IntVar(&logSinks.SyslogMaxLength, "syslog-max-length", 0, types.SyslogMaxLengthHelpString)
+		cmd.Flags().StringVar(&logSinks.LogPipe, "log-pipe", "", "Forward the task log to stdin of a long-running shell command, one json object per line")
 		cmd.Flags().BoolVar(&noAutoRun, "no-auto-run", false, "Do not automatically start the backend if it is not running")
 		AddCommonFlags(cmd, &commonFlags)
 		cmd.MarkFlagRequired("display")
@@ -173,29 +424,96 @@ func CreateCliCommands() (commands []*cobra.Command) {
 	}
 
 	{
-		var commonFlags CommonFlags
+		var (
+			anyNameFilter []string
+			display       string
+			tags          []string
+			where         string
+			commonFlags   CommonFlags
+		)
 		cmd := &cobra.Command{
-			Use:   "peek TASK_ID [OPTIONS...]",
-			Short: "Displays logs of a given task",
-			Args:  cobra.ExactArgs(1),
+			Use:               "peek [TASK] [OPTIONS...]",
+			Short:             "Displays logs of a given task. With filters, displays logs of all matching active tasks at once",
+			Long:              "Displays logs of a given task. With filters, displays logs of all matching active tasks at once. " + taskArgHelpString,
+			Args:              cobra.RangeArgs(0, 1),
+			ValidArgsFunction: completeTaskArg(ftypes.TaskStatusFilterAll),
 			RunE: func(cmd *cobra.Command, args []string) error {
-				taskId, err := strconv.Atoi(args[0])
-				if err != nil {
-					return fmt.Errorf("invalid integer: %v", err)
+				filter := types.TaskFilter{
+					IdFilter:      math.MaxInt,
+					AnyNameFilter: anyNameFilter,
+					AllTagsFilter: tags,
+					WhereFilter:   where,
+				}
+				if err := filter.DisplayFilter.ParseDisplaySelection(display, true); err != nil {
+					return err
+				}
+				if err := validateWhereFilter(where); err != nil {
+					return err
+				}
+				filter.Derive()
+
+				switch {
+				case len(args) == 1 && filter.HasAnyFilter:
+					return errors.New("TASK cannot be used together with filters")
+				case len(args) == 0 && !filter.HasAnyFilter:
+					return errors.New("either TASK or a filter must be specified")
 				}
 
-				connection, err := ConnectToBackend(false, commonFlags.serverAddress, commonFlags.serverPort)
+				backendClient, err := connectToBackend(cmd.Context(), false, &commonFlags)
 				if err == nil {
-					defer connection.Close()
-					err = CmdPeek(connection, taskId, nil)
+					defer backendClient.Close()
+					if filter.HasAnyFilter {
+						err = CmdPeekMany(cmd.Context(), backendClient, filter)
+					} else {
+						err = CmdPeek(cmd.Context(), backendClient, args[0])
+					}
 				}
 				return err
 			},
 		}
+		cmd.Flags().StringSliceVarP(&anyNameFilter, "names", "n", []string{}, "Follow tasks with any of given friendly names (comma separated)")
+		cmd.Flags().StringVarP(&display, "display", "p", "", "Follow tasks running on a display. "+types.DisplaySelectionHelpString)
+		cmd.Flags().StringSliceVarP(&tags, "tags", "t", []string{}, "Follow tasks having all of given tags (comma separated)")
+		cmd.Flags().StringVar(&where, "where", "", "Follow tasks matching an expression. "+types.TaskExpressionHelpString)
 		AddCommonFlags(cmd, &commonFlags)
 		commands = append(commands, cmd)
 	}
 
+	{
+		var (
+			authDir string
+			hosts   []string
+			force   bool
+		)
+		initCmd := &cobra.Command{
+			Use:   "init [OPTIONS...]",
+			Short: "Create certificates and a token for remote connections",
+			Long: "Create a CA, certificates for the backend and the frontend, a token and a client config. The backend uses " +
+				"them when started with serve --remote. The client config can be copied to other machines to connect to it.",
+			Args: cobra.ExactArgs(0),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if authDir == "" {
+					var err error
+					authDir, err = common.GetDefaultAuthDir()
+					if err != nil {
+						return err
+					}
+				}
+				return CmdAuthInit(authDir, hosts, force)
+			},
+		}
+		initCmd.Flags().StringVar(&authDir, "auth-dir", "", "Directory to write the credentials to (default: ~/.config/Spieven/auth)")
+		initCmd.Flags().StringSliceVar(&hosts, "hosts", []string{}, "Additional host names or IP addresses the backend certificate is valid for")
+		initCmd.Flags().BoolVar(&force, "force", false, "Overwrite existing credentials")
+
+		cmd := &cobra.Command{
+			Use:   "auth",
+			Short: "Manage credentials for remote connections",
+		}
+		cmd.AddCommand(initCmd)
+		commands = append(commands, cmd)
+	}
+
 	{
 		var commonFlags CommonFlags
 		cmd := &cobra.Command{
@@ -203,9 +521,9 @@ func CreateCliCommands() (commands []*cobra.Command) {
 			Short: "Checks whether the backend is running and can be connected to",
 			Args:  cobra.ExactArgs(0),
 			RunE: func(cmd *cobra.Command, args []string) error {
-				connection, err := ConnectToBackend(false, commonFlags.serverAddress, commonFlags.serverPort)
+				backendClient, err := connectToBackend(cmd.Context(), false, &commonFlags)
 				if err == nil {
-					defer connection.Close()
+					defer backendClient.Close()
 					fmt.Println("backend works correctly")
 					return nil
 				}
@@ -221,6 +539,7 @@ func CreateCliCommands() (commands []*cobra.Command) {
 			idFilter      int
 			anyNameFilter []string
 			allTagsFilter []string
+			where         string
 			commonFlags   CommonFlags
 		)
 		cmd := &cobra.Command{
@@ -232,19 +551,25 @@ func CreateCliCommands() (commands []*cobra.Command) {
 					IdFilter:      idFilter,
 					AnyNameFilter: anyNameFilter,
 					AllTagsFilter: allTagsFilter,
+					WhereFilter:   where,
+					IncludeActive: true,
+				}
+				if err := validateWhereFilter(where); err != nil {
+					return err
 				}
 
-				connection, err := ConnectToBackend(false, commonFlags.serverAddress, commonFlags.serverPort)
+				backendClient, err := connectToBackend(cmd.Context(), false, &commonFlags)
 				if err != nil {
 					return errors.New("cannot connect to backend")
 				}
-				defer connection.Close()
-				return CmdRefresh(connection, filter)
+				defer backendClient.Close()
+				return CmdRefresh(cmd.Context(), backendClient, filter)
 			},
 		}
 		cmd.Flags().IntVarP(&idFilter, "id", "i", math.MaxInt, "Filter tasks by id")
 		cmd.Flags().StringSliceVarP(&anyNameFilter, "names", "n", []string{}, "Filter tasks by friendly names. Multiple names can be specified (comma separated) to allow multiple results")
 		cmd.Flags().StringSliceVarP(&allTagsFilter, "tags", "t", []string{}, "Filter tasks by tags. Multiple tags can be specified (comma separated) to require multiple tags to be present")
+		cmd.Flags().StringVar(&where, "where", "", "Filter tasks by an expression. "+types.TaskExpressionHelpString)
 		AddCommonFlags(cmd, &commonFlags)
 		commands = append(commands, cmd)
 	}
@@ -252,64 +577,255 @@ func CreateCliCommands() (commands []*cobra.Command) {
 	{
 		var (
 			peek        bool
+			filterFlags bulkTaskFilterFlags
 			commonFlags CommonFlags
 		)
 		cmd := &cobra.Command{
-			Use:   "resume TASK_ID [OPTIONS...]",
-			Short: "EncodeRunResponsePacket a stopped task again by its ID.",
-			Args:  cobra.ExactArgs(1),
+			Use:   "resume [TASK] [OPTIONS...]",
+			Short: "Run a stopped task again, keeping its ID. With filters, resumes all matching stopped tasks",
+			Long: "Run a stopped task again, keeping its ID. With filters, resumes all matching stopped tasks and reports " +
+				"the result for each of them. " + taskArgHelpString,
+			Args:              cobra.RangeArgs(0, 1),
+			ValidArgsFunction: completeTaskArg(ftypes.TaskStatusFilterInactive),
 			RunE: func(cmd *cobra.Command, args []string) error {
-				taskId, err := strconv.Atoi(args[0])
+				filter, err := parseBulkTaskFilter(args, &filterFlags)
 				if err != nil {
 					return err
 				}
+				if filter != nil && peek {
+					return errors.New("peek cannot be used together with filters")
+				}
 
-				connection, err := ConnectToBackend(false, commonFlags.serverAddress, commonFlags.serverPort)
+				backendClient, err := connectToBackend(cmd.Context(), false, &commonFlags)
 				if err == nil {
-					defer connection.Close()
-					response, err := CmdResume(connection, taskId)
+					defer backendClient.Close()
+					if filter != nil {
+						return CmdResumeMany(cmd.Context(), backendClient, *filter, filterFlags.all)
+					}
+
+					response, err := CmdResume(cmd.Context(), backendClient, args[0])
 					if err != nil {
 						return err
 					}
 
 					if peek {
-						err := CmdPeek(connection, response.Id, &response.LogFile)
+						err := CmdPeek(cmd.Context(), backendClient, strconv.Itoa(response.Id))
 						if err != nil {
 							return err
 						}
 					}
 				}
-				return nil
+				return err
 			},
 		}
 		cmd.Flags().BoolVarP(&peek, "peek", "w", false, "Peek task log after successful resuming. Functionally equivalent to running spieven peek <taskId>")
+		addBulkTaskFilterFlags(cmd, &filterFlags)
 		AddCommonFlags(cmd, &commonFlags)
 		commands = append(commands, cmd)
 	}
 
 	{
-		var commonFlags CommonFlags
+		var (
+			filterFlags bulkTaskFilterFlags
+			commonFlags CommonFlags
+		)
 		cmd := &cobra.Command{
-			Use:   "stop TASK_ID [OPTIONS...]",
-			Short: "Manually deactivate a task by its ID.",
-			Args:  cobra.ExactArgs(1),
+			Use:   "stop [TASK] [OPTIONS...]",
+			Short: "Manually deactivate a task. With filters, deactivates all matching tasks",
+			Long: "Manually deactivate a task. With filters, deactivates all matching tasks and reports the result for " +
+				"each of them. " + taskArgHelpString,
+			Args:              cobra.RangeArgs(0, 1),
+			ValidArgsFunction: completeTaskArg(ftypes.TaskStatusFilterActive),
 			RunE: func(cmd *cobra.Command, args []string) error {
-				taskId, err := strconv.Atoi(args[0])
+				filter, err := parseBulkTaskFilter(args, &filterFlags)
 				if err != nil {
 					return err
 				}
 
-				connection, err := ConnectToBackend(false, commonFlags.serverAddress, commonFlags.serverPort)
+				backendClient, err := connectToBackend(cmd.Context(), false, &commonFlags)
 				if err == nil {
-					defer connection.Close()
-					err = CmdStop(connection, taskId)
+					defer backendClient.Close()
+					if filter != nil {
+						err = CmdStopMany(cmd.Context(), backendClient, *filter, filterFlags.all)
+					} else {
+						err = CmdStop(cmd.Context(), backendClient, args[0])
+					}
 				}
 				return err
 			},
 		}
+		addBulkTaskFilterFlags(cmd, &filterFlags)
 		AddCommonFlags(cmd, &commonFlags)
 		commands = append(commands, cmd)
 	}
 
+	{
+		var (
+			display     string
+			peek        bool
+			commonFlags CommonFlags
+		)
+		cmd := &cobra.Command{
+			Use:               "move TASK [OPTIONS...]",
+			Short:             "Restart a task on a different display, keeping its ID and counters.",
+			Long:              "Restart a task on a different display, keeping its ID and counters. " + taskArgHelpString,
+			Args:              cobra.ExactArgs(1),
+			ValidArgsFunction: completeTaskArg(ftypes.TaskStatusFilterAll),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var displaySelection types.DisplaySelection
+				if err := displaySelection.ParseDisplaySelection(display, false); err != nil {
+					return err
+				}
+
+				backendClient, err := connectToBackend(cmd.Context(), false, &commonFlags)
+				if err == nil {
+					defer backendClient.Close()
+					response, err := CmdMove(cmd.Context(), backendClient, args[0], displaySelection)
+					if err != nil {
+						return err
+					}
+
+					if peek {
+						err := CmdPeek(cmd.Context(), backendClient, strconv.Itoa(response.Id))
+						if err != nil {
+							return err
+						}
+					}
+				}
+				return err
+			},
+		}
+		cmd.Flags().StringVarP(&display, "display", "p", "", "Display to move the task to. "+types.DisplaySelectionHelpString)
+		cmd.Flags().BoolVarP(&peek, "peek", "w", false, "Peek task log after successful moving. Functionally equivalent to running spieven peek <taskId>")
+		AddCommonFlags(cmd, &commonFlags)
+		cmd.MarkFlagRequired("display")
+		commands = append(commands, cmd)
+	}
+
+	{
+		var (
+			tail        int
+			since       string
+			until       string
+			execution   string
+			stdout      bool
+			stderr      bool
+			follow      bool
+			grep        string
+			commonFlags CommonFlags
+		)
+		cmd := &cobra.Command{
+			Use:               "logs TASK [OPTIONS...]",
+			Short:             "Display logs of a task, including deactivated ones",
+			Long:              "Display logs of a task, including deactivated ones. " + taskArgHelpString,
+			Args:              cobra.ExactArgs(1),
+			ValidArgsFunction: completeTaskArg(ftypes.TaskStatusFilterAll),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				request := packet.TaskLogsRequestBody{
+					Task: args[0],
+					Tail: tail,
+					Grep: grep,
+				}
+
+				switch {
+				case stdout && stderr:
+					return errors.New("--stdout and --stderr cannot be used together")
+				case stdout:
+					request.Source = packet.TaskLogsSourceStdout
+				case stderr:
+					request.Source = packet.TaskLogsSourceStderr
+				default:
+					request.Source = packet.TaskLogsSourceTaskLog
+				}
+
+				var err error
+				if request.Execution, err = types.ParseExecutionSelection(execution); err != nil {
+					return err
+				}
+				if request.Since, err = ftypes.ParseTimeSelection(since); err != nil {
+					return err
+				}
+				if request.Until, err = ftypes.ParseTimeSelection(until); err != nil {
+					return err
+				}
+				if (stdout || stderr) && (since != "" || until != "") {
+					return errors.New("--since and --until cannot be used with --stdout or --stderr, because captured output has no timestamps")
+				}
+				if tail < 0 {
+					return errors.New("--tail cannot be negative")
+				}
+
+				backendClient, err := connectToBackend(cmd.Context(), false, &commonFlags)
+				if err == nil {
+					defer backendClient.Close()
+					err = CmdLogs(cmd.Context(), backendClient, request, follow)
+				}
+				return err
+			},
+		}
+		cmd.Flags().IntVarP(&tail, "tail", "n", 0, "Display only the last N lines (0 means all lines)")
+		cmd.Flags().StringVar(&since, "since", "", "Display only lines logged after given time. Cannot be used with --stdout or --stderr. "+ftypes.TimeSelectionHelpString)
+		cmd.Flags().StringVar(&until, "until", "", "Display only lines logged before given time. Cannot be used with --stdout or --stderr. "+ftypes.TimeSelectionHelpString)
+		cmd.Flags().StringVarP(&execution, "execution", "e", "all", "Select execution of the task to display. "+types.ExecutionSelectionHelpString)
+		cmd.Flags().BoolVar(&stdout, "stdout", false, "Display captured stdout of the task instead of the task log. Requires the task to be run with --capture-stdout")
+		cmd.Flags().BoolVar(&stderr, "stderr", false, "Display captured stderr of the task instead of the task log. Requires the task to be run with --capture-stderr")
+		cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Keep displaying new lines until the task is deactivated")
+		cmd.Flags().StringVarP(&grep, "grep", "g", "", "Display only lines matching given regular expression")
+		AddCommonFlags(cmd, &commonFlags)
+		commands = append(commands, cmd)
+	}
+
+	{
+		var (
+			jsonOutput  bool
+			commonFlags CommonFlags
+		)
+		cmd := &cobra.Command{
+			Use:               "history TASK [OPTIONS...]",
+			Short:             "Display recent executions of a task with their times and results",
+			Long:              "Display recent executions of a task with their times and results. " + taskArgHelpString,
+			Args:              cobra.ExactArgs(1),
+			ValidArgsFunction: completeTaskArg(ftypes.TaskStatusFilterAll),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				backendClient, err := connectToBackend(cmd.Context(), false, &commonFlags)
+				if err == nil {
+					defer backendClient.Close()
+					err = CmdHistory(cmd.Context(), backendClient, args[0], jsonOutput)
+				}
+				return err
+			},
+		}
+		cmd.Flags().BoolVar(&jsonOutput, "json", false, "Display the history in json format")
+		AddCommonFlags(cmd, &commonFlags)
+		commands = append(commands, cmd)
+	}
+	{
+		var (
+			jsonOutput  bool
+			commonFlags CommonFlags
+		)
+		cmd := &cobra.Command{
+			Use:               "inspect TASK [OPTIONS...]",
+			Short:             "Display everything the backend knows about a task, including its state, processes and files",
+			Long:              "Display everything the backend knows about a task, including its state, processes and files. " + taskArgHelpString,
+			Args:              cobra.ExactArgs(1),
+			ValidArgsFunction: completeTaskArg(ftypes.TaskStatusFilterAll),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				backendClient, err := connectToBackend(cmd.Context(), false, &commonFlags)
+				if err == nil {
+					defer backendClient.Close()
+					err = CmdInspect(cmd.Context(), backendClient, args[0], jsonOutput)
+				}
+				return err
+			},
+		}
+		cmd.Flags().BoolVar(&jsonOutput, "json", false, "Display the details in json format")
+		AddCommonFlags(cmd, &commonFlags)
+		commands = append(commands, cmd)
+	}
+
+	for _, cmd := range commands {
+		registerFlagCompletions(cmd)
+	}
 	return
 }
@@ -1,77 +1,99 @@
 package frontend
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
 	"os"
+	"spieven/client"
 	"spieven/common/packet"
 	"spieven/common/types"
 	ftypes "spieven/frontend/types"
+	"strconv"
 	"strings"
-	"sync"
-	"sync/atomic"
 	"time"
 )
 
-func CmdLog(backendConnection net.Conn) error {
-	requestPacket, err := packet.EncodeLogPacket()
-	if err != nil {
-		return err
-	}
-
-	err = packet.SendPacket(backendConnection, requestPacket)
-	if err != nil {
-		return err
+func CmdLog(ctx context.Context, backendClient *client.Client, request packet.LogRequestBody, jsonOutput bool) error {
+	printMessage := func(message types.BackendMessage) error {
+		if jsonOutput {
+			serialized, err := json.Marshal(&message)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(serialized))
+		} else {
+			fmt.Println(message.String())
+		}
+		return nil
 	}
 
-	responsePacket, err := packet.ReceivePacket(backendConnection)
-	if err != nil {
-		return err
+	if request.Follow {
+		return backendClient.FollowMessages(ctx, request, printMessage)
 	}
 
-	response, err := packet.DecodeLogResponsePacket(responsePacket)
+	response, err := backendClient.Messages(ctx, request)
 	if err != nil {
 		return err
 	}
-
-	for _, line := range response {
-		fmt.Println(line)
+	for _, message := range response {
+		if err := printMessage(message); err != nil {
+			return err
+		}
 	}
-
 	return nil
 }
 
+func CmdEvents(ctx context.Context, backendClient *client.Client, filter types.EventFilter, jsonOutput bool) error {
+	return backendClient.Events(ctx, filter, func(event types.Event) error {
+		if jsonOutput {
+			serialized, err := json.Marshal(&event)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(serialized))
+		} else {
+			fmt.Println(event.String())
+		}
+		return nil
+	})
+}
+
+// CmdList prints tasks matching the filter. Columns and sortBy select columns of the default and csv formats and the
+// order of tasks in all formats, see getListColumns. Template is only used by the template format.
 func CmdList(
-	backendConnection net.Conn,
+	ctx context.Context,
+	backendClient *client.Client,
 	filter types.TaskFilter,
 	format ftypes.ListFormat,
+	template string,
 	uniqueNames bool,
+	columnNames []string,
+	sortBy string,
 ) error {
-	request := packet.ListRequestBody{
-		Filter:      filter,
-		UniqueNames: uniqueNames,
-	}
-
-	requestPacket, err := packet.EncodeListPacket(request)
+	var now time.Time
+	allColumns := getListColumns(&now)
+	columns, err := parseListColumns(allColumns, columnNames)
 	if err != nil {
 		return err
 	}
 
-	err = packet.SendPacket(backendConnection, requestPacket)
-	if err != nil {
-		return err
+	request := packet.ListRequestBody{
+		Filter:      filter,
+		UniqueNames: uniqueNames,
 	}
 
-	responsePacket, err := packet.ReceivePacket(backendConnection)
+	response, err := backendClient.List(ctx, request)
 	if err != nil {
 		return err
 	}
+	now = time.Now()
 
-	response, err := packet.DecodeListResponsePacket(responsePacket)
-	if err != nil {
-		return err
+	if sortBy != "" {
+		if err := sortListResponse(allColumns, response, sortBy); err != nil {
+			return err
+		}
 	}
 
 	switch format {
@@ -83,6 +105,18 @@ func CmdList(
 		fmt.Println(string(output))
 		return nil
 
+	case ftypes.ListFormatNdjson:
+		return printListNdjson(response)
+
+	case ftypes.ListFormatYaml:
+		return printListYaml(response)
+
+	case ftypes.ListFormatCsv:
+		return printListCsv(columns, columnNames, response)
+
+	case ftypes.ListFormatTemplate:
+		return printListTemplate(template, response)
+
 	case ftypes.ListFormatDefault:
 		if len(response) == 0 {
 			filter.Derive()
@@ -93,126 +127,7 @@ func CmdList(
 			}
 			return nil
 		}
-
-		type Column struct {
-			header string
-			get    func(task *packet.ListResponseBodyItem) string
-			width  int
-		}
-
-		columns := []Column{
-			{
-				header: "Id",
-				get: func(task *packet.ListResponseBodyItem) string {
-					return fmt.Sprintf("%d", task.Id)
-				},
-				width: 0, // Will be initialized from header length
-			},
-			{
-				header: "Name",
-				get: func(task *packet.ListResponseBodyItem) string {
-					name := task.FriendlyName
-					if name == "" && len(task.Cmdline) > 0 {
-						name = task.Cmdline[0]
-					}
-					return name
-				},
-				width: 0,
-			},
-			{
-				header: "Active",
-				get: func(task *packet.ListResponseBodyItem) string {
-					if task.IsDeactivated {
-						return "no"
-					}
-					return "yes"
-				},
-				width: 0,
-			},
-			{
-				header: "Display",
-				get: func(task *packet.ListResponseBodyItem) string {
-					return task.Display.ComputeDisplayLabel()
-				},
-				width: 0,
-			},
-			{
-				header: "Runs",
-				get: func(task *packet.ListResponseBodyItem) string {
-					return fmt.Sprintf("%d", task.RunCount)
-				},
-				width: 0,
-			},
-			{
-				header: "Failures",
-				get: func(task *packet.ListResponseBodyItem) string {
-					maxFailures := task.MaxSubsequentFailures
-					maxFailuresStr := ""
-					if maxFailures >= 0 {
-						maxFailuresStr = fmt.Sprintf("/%d", maxFailures)
-					}
-					return fmt.Sprintf("%d%s", task.FailureCount, maxFailuresStr)
-				},
-				width: 0,
-			},
-		}
-
-		colCount := len(columns)
-
-		// Initialize column widths from headers.
-		for i := range columns {
-			columns[i].width = len(columns[i].header)
-		}
-
-		// Build rows and update widths based on cell contents.
-		rows := make([][]string, len(response))
-		for i := range response {
-			task := &response[i]
-			row := make([]string, colCount)
-			for ci := range columns {
-				val := columns[ci].get(task)
-				row[ci] = val
-				if len(val) > columns[ci].width {
-					columns[ci].width = len(val)
-				}
-			}
-			rows[i] = row
-		}
-
-		// Build format string with vertical bars based on computed widths.
-		var formatBuilder strings.Builder
-		formatBuilder.WriteString("|")
-		for _, col := range columns {
-			fmt.Fprintf(&formatBuilder, " %%-%dv |", col.width)
-		}
-		formatBuilder.WriteString("\n")
-		format := formatBuilder.String()
-
-		// Build separator line like: |----|--------|...
-		var sepBuilder strings.Builder
-		sepBuilder.WriteString("|")
-		for _, col := range columns {
-			sepBuilder.WriteString(strings.Repeat("-", col.width+2))
-			sepBuilder.WriteString("|")
-		}
-		sep := sepBuilder.String()
-
-		// Print header and separator.
-		headerArgs := make([]any, colCount)
-		for i, col := range columns {
-			headerArgs[i] = col.header
-		}
-		fmt.Printf(format, headerArgs...)
-		fmt.Println(sep)
-
-		// Print rows.
-		for _, row := range rows {
-			rowArgs := make([]any, colCount)
-			for i, val := range row {
-				rowArgs[i] = val
-			}
-			fmt.Printf(format, rowArgs...)
-		}
+		printListTable(columns, response)
 
 	case ftypes.ListFormatDetailed:
 		if len(response) == 0 {
@@ -245,6 +160,11 @@ func CmdList(
 			fmt.Printf("  FailureCount:           %v\n", task.FailureCount)
 			fmt.Printf("  SubsequentFailureCount: %v\n", task.SubsequentFailureCount)
 			fmt.Printf("  LastExitValue:          %v\n", task.LastExitValue)
+			fmt.Printf("  CpuTime:                %v (user %v, sys %v)\n", task.TotalUsage.CpuTime().Round(time.Millisecond),
+				task.TotalUsage.UserTime.Round(time.Millisecond), task.TotalUsage.SystemTime.Round(time.Millisecond))
+			fmt.Printf("  MaxRss:                 %v KiB\n", task.TotalUsage.MaxRssKb)
+			fmt.Printf("  MajorFaults:            %v\n", task.TotalUsage.MajorFaults)
+			fmt.Printf("  ContextSwitches:        %v voluntary, %v involuntary\n", task.TotalUsage.VoluntaryContextSwitches, task.TotalUsage.InvoluntaryContextSwitches)
 			if i < len(response)-1 {
 				fmt.Println()
 			}
@@ -257,7 +177,8 @@ func CmdList(
 }
 
 func CmdRun(
-	backendConnection net.Conn,
+	ctx context.Context,
+	backendClient *client.Client,
 	args []string,
 	friendlyName string,
 	captureStdout bool,
@@ -267,23 +188,16 @@ func CmdRun(
 	rerunDelayAfterFailure int,
 	maxSubsequentFailures int,
 	tags []string,
+	logRetention types.LogRetention,
+	logFormat types.TaskLogFormat,
+	logSinks types.LogSinks,
 ) (*packet.RunResponseBody, error) {
-	cwd, err := os.Getwd()
-	if err != nil {
-		var found bool
-		cwd, found = os.LookupEnv("HOME")
-		if !found {
-			return nil, fmt.Errorf("could not determine working directory for the task")
-		}
-	}
-
 	if friendlyName == "" {
 		friendlyName = args[0]
 	}
 
 	body := packet.RunRequestBody{
 		Cmdline:               args,
-		Cwd:                   cwd,
 		Env:                   os.Environ(),
 		FriendlyName:          friendlyName,
 		CaptureStdout:         captureStdout,
@@ -293,294 +207,402 @@ func CmdRun(
 		DelayAfterFailureMs:   rerunDelayAfterFailure,
 		MaxSubsequentFailures: maxSubsequentFailures,
 		Tags:                  tags,
+		LogRetention:          logRetention,
+		LogFormat:             logFormat,
+		LogSinks:              logSinks,
 	}
 
-	err = ValidateRunRequestBody(&body)
-	if err != nil {
-		return nil, err
-	}
-
-	requestPacket, err := packet.EncodeRunPacket(body)
-	if err != nil {
-		return nil, err
-	}
-
-	err = packet.SendPacket(backendConnection, requestPacket)
-	if err != nil {
-		return nil, err
-	}
-
-	responsePacket, err := packet.ReceivePacket(backendConnection)
-	if err != nil {
-		return nil, err
-	}
-
-	response, err := packet.DecodeRunResponsePacket(responsePacket)
-	if err != nil {
-		return nil, err
-	}
-
-	switch response.Status {
-	case types.RunResponseStatusSuccess:
+	response, err := backendClient.Run(ctx, body)
+	switch {
+	case err == nil:
 		fmt.Println("Run task")
 		fmt.Println("Log file: ", response.LogFile)
-		return &response, nil
-	case types.RunResponseStatusAlreadyRunning:
-		err = errors.New("task is already running. To run multiple instances of the same task use friendly name. See help message for details")
-		return nil, err
-	case types.RunResponseStatusNameDisplayAlreadyRunning:
-		err = fmt.Errorf("task named %v is already running on current display", friendlyName)
-		return nil, err
-	case types.RunResponseStatusInvalidDisplay:
-		err = errors.New("task is using invalid display")
-		return nil, err
+		return response, nil
+	case errors.Is(err, client.ErrAlreadyRunning):
+		return nil, errors.New("task is already running. To run multiple instances of the same task use friendly name. See help message for details")
+	case errors.Is(err, client.ErrNameDisplayAlreadyRunning):
+		return nil, fmt.Errorf("task named %v is already running on current display", friendlyName)
+	case errors.Is(err, client.ErrInvalidDisplay):
+		return nil, errors.New("task is using invalid display")
 	default:
-		err = errors.New("unknown task run error")
 		return nil, err
 	}
 }
 
-func CmdPeek(backendConnection net.Conn, taskId int, logFilePath *string) error {
-	retrieveLogFilePath := func() (string, error) {
-		filter := types.TaskFilter{
-			IdFilter:           taskId,
-			IncludeActive:      true,
-			IncludeDeactivated: true,
-		}
-		request := packet.ListRequestBody{Filter: filter}
-
-		requestPacket, err := packet.EncodeListPacket(request)
-		if err != nil {
-			return "", err
-		}
-
-		err = packet.SendPacket(backendConnection, requestPacket)
-		if err != nil {
-			return "", err
-		}
-
-		responsePacket, err := packet.ReceivePacket(backendConnection)
-		if err != nil {
-			return "", err
-		}
-
-		response, err := packet.DecodeListResponsePacket(responsePacket)
-		if err != nil {
-			return "", err
-		}
-
-		switch len(response) {
-		case 0:
-			return "", fmt.Errorf("could not find log file")
-		case 1:
-			return response[0].OutFilePath, nil
-		default:
-			return "", fmt.Errorf("multiple log files found. This is highly unexpected")
-		}
+func CmdPeek(ctx context.Context, backendClient *client.Client, task string) error {
+	printLine := func(line string) error {
+		fmt.Print(line)
+		return nil
 	}
+	return backendClient.Follow(ctx, task, 0, printLine)
+}
 
-	checkTaskActiveStatus := func() (bool, error) {
-		requestPacket, err := packet.EncodeQueryTaskActivePacket(packet.QueryTaskActiveRequestBody(taskId))
-		if err != nil {
-			return false, err
-		}
+// CmdPeekMany follows logs of all active tasks matching the filter. Each line is prefixed with a label of the task it
+// comes from. It runs until interrupted.
+func CmdPeekMany(ctx context.Context, backendClient *client.Client, filter types.TaskFilter) error {
+	useColors := ftypes.IsTerminal(os.Stdout)
+	labels := make(map[int]string)
+	labelWidth := 0
 
-		err = packet.SendPacket(backendConnection, requestPacket)
-		if err != nil {
-			return false, err
+	handleLine := func(line client.TaskLine) error {
+		// Empty lines only separate executions in a task log. They'd be meaningless when mixed with other tasks.
+		if strings.TrimSpace(line.Line) == "" {
+			return nil
 		}
 
-		responsePacket, err := packet.ReceivePacket(backendConnection)
-		if err != nil {
-			return false, err
+		if _, found := labels[line.TaskId]; !found {
+			label := fmt.Sprintf("#%v", line.TaskId)
+			if line.TaskName != "" {
+				label = fmt.Sprintf("%v#%v", line.TaskName, line.TaskId)
+			}
+			labels[line.TaskId] = label
+			labelWidth = max(labelWidth, len(label))
 		}
 
-		response, err := packet.DecodeQueryTaskActiveResponsePacket(responsePacket)
-		if err != nil {
-			return false, err
+		label := fmt.Sprintf("%-*v |", labelWidth, labels[line.TaskId])
+		if useColors {
+			label = ftypes.Colorize(label, line.TaskId)
 		}
-
-		switch response {
-		case packet.QueryTaskActiveResponseBodyActive:
-			return true, nil
-		case packet.QueryTaskActiveResponseBodyInactive:
-			return false, nil
-		case packet.QueryTaskActiveResponseInvalidTask:
-			return false, errors.New("invalid task ID sent to backend")
-		default:
-			return false, errors.New("unknown backend error")
+		fmt.Printf("%v %v", label, line.Line)
+		if !strings.HasSuffix(line.Line, "\n") {
+			fmt.Println()
 		}
+		return nil
 	}
 
-	// If we don't know the path to the log file, we can ask backend for it using taskId.
-	if logFilePath == nil {
-		path, err := retrieveLogFilePath()
-		if err != nil {
-			return err
-		}
-		logFilePath = &path
-	}
-
-	// Setup vars for communicating with goroutines
-	var goroutinesStopFlag atomic.Int32
-	var sync sync.WaitGroup
-	sync.Add(2)
-
-	// Goroutine 1: Read the file continuously
-	var fileWatchError error
-	go func() {
-		fileWatchError = WatchFile(taskId, *logFilePath, &goroutinesStopFlag)
-
-		sync.Done()
-		goroutinesStopFlag.Store(1)
-	}()
-
-	// Goroutine 2: Wait for the response packet
-	var backendCommunicationError error
-	go func() {
-		taskActive := true
-		for goroutinesStopFlag.Load() == 0 && taskActive {
-			taskActive, backendCommunicationError = checkTaskActiveStatus()
-			if taskActive {
-				time.Sleep(time.Second)
-			}
-		}
-
-		sync.Done()
-		goroutinesStopFlag.Store(1)
-	}()
-
-	// Wait for both goroutines
-	sync.Wait()
+	return backendClient.FollowTasks(ctx, filter, handleLine)
+}
 
-	// Print error if any
-	if fileWatchError != nil {
-		fmt.Printf("Log file watching error: %v\n", fileWatchError)
-	}
-	if backendCommunicationError != nil {
-		fmt.Printf("Backend communication error: %v\n", backendCommunicationError)
+func CmdRefresh(ctx context.Context, backendClient *client.Client, filter types.TaskFilter) error {
+	response, err := backendClient.Refresh(ctx, filter)
+	if err != nil {
+		return err
 	}
 
+	fmt.Printf("%v out of %v tasks were refreshed\n", response.RefreshedTasksCount, response.ActiveTasksCount)
 	return nil
 }
 
-func CmdRefresh(backendConnection net.Conn, filter types.TaskFilter) error {
-	request := packet.RefreshRequestBody{
-		Filter: filter,
+func CmdResume(ctx context.Context, backendClient *client.Client, task string) (*packet.ResumeResponseBody, error) {
+	response, err := backendClient.Resume(ctx, task)
+	switch {
+	case err == nil:
+		fmt.Println("Resumed task")
+		fmt.Println("Log file: ", response.LogFile)
+		return response, nil
+	case errors.Is(err, client.ErrAlreadyRunning):
+		return nil, fmt.Errorf("task is already running. Looks like you ran an identical task after task %v was deactivated", task)
+	case errors.Is(err, client.ErrNameDisplayAlreadyRunning):
+		return nil, fmt.Errorf("task with this name is already running on current display. Looks like you ran an identical task after task %v was deactivated", task)
+	case errors.Is(err, client.ErrInvalidDisplay):
+		return nil, errors.New("task is using invalid display")
+	case errors.Is(err, client.ErrTaskNotFound):
+		return nil, errors.New("task not found")
+	case errors.Is(err, client.ErrTaskNotDeactivated):
+		return nil, errors.New("task is already active")
+	default:
+		return nil, err
 	}
+}
 
-	requestPacket, err := packet.EncodeRefreshPacket(request)
+func CmdStop(ctx context.Context, backendClient *client.Client, task string) error {
+	err := backendClient.Stop(ctx, task)
 	if err != nil {
 		return err
 	}
 
-	err = packet.SendPacket(backendConnection, requestPacket)
-	if err != nil {
-		return err
-	}
+	fmt.Println("Stopped task")
+	return nil
+}
 
-	responsePacket, err := packet.ReceivePacket(backendConnection)
-	if err != nil {
-		return err
+// CmdStopMany stops all tasks matching the filter and prints what happened to each of them. The filter can select tasks
+// only by status, if all is set.
+func CmdStopMany(ctx context.Context, backendClient *client.Client, filter types.TaskFilter, all bool) error {
+	stop := backendClient.StopMany
+	if all {
+		stop = backendClient.StopAll
 	}
-
-	response, err := packet.DecodeRefreshResponsePacket(responsePacket)
+	results, err := stop(ctx, filter)
 	if err != nil {
 		return err
 	}
-
-	fmt.Printf("%v out of %v tasks were refreshed\n", response.RefreshedTasksCount, response.ActiveTasksCount)
-	return nil
+	return printBulkTaskResults(results, "stopped")
 }
 
-func CmdResume(backendConnection net.Conn, taskId int) (*packet.ResumeResponseBody, error) {
-	request := packet.ResumeRequestBody{
-		TaskId: taskId,
+// CmdResumeMany resumes all tasks matching the filter and prints what happened to each of them. The filter can select
+// tasks only by status, if all is set.
+func CmdResumeMany(ctx context.Context, backendClient *client.Client, filter types.TaskFilter, all bool) error {
+	resume := backendClient.ResumeMany
+	if all {
+		resume = backendClient.ResumeAll
 	}
-
-	requestPacket, err := packet.EncodeResumePacket(request)
+	results, err := resume(ctx, filter)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return printBulkTaskResults(results, "resumed")
+}
 
-	err = packet.SendPacket(backendConnection, requestPacket)
-	if err != nil {
-		return nil, err
+// printBulkTaskResults prints a table of results of a bulk request with a summary. It returns an error if any of the
+// tasks failed, so scripts can tell from the exit code.
+func printBulkTaskResults(results []types.BulkTaskResult, verb string) error {
+	if len(results) == 0 {
+		fmt.Println("No tasks match the filter")
+		return nil
 	}
 
-	responsePacket, err := packet.ReceivePacket(backendConnection)
-	if err != nil {
-		return nil, err
+	headers := []string{"Id", "Name", "Display", "Result"}
+	rows := make([][]string, 0, len(results))
+	changedCount := 0
+	failedCount := 0
+	for _, result := range results {
+		outcome := string(result.Outcome)
+		switch result.Outcome {
+		case types.BulkTaskOutcomeFailed:
+			outcome = fmt.Sprintf("%v: %v", outcome, result.Error)
+			failedCount++
+		case types.BulkTaskOutcomeStopped, types.BulkTaskOutcomeResumed:
+			changedCount++
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(result.TaskId),
+			result.FriendlyName,
+			result.Display.ComputeDisplayLabel(),
+			outcome,
+		})
 	}
+	printTable(headers, rows)
 
-	response, err := packet.DecodeResumeResponsePacket(responsePacket)
-	if err != nil {
-		return nil, err
+	fmt.Printf("%v out of %v tasks were %v\n", changedCount, len(results), verb)
+	if failedCount > 0 {
+		return fmt.Errorf("%v tasks failed", failedCount)
 	}
+	return nil
+}
 
-	switch response.Status {
-	case types.RunResponseStatusSuccess:
-		fmt.Println("Resumed task")
+func CmdMove(ctx context.Context, backendClient *client.Client, task string, display types.DisplaySelection) (*packet.MoveResponseBody, error) {
+	response, err := backendClient.Move(ctx, task, display)
+	switch {
+	case err == nil:
+		fmt.Println("Moved task")
 		fmt.Println("Log file: ", response.LogFile)
-		return &response, nil
-	case types.RunResponseStatusAlreadyRunning:
-		err = fmt.Errorf("task is already running. Looks like you ran an identical task after task %v was deactivated", taskId)
-		return nil, err
-	case types.RunResponseStatusNameDisplayAlreadyRunning:
-		err = fmt.Errorf("task with this name is already running on current display. Looks like you ran an identical task after task %v was deactivated", taskId)
-		return nil, err
-	case types.RunResponseStatusInvalidDisplay:
-		err = errors.New("task is using invalid display")
-		return nil, err
-	case types.RunResponseStatusTaskNotFound:
-		err = errors.New("task not found")
-		return nil, err
-	case types.RunResponseStatusTaskNotDeactivated:
-		err = errors.New("task is already active")
-		return nil, err
+		return response, nil
+	case errors.Is(err, client.ErrAlreadyRunning):
+		return nil, errors.New("an identical task is already running on the target display")
+	case errors.Is(err, client.ErrNameDisplayAlreadyRunning):
+		return nil, errors.New("task with this name is already running on the target display")
+	case errors.Is(err, client.ErrInvalidDisplay):
+		return nil, errors.New("target display is invalid")
+	case errors.Is(err, client.ErrTaskNotFound):
+		return nil, errors.New("task not found")
+	case errors.Is(err, client.ErrTaskNotDeactivated):
+		return nil, errors.New("task was resumed while being moved")
 	default:
-		err = errors.New("unknown resuming error")
 		return nil, err
 	}
 }
 
-func CmdStop(backendConnection net.Conn, taskId int) error {
-	request := packet.StopRequestBody{
-		TaskId: taskId,
+func CmdLogs(ctx context.Context, backendClient *client.Client, request packet.TaskLogsRequestBody, follow bool) error {
+	printRecord := func(record *types.TaskLogRecord) error {
+		if request.Source == packet.TaskLogsSourceTaskLog {
+			fmt.Print(record.Format(types.TaskLogFormatText))
+		} else {
+			fmt.Println(record.Line)
+		}
+		return nil
+	}
+
+	if follow {
+		return backendClient.FollowLogs(ctx, request, printRecord)
 	}
 
-	requestPacket, err := packet.EncodeStopPacket(request)
+	response, err := backendClient.Logs(ctx, request)
 	if err != nil {
 		return err
 	}
+	for i := range response.Records {
+		printRecord(&response.Records[i])
+	}
+	return nil
+}
 
-	err = packet.SendPacket(backendConnection, requestPacket)
+func CmdHistory(ctx context.Context, backendClient *client.Client, task string, jsonOutput bool) error {
+	response, err := backendClient.History(ctx, task)
 	if err != nil {
 		return err
 	}
 
-	responsePacket, err := packet.ReceivePacket(backendConnection)
-	if err != nil {
-		return err
+	if jsonOutput {
+		output, err := json.MarshalIndent(response, "", "    ")
+		if err != nil {
+			return errors.New("failed generating json report")
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	if len(response.Executions) == 0 {
+		fmt.Printf("task %v has no finished executions\n", response.TaskId)
+		return nil
+	}
+
+	// Output paths are long, so only show them for tasks capturing the output
+	var hasStdout, hasStderr bool
+	for _, record := range response.Executions {
+		hasStdout = hasStdout || record.StdoutFilePath != ""
+		hasStderr = hasStderr || record.StderrFilePath != ""
+	}
+
+	headers := []string{"Execution", "Trigger", "Started", "Duration", "Result", "Exit code", "CPU", "Max RSS"}
+	if hasStdout {
+		headers = append(headers, "Stdout")
+	}
+	if hasStderr {
+		headers = append(headers, "Stderr")
+	}
+
+	rows := make([][]string, 0, len(response.Executions))
+	for _, record := range response.Executions {
+		exitCode := "-"
+		if record.HasExitCode() {
+			exitCode = strconv.Itoa(record.ExitCode)
+		}
+		cpuTime, maxRss := "-", "-"
+		if record.Usage != nil {
+			cpuTime = record.Usage.CpuTime().Round(time.Millisecond).String()
+			maxRss = fmt.Sprintf("%v KiB", record.Usage.MaxRssKb)
+		}
+		row := []string{
+			strconv.Itoa(record.Execution),
+			string(record.Trigger),
+			record.StartTime.Format("2006-01-02 15:04:05"),
+			record.Duration.Round(time.Millisecond).String(),
+			string(record.Result),
+			exitCode,
+			cpuTime,
+			maxRss,
+		}
+		if hasStdout {
+			row = append(row, record.StdoutFilePath)
+		}
+		if hasStderr {
+			row = append(row, record.StderrFilePath)
+		}
+		rows = append(rows, row)
 	}
+	printTable(headers, rows)
+
+	if dropped := response.RunCount - len(response.Executions); dropped > 0 {
+		fmt.Printf("%v older executions are not kept in the history\n", dropped)
+	}
+	fmt.Printf("Total usage of all executions: %v\n", response.TotalUsage.String())
+	return nil
+}
 
-	response, err := packet.DecodeStopResponsePacket(responsePacket)
+func CmdInspect(ctx context.Context, backendClient *client.Client, task string, jsonOutput bool) error {
+	response, err := backendClient.Inspect(ctx, task)
 	if err != nil {
 		return err
 	}
 
-	switch response.Status {
-	case types.StopResponseStatusSuccess:
-		fmt.Println("Stopped task")
+	if jsonOutput {
+		output, err := json.MarshalIndent(response, "", "    ")
+		if err != nil {
+			return errors.New("failed generating json report")
+		}
+		fmt.Println(string(output))
 		return nil
-	case types.StopResponseStatusTaskNotFound:
-		err = errors.New("task not found")
-		return err
-	case types.StopResponseStatusAlreadyStopped:
-		err = errors.New("task is already stopped")
-		return err
-	default:
-		err = errors.New("unknown stop error")
-		return err
 	}
+
+	formatDelay := func(delayMs int) string {
+		return (time.Duration(delayMs) * time.Millisecond).String()
+	}
+	formatLimit := func(value any, hasLimit bool) string {
+		if !hasLimit {
+			return "unlimited"
+		}
+		return fmt.Sprint(value)
+	}
+	formatOptional := func(value string) string {
+		if value == "" {
+			return "-"
+		}
+		return value
+	}
+
+	activeStr := "Yes"
+	if response.IsDeactivated {
+		activeStr = fmt.Sprintf("No (%v)", response.DeactivationReason)
+	}
+	maxFailuresStr := "unlimited"
+	if response.MaxSubsequentFailures >= 0 {
+		maxFailuresStr = strconv.Itoa(response.MaxSubsequentFailures)
+	}
+
+	fmt.Printf("Task %v\n", response.FriendlyName)
+	fmt.Printf("  Id:                     %v\n", response.Id)
+	fmt.Printf("  Active:                 %v\n", activeStr)
+	fmt.Printf("  Hash:                   %v\n", response.Hash)
+	fmt.Printf("  NameDisplayHash:        %v\n", response.NameDisplayHash)
+
+	fmt.Println("Definition")
+	fmt.Printf("  Cmdline:                %v\n", response.Cmdline)
+	fmt.Printf("  Cwd:                    %v\n", response.Cwd)
+	fmt.Printf("  Display:                %v (%v)\n", response.Display.ComputeDisplayLabelLong(), response.DisplayState)
+	fmt.Printf("  Tags:                   %v\n", response.Tags)
+	fmt.Printf("  CaptureStdout:          %v\n", response.CaptureStdout)
+	fmt.Printf("  CaptureStderr:          %v\n", response.CaptureStderr)
+	fmt.Printf("  DelayAfterSuccess:      %v\n", formatDelay(response.DelayAfterSuccessMs))
+	fmt.Printf("  DelayAfterFailure:      %v\n", formatDelay(response.DelayAfterFailureMs))
+	fmt.Printf("  MaxSubsequentFailures:  %v\n", maxFailuresStr)
+	fmt.Printf("  LogFormat:              %v\n", response.LogFormat)
+	fmt.Printf("  LogMaxExecutions:       %v\n", formatLimit(response.LogRetention.MaxExecutions, response.LogRetention.HasMaxExecutions()))
+	fmt.Printf("  LogMaxTaskLogBytes:     %v\n", formatLimit(response.LogRetention.MaxTaskLogBytes, response.LogRetention.HasMaxTaskLogBytes()))
+	fmt.Printf("  LogMaxAge:              %v\n", formatLimit(response.LogRetention.MaxAge, response.LogRetention.HasMaxAge()))
+	fmt.Printf("  LogCompress:            %v\n", response.LogRetention.Compress)
+	fmt.Printf("  Syslog:                 %v\n", formatOptional(response.LogSinks.Syslog))
+	fmt.Printf("  SyslogMaxLength:        %v\n", response.LogSinks.GetSyslogMaxLength())
+	fmt.Printf("  LogPipe:                %v\n", formatOptional(response.LogSinks.LogPipe))
+	fmt.Printf("  Env:\n")
+	for _, entry := range response.Env {
+		fmt.Printf("    %v\n", entry)
+	}
+
+	fmt.Println("State")
+	fmt.Printf("  RunCount:               %v\n", response.RunCount)
+	fmt.Printf("  FailureCount:           %v\n", response.FailureCount)
+	fmt.Printf("  SubsequentFailureCount: %v\n", response.SubsequentFailureCount)
+	fmt.Printf("  LastExitValue:          %v\n", response.LastExitValue)
+	if len(response.Pids) > 0 {
+		fmt.Printf("  Pids:                   %v\n", response.Pids)
+		fmt.Printf("  RunningSince:           %v (%v)\n", response.CurrentStartTime.Format("2006-01-02 15:04:05"), response.CurrentUptime.Round(time.Second))
+	} else {
+		fmt.Printf("  Pids:                   -\n")
+	}
+	if !response.NextRunTime.IsZero() {
+		fmt.Printf("  NextRun:                %v (in %v)\n", response.NextRunTime.Format("2006-01-02 15:04:05"), response.TimeUntilNextRun.Round(time.Second))
+	} else {
+		fmt.Printf("  NextRun:                -\n")
+	}
+	fmt.Printf("  TotalUsage:             %v\n", response.TotalUsage.String())
+
+	fmt.Println("Files")
+	fmt.Printf("  LogFile:                %v\n", response.LogFile)
+	fmt.Printf("  LastStdout:             %v\n", formatOptional(response.LastStdoutFilePath))
+	fmt.Printf("  LastStderr:             %v\n", formatOptional(response.LastStderrFilePath))
+
+	fmt.Println("Recent executions")
+	if len(response.RecentExecutions) == 0 {
+		fmt.Println("  none")
+		return nil
+	}
+	for _, record := range response.RecentExecutions {
+		exitCode := ""
+		if record.HasExitCode() {
+			exitCode = fmt.Sprintf(", exit code %v", record.ExitCode)
+		}
+		fmt.Printf("  #%v %v, %v, %v (%v%v)\n", record.Execution, record.StartTime.Format("2006-01-02 15:04:05"),
+			record.Trigger, record.Duration.Round(time.Millisecond), record.Result, exitCode)
+	}
+	return nil
 }
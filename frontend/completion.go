@@ -0,0 +1,160 @@
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"spieven/common/packet"
+	"spieven/common/types"
+	ftypes "spieven/frontend/types"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// completionTimeout bounds the time spent asking the backend for completion candidates. Completions are requested on
+// every tab press, so a busy or unreachable backend must not freeze the shell.
+const completionTimeout = 500 * time.Millisecond
+
+// fetchCompletionTasks lists tasks for completions, using connection flags already parsed by cobra. The backend is
+// never started for completions and errors result in no candidates.
+func fetchCompletionTasks(cmd *cobra.Command, status ftypes.TaskStatusFilter) packet.ListResponseBody {
+	var flags CommonFlags
+	flags.serverAddress, _ = cmd.Flags().GetString("server-address")
+	flags.serverPort, _ = cmd.Flags().GetInt("server-port")
+	flags.clientConfig, _ = cmd.Flags().GetString("client-config")
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+	defer cancel()
+
+	backendClient, err := connectToBackend(ctx, false, &flags)
+	if err != nil {
+		return nil
+	}
+	defer backendClient.Close()
+
+	filter := types.TaskFilter{
+		IdFilter:           math.MaxInt,
+		IncludeActive:      status == ftypes.TaskStatusFilterAll || status == ftypes.TaskStatusFilterActive,
+		IncludeDeactivated: status == ftypes.TaskStatusFilterAll || status == ftypes.TaskStatusFilterInactive,
+	}
+	filter.Derive()
+	response, err := backendClient.List(ctx, packet.ListRequestBody{Filter: filter})
+	if err != nil {
+		return nil
+	}
+	return response
+}
+
+// completionCandidates collects unique candidates starting with the text being completed.
+type completionCandidates struct {
+	toComplete  string
+	completions []cobra.Completion
+	seen        map[string]bool
+}
+
+func newCompletionCandidates(toComplete string) *completionCandidates {
+	return &completionCandidates{toComplete: toComplete, seen: make(map[string]bool)}
+}
+
+func (candidates *completionCandidates) add(candidate string, description string) {
+	if candidates.seen[candidate] || !strings.HasPrefix(candidate, candidates.toComplete) {
+		return
+	}
+	candidates.seen[candidate] = true
+	candidates.completions = append(candidates.completions, cobra.CompletionWithDesc(candidate, description))
+}
+
+// completeTaskArg completes the TASK argument with ids and names of tasks having given status. Names with displays
+// and tags are offered only once the user started typing them, so they don't drown the ids and names.
+func completeTaskArg(status ftypes.TaskStatusFilter) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		candidates := newCompletionCandidates(toComplete)
+		for _, task := range fetchCompletionTasks(cmd, status) {
+			display := task.Display.ComputeDisplayLabel()
+			state := "active"
+			if task.IsDeactivated {
+				state = "deactivated"
+			}
+
+			candidates.add(strconv.Itoa(task.Id), fmt.Sprintf("%v@%v, %v", task.FriendlyName, display, state))
+			if task.FriendlyName == "" {
+				continue
+			}
+			candidates.add(types.TaskSelector{Name: task.FriendlyName}.String(), strings.Join(task.Cmdline, " "))
+			if strings.Contains(toComplete, "@") {
+				candidates.add(task.FriendlyName+"@"+display, strings.Join(task.Cmdline, " "))
+			}
+			if strings.HasPrefix(toComplete, "tag:") {
+				for _, tag := range task.Tags {
+					candidates.add("tag:"+tag, "tag")
+				}
+			}
+		}
+		return candidates.completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeTags completes comma separated tags of the --tags flag with tags of known tasks.
+func completeTags(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	prefix := ""
+	if index := strings.LastIndex(toComplete, ","); index >= 0 {
+		prefix = toComplete[:index+1]
+	}
+	typedTags := strings.Split(prefix, ",")
+
+	candidates := newCompletionCandidates(toComplete)
+	for _, task := range fetchCompletionTasks(cmd, ftypes.TaskStatusFilterAll) {
+		for _, tag := range task.Tags {
+			if !slices.Contains(typedTags, tag) {
+				candidates.add(prefix+tag, "tag")
+			}
+		}
+	}
+	return candidates.completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeDisplay completes the --display flag with the generic selections and displays used by known tasks.
+func completeDisplay(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	candidates := newCompletionCandidates(toComplete)
+	candidates.add("h", "headless")
+	candidates.add("x", "current xorg display")
+	candidates.add("w", "current wayland display")
+	for _, task := range fetchCompletionTasks(cmd, ftypes.TaskStatusFilterAll) {
+		candidates.add(task.Display.ComputeDisplayLabel(), task.Display.ComputeDisplayLabelLong())
+	}
+	return candidates.completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeListFormat(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if strings.HasPrefix(toComplete, "template=") {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	completions := []cobra.Completion{"default", "detailed", "json", "ndjson", "yaml", "csv", "template="}
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// registerFlagCompletions adds completions to flags having the same meaning in all commands defining them.
+func registerFlagCompletions(cmd *cobra.Command) {
+	flagCompletions := map[string]cobra.CompletionFunc{
+		"tags":    completeTags,
+		"display": completeDisplay,
+		"status":  cobra.FixedCompletions([]cobra.Completion{"all", "active", "inactive"}, cobra.ShellCompDirectiveNoFileComp),
+	}
+	for name, completion := range flagCompletions {
+		if cmd.Flags().Lookup(name) != nil {
+			cmd.RegisterFlagCompletionFunc(name, completion)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package frontend
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"spieven/common/packet"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// listColumn is a column of the list command. Values are empty if they don't apply to a task, e.g. uptime of a task,
+// which is not running.
+type listColumn struct {
+	header  string
+	get     func(task *packet.ListResponseBodyItem) string
+	compare func(a, b *packet.ListResponseBodyItem) int
+}
+
+var defaultListColumns = []string{"id", "name", "active", "display", "runs", "failures"}
+
+const listColumnNames = "id, name, active, display, runs, failures, tags, uptime, last-exit, next-run, pid, cmdline, cwd, " +
+	"reason, cpu-time, max-rss, log-file"
+
+// getListColumns returns all columns of the list command by their names. Durations are computed relative to now, so
+// all tasks are shown at the same point in time. It's read when values are computed, so columns can be selected before
+// the tasks are received.
+func getListColumns(now *time.Time) map[string]listColumn {
+	uptime := func(task *packet.ListResponseBodyItem) time.Duration {
+		if task.CurrentStartTime.IsZero() {
+			return 0
+		}
+		return now.Sub(task.CurrentStartTime)
+	}
+	timeUntilNextRun := func(task *packet.ListResponseBodyItem) time.Duration {
+		if task.NextRunTime.IsZero() {
+			return math.MaxInt64 // sort tasks which are not waiting last
+		}
+		return max(task.NextRunTime.Sub(*now), 0)
+	}
+	compareInts := func(get func(task *packet.ListResponseBodyItem) int) func(a, b *packet.ListResponseBodyItem) int {
+		return func(a, b *packet.ListResponseBodyItem) int { return cmp.Compare(get(a), get(b)) }
+	}
+	compareStrings := func(get func(task *packet.ListResponseBodyItem) string) func(a, b *packet.ListResponseBodyItem) int {
+		return func(a, b *packet.ListResponseBodyItem) int { return strings.Compare(get(a), get(b)) }
+	}
+
+	getName := func(task *packet.ListResponseBodyItem) string {
+		name := task.FriendlyName
+		if name == "" && len(task.Cmdline) > 0 {
+			name = task.Cmdline[0]
+		}
+		return name
+	}
+	getActive := func(task *packet.ListResponseBodyItem) string {
+		if task.IsDeactivated {
+			return "no"
+		}
+		return "yes"
+	}
+	getDisplay := func(task *packet.ListResponseBodyItem) string { return task.Display.ComputeDisplayLabel() }
+	getTags := func(task *packet.ListResponseBodyItem) string { return strings.Join(task.Tags, ",") }
+	getCmdline := func(task *packet.ListResponseBodyItem) string { return strings.Join(task.Cmdline, " ") }
+	getCwd := func(task *packet.ListResponseBodyItem) string { return task.Cwd }
+	getReason := func(task *packet.ListResponseBodyItem) string { return task.DeactivationReason }
+	getLogFile := func(task *packet.ListResponseBodyItem) string { return task.OutFilePath }
+
+	return map[string]listColumn{
+		"id": {
+			header:  "Id",
+			get:     func(task *packet.ListResponseBodyItem) string { return strconv.Itoa(task.Id) },
+			compare: compareInts(func(task *packet.ListResponseBodyItem) int { return task.Id }),
+		},
+		"name":    {header: "Name", get: getName, compare: compareStrings(getName)},
+		"active":  {header: "Active", get: getActive, compare: compareStrings(getActive)},
+		"display": {header: "Display", get: getDisplay, compare: compareStrings(getDisplay)},
+		"runs": {
+			header:  "Runs",
+			get:     func(task *packet.ListResponseBodyItem) string { return strconv.Itoa(task.RunCount) },
+			compare: compareInts(func(task *packet.ListResponseBodyItem) int { return task.RunCount }),
+		},
+		"failures": {
+			header: "Failures",
+			get: func(task *packet.ListResponseBodyItem) string {
+				maxFailures := task.MaxSubsequentFailures
+				maxFailuresStr := ""
+				if maxFailures >= 0 {
+					maxFailuresStr = fmt.Sprintf("/%d", maxFailures)
+				}
+				return fmt.Sprintf("%d%s", task.FailureCount, maxFailuresStr)
+			},
+			compare: compareInts(func(task *packet.ListResponseBodyItem) int { return task.FailureCount }),
+		},
+		"tags": {header: "Tags", get: getTags, compare: compareStrings(getTags)},
+		"uptime": {
+			header: "Uptime",
+			get: func(task *packet.ListResponseBodyItem) string {
+				if task.CurrentStartTime.IsZero() {
+					return ""
+				}
+				return uptime(task).Round(time.Second).String()
+			},
+			compare: func(a, b *packet.ListResponseBodyItem) int { return cmp.Compare(uptime(a), uptime(b)) },
+		},
+		"last-exit": {
+			header: "LastExit",
+			get: func(task *packet.ListResponseBodyItem) string {
+				if task.RunCount == 0 {
+					return ""
+				}
+				return strconv.Itoa(task.LastExitValue)
+			},
+			compare: compareInts(func(task *packet.ListResponseBodyItem) int { return task.LastExitValue }),
+		},
+		"next-run": {
+			header: "NextRun",
+			get: func(task *packet.ListResponseBodyItem) string {
+				if task.NextRunTime.IsZero() {
+					return ""
+				}
+				return "in " + timeUntilNextRun(task).Round(time.Second).String()
+			},
+			compare: func(a, b *packet.ListResponseBodyItem) int {
+				return cmp.Compare(timeUntilNextRun(a), timeUntilNextRun(b))
+			},
+		},
+		"pid": {
+			header: "Pid",
+			get: func(task *packet.ListResponseBodyItem) string {
+				if task.CurrentPid == 0 {
+					return ""
+				}
+				return strconv.Itoa(task.CurrentPid)
+			},
+			compare: compareInts(func(task *packet.ListResponseBodyItem) int { return task.CurrentPid }),
+		},
+		"cmdline": {header: "Cmdline", get: getCmdline, compare: compareStrings(getCmdline)},
+		"cwd":     {header: "Cwd", get: getCwd, compare: compareStrings(getCwd)},
+		"reason":  {header: "Reason", get: getReason, compare: compareStrings(getReason)},
+		"cpu-time": {
+			header: "CpuTime",
+			get: func(task *packet.ListResponseBodyItem) string {
+				return task.TotalUsage.CpuTime().Round(time.Millisecond).String()
+			},
+			compare: func(a, b *packet.ListResponseBodyItem) int {
+				return cmp.Compare(a.TotalUsage.CpuTime(), b.TotalUsage.CpuTime())
+			},
+		},
+		"max-rss": {
+			header: "MaxRssKiB",
+			get: func(task *packet.ListResponseBodyItem) string {
+				return strconv.FormatInt(int64(task.TotalUsage.MaxRssKb), 10)
+			},
+			compare: func(a, b *packet.ListResponseBodyItem) int {
+				return cmp.Compare(a.TotalUsage.MaxRssKb, b.TotalUsage.MaxRssKb)
+			},
+		},
+		"log-file": {header: "LogFile", get: getLogFile, compare: compareStrings(getLogFile)},
+	}
+}
+
+// parseListColumns selects columns by their names.
+func parseListColumns(allColumns map[string]listColumn, names []string) ([]listColumn, error) {
+	columns := make([]listColumn, 0, len(names))
+	for _, name := range names {
+		column, found := allColumns[name]
+		if !found {
+			return nil, fmt.Errorf("invalid column %q, expected one of: %v", name, listColumnNames)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// sortListResponse sorts tasks by a column given as name[:asc|:desc]. Tasks with equal values keep the order of the
+// backend.
+func sortListResponse(allColumns map[string]listColumn, response packet.ListResponseBody, sortBy string) error {
+	name, order, _ := strings.Cut(sortBy, ":")
+	column, found := allColumns[name]
+	if !found {
+		return fmt.Errorf("invalid sort column %q, expected one of: %v", name, listColumnNames)
+	}
+
+	var descending bool
+	switch order {
+	case "", "asc":
+	case "desc":
+		descending = true
+	default:
+		return fmt.Errorf("invalid sort order %q, expected asc or desc", order)
+	}
+
+	slices.SortStableFunc(response, func(a, b packet.ListResponseBodyItem) int {
+		result := column.compare(&a, &b)
+		if descending {
+			result = -result
+		}
+		return result
+	})
+	return nil
+}
+
+func printListTable(columns []listColumn, response packet.ListResponseBody) {
+	headers := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = col.header
+	}
+	rows := make([][]string, len(response))
+	for i := range response {
+		row := make([]string, len(columns))
+		for ci, col := range columns {
+			row[ci] = col.get(&response[i])
+			if row[ci] == "" {
+				row[ci] = "-"
+			}
+		}
+		rows[i] = row
+	}
+	printTable(headers, rows)
+}
+
+// printListCsv prints a header with column names followed by a row for each task.
+func printListCsv(columns []listColumn, names []string, response packet.ListResponseBody) error {
+	writer := csv.NewWriter(os.Stdout)
+	if err := writer.Write(names); err != nil {
+		return err
+	}
+	for i := range response {
+		row := make([]string, len(columns))
+		for ci, col := range columns {
+			row[ci] = col.get(&response[i])
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func printListNdjson(response packet.ListResponseBody) error {
+	for i := range response {
+		serialized, err := json.Marshal(&response[i])
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(serialized))
+	}
+	return nil
+}
+
+// printListTemplate executes a text/template for each task, followed by a newline. The template gets the task as
+// returned in json output and can use the join function, e.g. {{join .Tags ","}}.
+func printListTemplate(text string, response packet.ListResponseBody) error {
+	tmpl, err := template.New("list").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid template: %v", err)
+	}
+	for i := range response {
+		var output bytes.Buffer
+		if err := tmpl.Execute(&output, &response[i]); err != nil {
+			return fmt.Errorf("failed executing template: %v", err)
+		}
+		fmt.Println(output.String())
+	}
+	return nil
+}
+
+// printListYaml prints tasks as a yaml sequence of mappings, with the same fields as json output.
+func printListYaml(response packet.ListResponseBody) error {
+	serialized, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(serialized))
+	decoder.UseNumber()
+	root, err := readYamlNode(decoder)
+	if err != nil {
+		return err
+	}
+
+	var output strings.Builder
+	writeYamlNode(&output, root, 0)
+	fmt.Print(output.String())
+	return nil
+}
+
+// yamlNode is a json value with the order of fields kept, so yaml output lists them like json output does.
+type yamlNode struct {
+	scalar   []byte // json encoded scalar, nil for mappings and sequences
+	isList   bool
+	keys     []string // only for mappings
+	children []*yamlNode
+}
+
+func readYamlNode(decoder *json.Decoder) (*yamlNode, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, isDelim := token.(json.Delim)
+	if !isDelim {
+		scalar, err := json.Marshal(token)
+		return &yamlNode{scalar: scalar}, err
+	}
+
+	node := &yamlNode{isList: delim == '['}
+	for decoder.More() {
+		if !node.isList {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			node.keys = append(node.keys, key.(string))
+		}
+		child, err := readYamlNode(decoder)
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+	}
+	_, err = decoder.Token() // closing delimiter
+	return node, err
+}
+
+var yamlPlainKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// writeYamlNode writes a node in block style. Scalars and empty collections are written inline, strings as json
+// strings, which are valid double quoted yaml scalars.
+func writeYamlNode(output *strings.Builder, node *yamlNode, indent int) {
+	inline := func(node *yamlNode) (string, bool) {
+		switch {
+		case node.scalar != nil:
+			return string(node.scalar), true
+		case len(node.children) > 0:
+			return "", false
+		case node.isList:
+			return "[]", true
+		default:
+			return "{}", true
+		}
+	}
+
+	if value, isInline := inline(node); isInline {
+		output.WriteString(strings.Repeat("  ", indent) + value + "\n")
+		return
+	}
+
+	padding := strings.Repeat("  ", indent)
+	for i, child := range node.children {
+		prefix := "-"
+		if !node.isList {
+			prefix = node.keys[i] + ":"
+			if !yamlPlainKeyRegex.MatchString(node.keys[i]) {
+				key, _ := json.Marshal(node.keys[i])
+				prefix = string(key) + ":"
+			}
+		}
+
+		if value, isInline := inline(child); isInline {
+			output.WriteString(padding + prefix + " " + value + "\n")
+			continue
+		}
+		if node.isList {
+			// Items start on the line of their dash, which takes as much space as one level of indentation
+			var item strings.Builder
+			writeYamlNode(&item, child, indent+1)
+			output.WriteString(padding + "- " + strings.TrimPrefix(item.String(), padding+"  "))
+			continue
+		}
+		output.WriteString(padding + prefix + "\n")
+		writeYamlNode(output, child, indent+1)
+	}
+}
@@ -0,0 +1,64 @@
+package frontend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// printTable prints rows as a table with vertical bars, with columns as wide as their longest cell.
+func printTable(headers []string, rows [][]string) {
+	for _, line := range formatTable(headers, rows) {
+		fmt.Println(line)
+	}
+}
+
+// formatTable returns lines of a table printed by printTable: the header, a separator and one line for each row.
+func formatTable(headers []string, rows [][]string) []string {
+	colCount := len(headers)
+
+	// Initialize column widths from headers and update them based on cell contents.
+	widths := make([]int, colCount)
+	for i, header := range headers {
+		widths[i] = len(header)
+	}
+	for _, row := range rows {
+		for i, val := range row {
+			widths[i] = max(widths[i], len(val))
+		}
+	}
+
+	// Build format string with vertical bars based on computed widths.
+	var formatBuilder strings.Builder
+	formatBuilder.WriteString("|")
+	for _, width := range widths {
+		fmt.Fprintf(&formatBuilder, " %%-%dv |", width)
+	}
+	format := formatBuilder.String()
+
+	// Build separator line like: |----|--------|...
+	var sepBuilder strings.Builder
+	sepBuilder.WriteString("|")
+	for _, width := range widths {
+		sepBuilder.WriteString(strings.Repeat("-", width+2))
+		sepBuilder.WriteString("|")
+	}
+	sep := sepBuilder.String()
+
+	// Format header and separator.
+	lines := make([]string, 0, len(rows)+2)
+	headerArgs := make([]any, colCount)
+	for i, header := range headers {
+		headerArgs[i] = header
+	}
+	lines = append(lines, fmt.Sprintf(format, headerArgs...), sep)
+
+	// Format rows.
+	for _, row := range rows {
+		rowArgs := make([]any, colCount)
+		for i, val := range row {
+			rowArgs[i] = val
+		}
+		lines = append(lines, fmt.Sprintf(format, rowArgs...))
+	}
+	return lines
+}
@@ -0,0 +1,495 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"spieven/client"
+	"spieven/common/packet"
+	"spieven/common/types"
+	ftypes "spieven/frontend/types"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Keys of the top view which are not single printable characters.
+const (
+	topKeyUp        = "up"
+	topKeyDown      = "down"
+	topKeyEnter     = "enter"
+	topKeyEscape    = "escape"
+	topKeyBackspace = "backspace"
+	topKeyCtrlC     = "ctrl-c"
+)
+
+const topHelp = "j/k select  s stop  r resume  f refresh  p peek  t tag  d display  a deactivated  q quit"
+
+// topLogLines is the number of lines of the task log fetched for the selected task. Only as many as fit on the screen
+// are shown.
+const topLogLines = 200
+
+var topColumnNames = []string{"id", "name", "active", "display", "runs", "failures", "uptime", "next-run"}
+
+// CmdTop shows a live view of tasks matching the filter with the log of the selected task, until the user quits. Tasks
+// are listed again on every tick of the interval and whenever the backend reports an event.
+func CmdTop(ctx context.Context, backendClient *client.Client, filter types.TaskFilter, interval time.Duration) error {
+	if !ftypes.IsTerminal(os.Stdin) || !ftypes.IsTerminal(os.Stdout) {
+		return errors.New("top must be run in an interactive terminal")
+	}
+
+	view := topView{client: backendClient, filter: filter, selectedId: -1}
+	columns, err := parseListColumns(getListColumns(&view.now), topColumnNames)
+	if err != nil {
+		return err
+	}
+	view.columns = columns
+
+	restoreMode, err := ftypes.EnableRawMode(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer restoreMode()
+	fmt.Print("\x1b[?1049h\x1b[?25l") // alternate screen, hidden cursor
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys := make(chan string, 16)
+	go readTopKeys(os.Stdin, keys)
+
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, syscall.SIGWINCH)
+	defer signal.Stop(resized)
+
+	changed := make(chan struct{}, 1)
+	backend := backendClient.Backend()
+	if backend.HasCapability(packet.CapabilityEvents) {
+		go backendClient.Events(ctx, types.EventFilter{}, func(event types.Event) error {
+			select {
+			case changed <- struct{}{}:
+			default:
+				// A refresh is already pending
+			}
+			return nil
+		})
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	view.update(ctx)
+	for {
+		view.render()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			if quit := view.handleKey(ctx, key); quit {
+				return nil
+			}
+		case <-resized:
+		case <-changed:
+			view.update(ctx)
+		case <-ticker.C:
+			view.update(ctx)
+		}
+	}
+}
+
+type topView struct {
+	client  *client.Client
+	filter  types.TaskFilter // filter from the command line, changed by tag, display and status keys
+	columns []listColumn
+
+	now        time.Time // time of the last update, all durations are relative to it
+	tasks      packet.ListResponseBody
+	selectedId int // -1 if there are no tasks
+	scroll     int // index of the first visible task
+	logRecords []types.TaskLogRecord
+
+	peek    bool // log of the selected task fills the screen
+	prompt  *topPrompt
+	message string // result of the last action, cleared by the next key
+}
+
+// topPrompt reads a value in the bottom line, e.g. a tag to filter by.
+type topPrompt struct {
+	label string
+	input string
+	apply func(value string) error
+}
+
+// update lists tasks again and reads the log of the selected task.
+func (view *topView) update(ctx context.Context) {
+	response, err := view.client.List(ctx, packet.ListRequestBody{Filter: view.filter})
+	if err != nil {
+		view.message = err.Error()
+		return
+	}
+	view.now = time.Now()
+
+	// Active tasks first, so old runs of deactivated tasks don't push them off the screen
+	slices.SortStableFunc(response, func(a, b packet.ListResponseBodyItem) int {
+		switch {
+		case a.IsDeactivated == b.IsDeactivated:
+			return a.Id - b.Id
+		case a.IsDeactivated:
+			return 1
+		default:
+			return -1
+		}
+	})
+
+	selectedIndex := view.selectedIndex()
+	view.tasks = response
+	if view.selectedIndex() < 0 {
+		view.selectedId = -1
+		if len(view.tasks) > 0 {
+			view.selectedId = view.tasks[min(max(selectedIndex, 0), len(view.tasks)-1)].Id
+		}
+	}
+
+	view.logRecords = nil
+	if task := view.selectedTask(); task != nil {
+		request := packet.TaskLogsRequestBody{
+			Task:      strconv.Itoa(task.Id),
+			Source:    packet.TaskLogsSourceTaskLog,
+			Execution: types.ExecutionSelectionAll,
+			Tail:      topLogLines,
+		}
+		if logs, err := view.client.Logs(ctx, request); err == nil {
+			view.logRecords = logs.Records
+		}
+	}
+}
+
+func (view *topView) selectedIndex() int {
+	return slices.IndexFunc(view.tasks, func(task packet.ListResponseBodyItem) bool { return task.Id == view.selectedId })
+}
+
+func (view *topView) selectedTask() *packet.ListResponseBodyItem {
+	if index := view.selectedIndex(); index >= 0 {
+		return &view.tasks[index]
+	}
+	return nil
+}
+
+// handleKey runs the action bound to a key. It returns true if the user wants to quit.
+func (view *topView) handleKey(ctx context.Context, key string) bool {
+	if view.prompt != nil {
+		switch key {
+		case topKeyCtrlC:
+			return true
+		case topKeyEnter:
+			prompt := view.prompt
+			view.prompt = nil
+			if err := prompt.apply(strings.TrimSpace(prompt.input)); err != nil {
+				view.message = err.Error()
+			} else {
+				view.update(ctx)
+			}
+		case topKeyEscape:
+			view.prompt = nil
+		case topKeyBackspace:
+			_, size := utf8.DecodeLastRuneInString(view.prompt.input)
+			view.prompt.input = view.prompt.input[:len(view.prompt.input)-size]
+		default:
+			if utf8.RuneCountInString(key) == 1 {
+				view.prompt.input += key
+			}
+		}
+		return false
+	}
+
+	view.message = ""
+	task := view.selectedTask()
+	switch key {
+	case "q", topKeyCtrlC:
+		return true
+	case "k", topKeyUp:
+		view.moveSelection(-1)
+		view.update(ctx)
+	case "j", topKeyDown:
+		view.moveSelection(1)
+		view.update(ctx)
+	case "p", topKeyEnter:
+		view.peek = !view.peek
+	case topKeyEscape:
+		view.peek = false
+	case "s":
+		if task != nil {
+			view.reportAction(view.client.Stop(ctx, strconv.Itoa(task.Id)), "Stopped task %v", task.Id)
+			view.update(ctx)
+		}
+	case "r":
+		if task != nil {
+			_, err := view.client.Resume(ctx, strconv.Itoa(task.Id))
+			view.reportAction(err, "Resumed task %v", task.Id)
+			view.update(ctx)
+		}
+	case "f":
+		if task != nil {
+			filter := types.TaskFilter{IdFilter: task.Id, IncludeActive: true}
+			response, err := view.client.Refresh(ctx, filter)
+			if err == nil && response.RefreshedTasksCount == 0 {
+				err = errors.New("task is not waiting for its next execution")
+			}
+			view.reportAction(err, "Refreshed task %v", task.Id)
+			view.update(ctx)
+		}
+	case "t":
+		view.prompt = &topPrompt{
+			label: "Tag (empty for all): ",
+			input: strings.Join(view.filter.AllTagsFilter, ","),
+			apply: func(value string) error {
+				view.filter.AllTagsFilter = nil
+				if value != "" {
+					view.filter.AllTagsFilter = strings.Split(value, ",")
+				}
+				return nil
+			},
+		}
+	case "d":
+		view.prompt = &topPrompt{
+			label: "Display (empty for all): ",
+			apply: func(value string) error {
+				var display types.DisplaySelection
+				if err := display.ParseDisplaySelection(value, true); err != nil {
+					return err
+				}
+				view.filter.DisplayFilter = display
+				return nil
+			},
+		}
+	case "a":
+		view.filter.IncludeDeactivated = !view.filter.IncludeDeactivated
+		view.filter.IncludeActive = true
+		view.update(ctx)
+	}
+	return false
+}
+
+func (view *topView) moveSelection(offset int) {
+	if len(view.tasks) == 0 {
+		return
+	}
+	index := min(max(view.selectedIndex()+offset, 0), len(view.tasks)-1)
+	view.selectedId = view.tasks[index].Id
+}
+
+func (view *topView) reportAction(err error, format string, args ...any) {
+	if err != nil {
+		view.message = err.Error()
+	} else {
+		view.message = fmt.Sprintf(format, args...)
+	}
+}
+
+// render draws the whole screen: a summary, the table of tasks, the log of the selected task, the result of the last
+// action or a prompt, and the key bindings.
+func (view *topView) render() {
+	width, height, err := ftypes.GetTerminalSize(os.Stdout)
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+
+	activeCount := 0
+	for _, task := range view.tasks {
+		if !task.IsDeactivated {
+			activeCount++
+		}
+	}
+
+	var lines []string
+	highlightedLine := -1
+	lines = append(lines, fmt.Sprintf("spieven top - %v tasks, %v active - %v - %v",
+		len(view.tasks), activeCount, view.describeFilter(), view.now.Format("15:04:05")))
+	bodyHeight := height - len(lines) - 2 // bottom lines
+
+	if !view.peek {
+		tableLines := view.formatTasks()
+		visibleRows := min(len(view.tasks), max(bodyHeight/2-2, 1))
+		selectedIndex := view.selectedIndex()
+		if selectedIndex >= 0 {
+			view.scroll = min(view.scroll, selectedIndex)
+			view.scroll = max(view.scroll, selectedIndex-visibleRows+1)
+		}
+		view.scroll = max(min(view.scroll, len(view.tasks)-visibleRows), 0)
+		if selectedIndex >= 0 {
+			highlightedLine = len(lines) + 2 + selectedIndex - view.scroll
+		}
+
+		lines = append(lines, tableLines[:2]...)
+		lines = append(lines, tableLines[2+view.scroll:2+view.scroll+visibleRows]...)
+		if len(view.tasks) == 0 {
+			lines = append(lines, "no tasks match the requested criteria")
+		}
+		lines = append(lines, "")
+	}
+
+	if task := view.selectedTask(); task != nil {
+		lines = append(lines, fmt.Sprintf("--- log of %v (%v) ---", task.FriendlyName, task.Id))
+		logHeight := max(height-len(lines)-2, 0)
+		records := view.logRecords[max(len(view.logRecords)-logHeight, 0):]
+		for _, record := range records {
+			lines = append(lines, fmt.Sprintf("%v %v %v", record.Time.Format("15:04:05"), record.Stream, record.Line))
+		}
+	}
+
+	for len(lines) < height-2 {
+		lines = append(lines, "")
+	}
+	lines = lines[:max(height-2, 0)]
+	switch {
+	case view.prompt != nil:
+		lines = append(lines, view.prompt.label+view.prompt.input+"_")
+	default:
+		lines = append(lines, view.message)
+	}
+	lines = append(lines, topHelp)
+
+	var screen bytes.Buffer
+	screen.WriteString("\x1b[H")
+	for index, line := range lines {
+		line = fitTopLine(line, width)
+		if index == highlightedLine {
+			line = "\x1b[7m" + line + strings.Repeat(" ", max(width-utf8.RuneCountInString(line), 0)) + "\x1b[0m"
+		}
+		screen.WriteString(line)
+		screen.WriteString("\x1b[K")
+		if index < len(lines)-1 {
+			screen.WriteString("\n")
+		}
+	}
+	screen.WriteString("\x1b[J")
+	os.Stdout.Write(screen.Bytes())
+}
+
+func (view *topView) formatTasks() []string {
+	headers := make([]string, len(view.columns))
+	for i, column := range view.columns {
+		headers[i] = column.header
+	}
+	rows := make([][]string, len(view.tasks))
+	for i := range view.tasks {
+		row := make([]string, len(view.columns))
+		for ci, column := range view.columns {
+			row[ci] = column.get(&view.tasks[i])
+			if row[ci] == "" {
+				row[ci] = "-"
+			}
+		}
+		rows[i] = row
+	}
+	return formatTable(headers, rows)
+}
+
+func (view *topView) describeFilter() string {
+	var parts []string
+	if len(view.filter.AllTagsFilter) > 0 {
+		parts = append(parts, "tags "+strings.Join(view.filter.AllTagsFilter, ","))
+	}
+	if view.filter.DisplayFilter.Type != types.DisplaySelectionTypeNone {
+		parts = append(parts, "display "+view.filter.DisplayFilter.ComputeDisplayLabel())
+	}
+	if len(view.filter.AnyNameFilter) > 0 {
+		parts = append(parts, "names "+strings.Join(view.filter.AnyNameFilter, ","))
+	}
+	if view.filter.WhereFilter != "" {
+		parts = append(parts, "where "+view.filter.WhereFilter)
+	}
+	if !view.filter.IncludeDeactivated {
+		parts = append(parts, "active only")
+	}
+	if len(parts) == 0 {
+		return "all tasks"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fitTopLine removes control characters, which would break the layout, and cuts the line to the width of the screen.
+func fitTopLine(line string, width int) string {
+	line = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		default:
+			return r
+		}
+	}, line)
+	if utf8.RuneCountInString(line) > width {
+		line = string([]rune(line)[:width])
+	}
+	return line
+}
+
+// readTopKeys passes key presses from the terminal to the channel until reading fails.
+func readTopKeys(file *os.File, keys chan<- string) {
+	defer close(keys)
+	buffer := make([]byte, 64)
+	for {
+		count, err := file.Read(buffer)
+		if err != nil {
+			return
+		}
+		for _, key := range parseTopKeys(buffer[:count]) {
+			keys <- key
+		}
+	}
+}
+
+// parseTopKeys splits input of a terminal in raw mode into keys. Printable characters are passed as they are, other
+// keys by their names. Unknown escape sequences are dropped.
+func parseTopKeys(data []byte) []string {
+	var keys []string
+	for len(data) > 0 {
+		switch {
+		case bytes.HasPrefix(data, []byte("\x1b[A")), bytes.HasPrefix(data, []byte("\x1bOA")):
+			keys = append(keys, topKeyUp)
+			data = data[3:]
+		case bytes.HasPrefix(data, []byte("\x1b[B")), bytes.HasPrefix(data, []byte("\x1bOB")):
+			keys = append(keys, topKeyDown)
+			data = data[3:]
+		case bytes.HasPrefix(data, []byte("\x1b[")):
+			end := bytes.IndexFunc(data[2:], func(r rune) bool { return r >= 0x40 && r <= 0x7e })
+			if end < 0 {
+				return keys
+			}
+			data = data[2+end+1:]
+		case data[0] == 0x1b:
+			keys = append(keys, topKeyEscape)
+			data = data[1:]
+		case data[0] == '\r' || data[0] == '\n':
+			keys = append(keys, topKeyEnter)
+			data = data[1:]
+		case data[0] == 0x7f || data[0] == 0x08:
+			keys = append(keys, topKeyBackspace)
+			data = data[1:]
+		case data[0] == 0x03:
+			keys = append(keys, topKeyCtrlC)
+			data = data[1:]
+		default:
+			r, size := utf8.DecodeRune(data)
+			if unicode.IsPrint(r) {
+				keys = append(keys, string(r))
+			}
+			data = data[size:]
+		}
+	}
+	return keys
+}
@@ -1,6 +1,9 @@
 package frontendtypes
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type ListFormat byte
 
@@ -8,19 +11,47 @@ const (
 	ListFormatDefault ListFormat = iota
 	ListFormatDetailed
 	ListFormatJson
+	ListFormatNdjson
+	ListFormatYaml
+	ListFormatCsv
+	ListFormatTemplate
 )
 
-func ParseListFormat(value string) (ListFormat, error) {
+const ListFormatStrValues = "default, detailed, json, ndjson, yaml, csv or template=TEMPLATE"
+
+const listFormatTemplatePrefix = "template="
+
+// ParseListFormat parses a format of the list command. The template format carries a Go text/template executed for
+// each task, which is returned along with it.
+func ParseListFormat(value string) (ListFormat, string, error) {
+	if template, found := strings.CutPrefix(value, listFormatTemplatePrefix); found {
+		if template == "" {
+			return ListFormatDefault, "", fmt.Errorf("template of the %q format must not be empty", "template")
+		}
+		return ListFormatTemplate, template, nil
+	}
+
 	switch value {
 	case "", "default":
-		return ListFormatDefault, nil
+		return ListFormatDefault, "", nil
 	case "detailed":
-		return ListFormatDetailed, nil
+		return ListFormatDetailed, "", nil
 	case "json":
-		return ListFormatJson, nil
+		return ListFormatJson, "", nil
+	case "ndjson":
+		return ListFormatNdjson, "", nil
+	case "yaml":
+		return ListFormatYaml, "", nil
+	case "csv":
+		return ListFormatCsv, "", nil
 	default:
-		return ListFormatDefault, fmt.Errorf("invalid format %q, expected one of: default, detailed, json", value)
+		return ListFormatDefault, "", fmt.Errorf("invalid format %q, expected one of: %v", value, ListFormatStrValues)
 	}
 }
 
+// HasColumns returns true if the format prints columns selected by the user.
+func (format ListFormat) HasColumns() bool {
+	return format == ListFormatDefault || format == ListFormatCsv
+}
+
 
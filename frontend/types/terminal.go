@@ -0,0 +1,70 @@
+package frontendtypes
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// IsTerminal returns true if the file is an interactive terminal, so it's safe to print escape sequences to it.
+func IsTerminal(file *os.File) bool {
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return fileInfo.Mode()&os.ModeCharDevice != 0
+}
+
+// Foreground colors which are readable on both dark and light backgrounds.
+var labelColors = []int{31, 32, 33, 34, 35, 36}
+
+// Colorize wraps the text in ANSI escape sequences. The color is selected based on colorIndex, so the same index always
+// results in the same color.
+func Colorize(text string, colorIndex int) string {
+	color := labelColors[colorIndex%len(labelColors)]
+	return fmt.Sprintf("\x1b[%vm%v\x1b[0m", color, text)
+}
+
+// EnableRawMode switches the terminal to read single key presses without echoing them, including Ctrl+C, which is not
+// turned into a signal anymore. Output processing is kept, so newlines still return the cursor. The returned function
+// restores the previous mode.
+func EnableRawMode(file *os.File) (func(), error) {
+	fd := file.Fd()
+	var previous syscall.Termios
+	if err := ioctl(fd, syscall.TCGETS, unsafe.Pointer(&previous)); err != nil {
+		return nil, fmt.Errorf("failed reading terminal mode: %w", err)
+	}
+
+	raw := previous
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(fd, syscall.TCSETS, unsafe.Pointer(&raw)); err != nil {
+		return nil, fmt.Errorf("failed setting terminal mode: %w", err)
+	}
+
+	return func() { ioctl(fd, syscall.TCSETS, unsafe.Pointer(&previous)) }, nil
+}
+
+// GetTerminalSize returns the number of columns and rows of the terminal.
+func GetTerminalSize(file *os.File) (int, int, error) {
+	var size struct {
+		rows, cols, xPixels, yPixels uint16
+	}
+	if err := ioctl(file.Fd(), syscall.TIOCGWINSZ, unsafe.Pointer(&size)); err != nil {
+		return 0, 0, fmt.Errorf("failed reading terminal size: %w", err)
+	}
+	return int(size.cols), int(size.rows), nil
+}
+
+func ioctl(fd uintptr, request uintptr, argument unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(argument))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package frontendtypes
+
+import (
+	"fmt"
+	"time"
+)
+
+const TimeSelectionHelpString = "Use a duration relative to now, e.g. 10m, or a timestamp, e.g. \"2025-01-31 12:00:00\"."
+
+// ParseTimeSelection parses a point in time passed by the user. Empty value results in a zero time.
+func ParseTimeSelection(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if result, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return result, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q. %v", value, TimeSelectionHelpString)
+}
@@ -21,7 +21,6 @@ func main() {
 		Args:         cobra.ExactArgs(0),
 		SilenceUsage: true,
 	}
-	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
 	backendCmd := backend.CreateCliCommand()
 	rootCmd.AddCommand(backendCmd)